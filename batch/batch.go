@@ -0,0 +1,132 @@
+package batch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Job is a conversion from an input file to an output file.
+type Job struct {
+	Input, Output string
+}
+
+// Result is the result of a job.
+type Result struct {
+	Job
+	Err error
+}
+
+// Expand expands the files, the directories and the glob patterns into the jobs.
+//
+// The files in the directories are taken recursively if match returns true for them.
+// The outputs have the extension ext instead of the inputs'. They are put into outdir
+// mirroring the tree under each directory, or next to the inputs if outdir is empty.
+//
+// The arguments which cannot be read or match no file are returned as the failed results without outputs,
+// and the others are still expanded. It returns an error if two inputs are converted into the same output.
+func Expand(args []string, outdir, ext string, match func(path string) bool) (jobs []Job, failed []Result, err error) {
+	jobs = []Job{}
+	fail := func(input string, err error) {
+		failed = append(failed, Result{Job: Job{Input: input}, Err: err})
+	}
+	seen := map[string]bool{}
+	inputs := map[string]string{}
+	add := func(input, rel string) error {
+		if seen[input] {
+			return nil
+		}
+		seen[input] = true
+		output := strings.TrimSuffix(rel, filepath.Ext(rel)) + ext
+		if outdir == "" {
+			output = strings.TrimSuffix(input, filepath.Ext(input)) + ext
+		} else {
+			output = filepath.Join(outdir, output)
+		}
+		if other, ok := inputs[output]; ok {
+			return fmt.Errorf("'%s' and '%s' are both converted into '%s'", other, input, output)
+		}
+		inputs[output] = input
+		jobs = append(jobs, Job{Input: input, Output: output})
+		return nil
+	}
+
+	for _, arg := range args {
+		paths := []string{arg}
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				fail(arg, err)
+				continue
+			}
+			if len(matches) == 0 {
+				fail(arg, fmt.Errorf("no file matches '%s'", arg))
+				continue
+			}
+			paths = matches
+		}
+
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			if err != nil {
+				fail(path, err)
+				continue
+			}
+			if !info.IsDir() {
+				if err := add(path, filepath.Base(path)); err != nil {
+					return nil, nil, err
+				}
+				continue
+			}
+			root := path
+			err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					// the unreadable files and directories are skipped
+					fail(path, err)
+					return nil
+				}
+				if info.IsDir() || !match(path) {
+					return nil
+				}
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				return add(path, rel)
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return jobs, failed, nil
+}
+
+// Run runs fn for each job with the given number of workers at most.
+// The results are in the same order as the jobs.
+func Run(jobs []Job, workers int, fn func(job Job) error) []Result {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]Result, len(jobs))
+	indices := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				results[i] = Result{Job: jobs[i], Err: fn(jobs[i])}
+			}
+		}()
+	}
+	for i := range jobs {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+	return results
+}
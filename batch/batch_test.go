@@ -0,0 +1,112 @@
+package batch
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"a.diag", "b.diag", "docs/c.diag", "docs/deep/d.diag", "docs/readme.txt"} {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, nil, 0644)
+	}
+	isDiag := func(path string) bool { return strings.HasSuffix(path, ".diag") }
+	in := func(name string) string { return filepath.Join(dir, name) }
+	out := func(name string) string { return filepath.Join(dir, "out", name) }
+
+	jobs, failed, err := Expand([]string{in("*.diag"), in("docs"), in("a.diag")}, out(""), ".xlsx", isDiag)
+	if err != nil || len(failed) > 0 {
+		t.Fatalf("Expand error %v %v", err, failed)
+	}
+	expected := []Job{
+		{in("a.diag"), out("a.xlsx")},
+		{in("b.diag"), out("b.xlsx")},
+		{in("docs/c.diag"), out("c.xlsx")},
+		{in("docs/deep/d.diag"), out("deep/d.xlsx")},
+	}
+	if !reflect.DeepEqual(jobs, expected) {
+		t.Errorf("Unexpected jobs %v", jobs)
+	}
+
+	jobs, _, err = Expand([]string{in("docs/c.diag")}, "", ".svg", isDiag)
+	if err != nil {
+		t.Fatalf("Expand error %v", err)
+	}
+	if !reflect.DeepEqual(jobs, []Job{{in("docs/c.diag"), in("docs/c.svg")}}) {
+		t.Errorf("Unexpected jobs without the output directory %v", jobs)
+	}
+
+	// the pattern without matches and the missing file fail without stopping the others
+	jobs, failed, err = Expand([]string{in("a.diag"), in("*.puml"), in("missing.diag"), in("b.diag")}, "", ".xlsx", isDiag)
+	if err != nil {
+		t.Fatalf("Expand error %v", err)
+	}
+	if !reflect.DeepEqual(jobs, []Job{{in("a.diag"), in("a.xlsx")}, {in("b.diag"), in("b.xlsx")}}) {
+		t.Errorf("Unexpected jobs with the failed arguments %v", jobs)
+	}
+	if len(failed) != 2 || failed[0].Input != in("*.puml") || failed[0].Err == nil ||
+		failed[1].Input != in("missing.diag") || !os.IsNotExist(failed[1].Err) {
+		t.Errorf("Unexpected failed arguments %v", failed)
+	}
+
+	os.MkdirAll(in("other"), 0755)
+	ioutil.WriteFile(in("other/c.diag"), nil, 0644)
+	ioutil.WriteFile(in("docs/c.puml"), nil, 0644)
+	if _, _, err := Expand([]string{in("docs"), in("other")}, out(""), ".xlsx", isDiag); err == nil ||
+		!strings.Contains(err.Error(), in("docs/c.diag")) || !strings.Contains(err.Error(), in("other/c.diag")) {
+		t.Errorf("No error for the inputs into the same output: %v", err)
+	}
+	if _, _, err := Expand([]string{in("docs/c.*")}, "", ".xlsx", isDiag); err == nil {
+		t.Errorf("No error for the inputs with different extensions into the same output")
+	}
+}
+
+func TestRun(t *testing.T) {
+	jobs := []Job{}
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		jobs = append(jobs, Job{Input: name})
+	}
+
+	var running, peak int32
+	results := Run(jobs, 2, func(job Job) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if job.Input == "c" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+
+	if peak > 2 {
+		t.Errorf("%d jobs ran at once", peak)
+	}
+	for i, r := range results {
+		if r.Job != jobs[i] {
+			t.Errorf("Result %d is for %v", i, r.Job)
+		}
+		if (r.Err != nil) != (r.Input == "c") {
+			t.Errorf("Unexpected error of %s: %v", r.Input, r.Err)
+		}
+	}
+}
@@ -1,83 +1,476 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"time"
 
-	"github.com/rsp9u/go-xlsshape/oxml"
 	"github.com/rsp9u/seq2xls"
-	"github.com/rsp9u/seq2xls/seqdiag"
-	"github.com/rsp9u/seq2xls/seqdiag/convertor"
+	"github.com/rsp9u/seq2xls/batch"
+	mmdgenerator "github.com/rsp9u/seq2xls/mermaid/generator"
+	"github.com/rsp9u/seq2xls/model"
+	pumlgenerator "github.com/rsp9u/seq2xls/plantuml/generator"
+	"github.com/rsp9u/seq2xls/render"
+	"github.com/rsp9u/seq2xls/seqdiag/generator"
+	"github.com/rsp9u/seq2xls/seqdiag/lint"
+	"github.com/rsp9u/seq2xls/seqdiag/printer"
+	"github.com/rsp9u/seq2xls/server"
+	"github.com/rsp9u/seq2xls/watch"
 )
 
+// inputFormats is the input formats by the file extension.
+var inputFormats = map[string]string{
+	".puml":     seq2xls.FormatPlantUML,
+	".plantuml": seq2xls.FormatPlantUML,
+	".pu":       seq2xls.FormatPlantUML,
+	".iuml":     seq2xls.FormatPlantUML,
+	".wsd":      seq2xls.FormatPlantUML,
+	".mmd":      seq2xls.FormatMermaid,
+	".mermaid":  seq2xls.FormatMermaid,
+	".md":       seq2xls.FormatMarkdown,
+	".markdown": seq2xls.FormatMarkdown,
+	".json":     seq2xls.FormatJSON,
+	".yaml":     seq2xls.FormatYAML,
+	".yml":      seq2xls.FormatYAML,
+}
+
+// generators is the generators of the output formats other than xlsx.
+var generators = map[string]func(io.Writer, *model.SequenceDiagram) error{
+	"seqdiag": generator.Generate,
+	"puml":    pumlgenerator.Generate,
+	"mermaid": mmdgenerator.Generate,
+	"json":    model.EncodeJSON,
+	"yaml":    model.EncodeYAML,
+	"svg":     render.SVG,
+	"png":     render.PNG,
+}
+
+// themedGenerators is the generators which draw the diagram with the theme given by the flag.
+var themedGenerators = map[string]func(io.Writer, *model.SequenceDiagram, *seq2xls.Theme) error{
+	"svg": render.SVGWithTheme,
+	"png": render.PNGWithTheme,
+}
+
+// outputExts is the file extensions of the output formats.
+var outputExts = map[string]string{
+	"xlsx":    ".xlsx",
+	"seqdiag": ".diag",
+	"puml":    ".puml",
+	"mermaid": ".mmd",
+	"json":    ".json",
+	"yaml":    ".yaml",
+	"svg":     ".svg",
+	"png":     ".png",
+}
+
+// commands is the subcommands by the name.
+var commands = map[string]func(args []string){
+	"convert": runConvert,
+	"watch":   runWatch,
+	"fmt":     runFmt,
+	"lint":    runLint,
+	"serve":   runServe,
+}
+
+const usage = `usage: seq2xls <command> [flags] [arguments]
+
+commands:
+  convert  convert the diagrams into xlsx or the other formats
+  watch    convert the diagrams again whenever they change
+  fmt      reformat the seqdiag files
+  lint     check the seqdiag files for likely mistakes
+  serve    start the HTTP server which renders the diagrams
+
+Run 'seq2xls <command> -h' for the flags of the command.
+The files given without a command are converted into *.xlsx next to them.
+`
+
 func main() {
-	switch runtime.GOOS {
-	case "windows":
-		runOnWindows()
-	default:
-		runOnLinux()
+	args := os.Args[1:]
+	if len(args) == 0 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	}
+
+	if run, ok := commands[args[0]]; ok {
+		run(args[1:])
+		return
+	}
+	if strings.HasPrefix(args[0], "-") {
+		// the flags without a command are for 'convert'
+		runConvert(args)
+		return
 	}
+	// the files dropped on the executable
+	runBatch(args, "", &outputOptions{format: "xlsx"}, runtime.NumCPU())
 }
 
-func runOnLinux() {
-	var inpath, outpath string
-	flag.StringVar(&inpath, "i", "-", "input file path")
-	flag.StringVar(&outpath, "o", "", "output file path")
-	flag.Parse()
-	if outpath == "" {
+// convertFlags is the flags shared by 'convert' and 'watch'.
+type convertFlags struct {
+	inpath, outpath, format, theme string
+	update                         bool
+}
+
+// outputOptions is the options of the conversion of each file.
+type outputOptions struct {
+	format string
+	update bool
+	// theme is the theme given by the flag, or nil to use the one of each diagram.
+	theme *seq2xls.Theme
+}
+
+func newConvertFlags(fs *flag.FlagSet) *convertFlags {
+	cf := &convertFlags{}
+	fs.StringVar(&cf.inpath, "i", "-", "input file path")
+	fs.StringVar(&cf.outpath, "o", "", "output file path, or '-' for the standard output; the output directory for the file arguments")
+	fs.BoolVar(&cf.update, "u", false, "update the existing output file keeping the manual edits")
+	fs.StringVar(&cf.format, "format", "xlsx", "output format: xlsx, seqdiag, puml, mermaid, json, yaml, svg or png")
+	fs.StringVar(&cf.theme, "theme", "", "theme of xlsx, svg and png: default, monochrome, high-contrast or the path of a theme file;\n"+
+		"it overrides the theme which the diagram selects")
+	return cf
+}
+
+// output returns the options of the conversion. It exits with the usage if the flags are invalid.
+func (cf *convertFlags) output(fs *flag.FlagSet) *outputOptions {
+	if _, ok := generators[cf.format]; !ok && cf.format != "xlsx" {
+		fmt.Printf("unknown output format '%s'\n\n", cf.format)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	o := &outputOptions{format: cf.format, update: cf.update}
+	if cf.theme != "" {
+		theme, err := seq2xls.LoadTheme(cf.theme)
+		if err != nil {
+			log.Fatalf("theme: %v", err)
+		}
+		o.theme = theme
+	}
+	return o
+}
+
+// runConvert converts the input file, or the files, directories and glob patterns in the arguments.
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	cf := newConvertFlags(fs)
+	workers := fs.Int("j", runtime.NumCPU(), "number of files converted in parallel for the file arguments")
+	fs.Parse(args)
+	o := cf.output(fs)
+
+	if fs.NArg() > 0 {
+		runBatch(fs.Args(), cf.outpath, o, *workers)
+		return
+	}
+	if cf.outpath == "" {
 		fmt.Printf("missing output file path\n\n")
-		flag.Usage()
+		fs.Usage()
+		os.Exit(2)
+	}
+	if err := convert(cf.inpath, cf.outpath, o); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// runBatch converts the files, the directories and the glob patterns in parallel and prints the summary.
+// The outputs are put into outdir mirroring the directories, or next to the inputs if outdir is empty.
+// It exits with the non-zero status if any of them fails, including the arguments which cannot be read.
+func runBatch(args []string, outdir string, o *outputOptions, workers int) {
+	if outdir == "-" {
+		log.Fatal("the file arguments cannot be written to the standard output")
+	}
+	jobs, results, err := batch.Expand(args, outdir, outputExts[o.format], isInputFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	results = append(results, batch.Run(jobs, workers, func(job batch.Job) error {
+		if job.Output == job.Input {
+			return errors.New("the output overwrites the input")
+		}
+		if err := os.MkdirAll(filepath.Dir(job.Output), 0755); err != nil {
+			return err
+		}
+		return convert(job.Input, job.Output, o)
+	})...)
+
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.Input, r.Err)
+			continue
+		}
+		fmt.Printf("ok   %s -> %s\n", r.Input, r.Output)
+	}
+	fmt.Printf("%d converted, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runWatch converts the input file, or each file in the input directory into the output directory,
+// and converts them again whenever they change. The errors are printed without exiting.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	cf := newConvertFlags(fs)
+	fs.Parse(args)
+	o := cf.output(fs)
+
+	inpath, outpath := cf.inpath, cf.outpath
+	if inpath == "-" || outpath == "" || outpath == "-" {
+		fmt.Printf("watch needs the input and output paths\n\n")
+		fs.Usage()
+		os.Exit(2)
+	}
+	info, err := os.Stat(inpath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	outputOf := func(path string) string { return outpath }
+	if info.IsDir() {
+		outputOf = func(path string) string {
+			rel, _ := filepath.Rel(inpath, path)
+			return filepath.Join(outpath, strings.TrimSuffix(rel, filepath.Ext(rel))+outputExts[o.format])
+		}
+	}
+
+	regenerate := func(paths []string) {
+		for _, path := range paths {
+			out := outputOf(path)
+			if out == path {
+				continue
+			}
+			err := os.MkdirAll(filepath.Dir(out), 0755)
+			if err == nil {
+				err = convert(path, out, o)
+			}
+			if err != nil {
+				log.Printf("%s: %v", path, err)
+				continue
+			}
+			log.Printf("%s -> %s", path, out)
+		}
+	}
+
+	w := watch.New([]string{inpath}, isInputFile)
+	regenerate(w.Files())
+	log.Printf("watching %s", inpath)
+	w.Run(nil, regenerate)
+}
+
+// runFmt reformats the given 'seqdiag' files in place.
+// If no file is given, it reformats the standard input and writes to the standard output.
+func runFmt(args []string) {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.Parse(args)
+	paths := fs.Args()
+	if len(paths) == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := printer.Format(b)
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		out, err := printer.Format(b)
+		if err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		if bytes.Equal(b, out) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		err = ioutil.WriteFile(path, out, info.Mode())
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// runLint checks the given 'seqdiag' files, or the standard input if no file is given, and prints the problems.
+// It exits with the non-zero status if any problem is found.
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the problems as a JSON array")
+	fs.Parse(args)
+
+	diags := []*lint.Diagnostic{}
+	if fs.NArg() == 0 {
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diags = lint.LintSource("<stdin>", b)
+	}
+	for _, path := range fs.Args() {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		diags = append(diags, lint.LintSource(path, b)...)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diags); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+	}
+	if len(diags) > 0 {
 		os.Exit(1)
 	}
-	convert(inpath, outpath)
 }
 
-func runOnWindows() {
-	flag.Parse()
-	for _, inpath := range flag.Args() {
-		ext := filepath.Ext(inpath)
-		outpath := inpath[0:len(inpath)-len(ext)] + ".xlsx"
-		convert(inpath, outpath)
+// runServe starts the HTTP server which renders the diagrams on demand.
+func runServe(args []string) {
+	var (
+		addr   string
+		limits server.Limits
+	)
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&addr, "addr", ":8080", "address to listen on")
+	fs.Int64Var(&limits.MaxBodySize, "max-body", server.DefaultLimits.MaxBodySize, "maximum size of a diagram in bytes")
+	fs.DurationVar(&limits.RenderTimeout, "timeout", server.DefaultLimits.RenderTimeout, "maximum time to render a diagram")
+	fs.IntVar(&limits.MaxRenders, "max-renders", server.DefaultLimits.MaxRenders, "maximum number of diagrams rendered at once")
+	fs.Parse(args)
+
+	s := &http.Server{
+		Addr:              addr,
+		Handler:           server.NewHandler(limits),
+		ReadHeaderTimeout: 10 * time.Second,
 	}
+	log.Printf("listening on %s", addr)
+	log.Fatal(s.ListenAndServe())
 }
 
-func convert(inpath, outpath string) {
+func convert(inpath, outpath string, o *outputOptions) error {
 	var (
 		b   []byte
 		err error
 	)
 
 	if inpath == "-" {
-		stdin := bufio.NewScanner(os.Stdin)
-		buf := new(bytes.Buffer)
-		buf.Grow(1024)
-		// read until eof
-		for stdin.Scan() {
-			buf.Write(stdin.Bytes())
-			buf.WriteString("\n")
-		}
-		b = buf.Bytes()
+		b, err = ioutil.ReadAll(os.Stdin)
 	} else {
 		b, err = ioutil.ReadFile(inpath)
+	}
+	if err != nil {
+		return err
+	}
+	format := inputFormat(inpath, b)
+	if format == seq2xls.FormatMarkdown {
+		if o.format != "xlsx" {
+			return errors.New("Markdown input supports only xlsx output")
+		}
+		if o.update {
+			return errors.New("update mode does not support Markdown input")
+		}
+	}
+
+	if generate, ok := generators[o.format]; ok {
+		if o.update {
+			return errors.New("update mode supports only xlsx output")
+		}
+		if draw, ok := themedGenerators[o.format]; ok {
+			generate = func(w io.Writer, seq *model.SequenceDiagram) error {
+				return draw(w, seq, o.theme)
+			}
+		}
+		seq, err := seq2xls.Parse(format, b)
 		if err != nil {
-			log.Fatal(err)
+			return err
+		}
+		buf := new(bytes.Buffer)
+		err = generate(buf, seq)
+		if err != nil {
+			return err
+		}
+		return writeOutput(outpath, buf.Bytes())
+	}
+
+	if o.update && outpath == "-" {
+		return errors.New("update mode needs the output file")
+	}
+	if o.update {
+		if _, err := os.Stat(outpath); err == nil {
+			seq, err := seq2xls.Parse(format, b)
+			if err != nil {
+				return err
+			}
+			return seq2xls.UpdateWorkbook(outpath, b, seq, seq2xls.WithTheme(o.theme))
 		}
 	}
-	d := seqdiag.ParseSeqdiag(b)
 
-	ss := oxml.NewSpreadsheet()
-	seq, err := convertor.AstToModel(d)
+	wb, err := seq2xls.Render(b, seq2xls.WithFormat(format), seq2xls.WithTheme(o.theme))
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	out, err := wb.Bytes()
+	if err != nil {
+		return err
+	}
+	return writeOutput(outpath, out)
+}
+
+// writeOutput writes the contents into the file, or into the standard output if the path is "-".
+func writeOutput(outpath string, b []byte) error {
+	if outpath == "-" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(outpath, b, 0644)
+}
+
+// isInputFile returns whether the file has the extension of an input format.
+func isInputFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	_, ok := inputFormats[ext]
+	return ok || ext == ".diag"
+}
+
+// inputFormat selects the input format by the file extension. The others are regarded as seqdiag.
+// The format of the standard input is guessed from its content.
+func inputFormat(inpath string, b []byte) string {
+	if inpath != "-" {
+		if format, ok := inputFormats[strings.ToLower(filepath.Ext(inpath))]; ok {
+			return format
+		}
+		return seq2xls.FormatSeqdiag
 	}
 
-	seq2xls.DrawSequenceDiagram(ss, seq)
-	ss.Dump(outpath)
+	return seq2xls.DetectFormat(b)
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rsp9u/seq2xls/seqdiag/generator"
+	"github.com/rsp9u/seq2xls/xls2seq"
+)
+
+func main() {
+	var inpath, outpath, sheet string
+	flag.StringVar(&inpath, "i", "", "input file path")
+	flag.StringVar(&outpath, "o", "-", "output file path")
+	flag.StringVar(&sheet, "sheet", "", "name of the worksheet to read, needed if the workbook has several")
+	flag.Parse()
+	if inpath == "" {
+		fmt.Printf("missing input file path\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	seq, err := xls2seq.ReadFileSheet(inpath, sheet)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if outpath == "-" {
+		err = generator.Generate(os.Stdout, seq)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	out, err := os.Create(outpath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = generator.Generate(out, seq)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
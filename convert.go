@@ -0,0 +1,236 @@
+package seq2xls
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/rsp9u/seq2xls/markdown"
+	"github.com/rsp9u/seq2xls/mermaid"
+	mmdconvertor "github.com/rsp9u/seq2xls/mermaid/convertor"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml"
+	pumlconvertor "github.com/rsp9u/seq2xls/plantuml/convertor"
+	"github.com/rsp9u/seq2xls/seqdiag"
+	"github.com/rsp9u/seq2xls/seqdiag/convertor"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// The input formats.
+const (
+	FormatSeqdiag  = "seqdiag"
+	FormatPlantUML = "plantuml"
+	FormatMermaid  = "mermaid"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatMarkdown = "markdown"
+)
+
+// blockFormats is the input formats by the language of the fenced code block in Markdown.
+var blockFormats = map[string]string{
+	"seqdiag":  FormatSeqdiag,
+	"plantuml": FormatPlantUML,
+	"puml":     FormatPlantUML,
+	"mermaid":  FormatMermaid,
+}
+
+// Option is an option of Convert and Render.
+type Option func(*options)
+
+type options struct {
+	format    string
+	sheetName string
+	layout    Layout
+	theme     *Theme
+}
+
+// WithFormat sets the input format such as FormatPlantUML.
+// Without this option, the format is detected from the input text by DetectFormat.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithSheetName sets the name of the worksheet. It is ignored for Markdown,
+// whose diagrams are named after their headings.
+func WithSheetName(name string) Option {
+	return func(o *options) {
+		o.sheetName = name
+	}
+}
+
+// WithLayout sets the spacing of the diagram.
+func WithLayout(layout Layout) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
+}
+
+// WithTheme sets the theme of the diagram, which takes precedence over the theme the diagram selects.
+func WithTheme(theme *Theme) Option {
+	return func(o *options) {
+		o.theme = theme
+	}
+}
+
+// newOptions applies the options to the defaults.
+func newOptions(opts []Option) *options {
+	o := &options{layout: DefaultLayout}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Convert reads a diagram text from r and writes it out to w as an xlsx file.
+func Convert(r io.Reader, w io.Writer, opts ...Option) error {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	wb, err := Render(src, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = wb.WriteTo(w)
+	return err
+}
+
+// Render parses the diagram text and draws it into a new workbook with the metadata.
+//
+// Each diagram in the fenced code blocks of a Markdown document is drawn into its own worksheet.
+func Render(src []byte, opts ...Option) (*xlsx.Workbook, error) {
+	o := newOptions(opts)
+	format := o.format
+	if format == "" {
+		format = DetectFormat(src)
+	}
+	if format == FormatMarkdown {
+		return renderMarkdown(src, o)
+	}
+
+	seq, err := Parse(format, src)
+	if err != nil {
+		return nil, err
+	}
+	theme, err := SelectTheme(seq, o.theme)
+	if err != nil {
+		return nil, err
+	}
+
+	wb := xlsx.NewWorkbook()
+	sheet := wb.Sheets()[0]
+	if o.sheetName != "" {
+		sheet.SetName(o.sheetName)
+	}
+	DrawSequenceDiagramWithTheme(sheet, seq, o.layout, theme)
+	err = EmbedSheetMetadata(wb, sheet, src, seq)
+	if err != nil {
+		return nil, err
+	}
+	return wb, nil
+}
+
+// renderMarkdown draws each diagram in the fenced code blocks of the Markdown
+// document into its own worksheet, which is named after the nearest heading.
+//
+// All the worksheets are added before drawing, so that the references can link to the diagrams below them.
+func renderMarkdown(src []byte, o *options) (*xlsx.Workbook, error) {
+	type sheetDiagram struct {
+		sheet *xlsx.Sheet
+		seq   *model.SequenceDiagram
+		theme *Theme
+		src   []byte
+	}
+
+	wb := xlsx.NewWorkbook()
+	diagrams := []*sheetDiagram{}
+	for _, block := range markdown.ExtractBlocks(src) {
+		format := blockFormats[block.Lang]
+		if format == "" {
+			continue
+		}
+		if format == FormatMermaid && DetectFormat(block.Text) != FormatMermaid {
+			// other kinds of Mermaid diagram such as flowchart
+			continue
+		}
+
+		seq, err := Parse(format, block.Text)
+		if err != nil {
+			return nil, fmt.Errorf("block at line %d: %v", block.Line, err)
+		}
+		theme, err := SelectTheme(seq, o.theme)
+		if err != nil {
+			return nil, fmt.Errorf("block at line %d: %v", block.Line, err)
+		}
+
+		var sheet *xlsx.Sheet
+		if len(diagrams) == 0 {
+			sheet = wb.Sheets()[0]
+			sheet.SetName(block.Heading)
+		} else {
+			sheet = wb.AddSheet(block.Heading)
+		}
+		diagrams = append(diagrams, &sheetDiagram{sheet, seq, theme, block.Text})
+	}
+	if len(diagrams) == 0 {
+		return nil, fmt.Errorf("no diagram is found in the Markdown document")
+	}
+
+	for _, d := range diagrams {
+		DrawSequenceDiagramWithTheme(d.sheet, d.seq, o.layout, d.theme)
+		err := EmbedSheetMetadata(wb, d.sheet, d.src, d.seq)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wb, nil
+}
+
+// DetectFormat guesses the input format from the beginning of the text.
+// The text which looks like none of the others is regarded as seqdiag.
+func DetectFormat(src []byte) string {
+	text := bytes.TrimSpace(src)
+	switch {
+	case bytes.HasPrefix(text, []byte("@startuml")):
+		return FormatPlantUML
+	case bytes.HasPrefix(text, []byte("sequenceDiagram")):
+		return FormatMermaid
+	case bytes.HasPrefix(text, []byte("{")) && json.Valid(text):
+		// seqdiag can begin with '{' too, but it is not a valid JSON
+		return FormatJSON
+	}
+	return FormatSeqdiag
+}
+
+// Parse parses the diagram text with the front end of the format and converts it to the drawable model.
+func Parse(format string, src []byte) (*model.SequenceDiagram, error) {
+	switch format {
+	case FormatPlantUML:
+		d, err := plantuml.ParsePlantUML(src)
+		if err != nil {
+			return nil, err
+		}
+		return pumlconvertor.AstToModel(d)
+	case FormatMermaid:
+		d, err := mermaid.ParseMermaid(src)
+		if err != nil {
+			return nil, err
+		}
+		return mmdconvertor.AstToModel(d)
+	case FormatJSON:
+		return model.DecodeJSON(bytes.NewReader(src))
+	case FormatYAML:
+		return model.DecodeYAML(bytes.NewReader(src))
+	case FormatSeqdiag, "":
+		d, err := seqdiag.Parse(src)
+		if err != nil {
+			return nil, err
+		}
+		return convertor.AstToModel(d)
+	}
+	return nil, fmt.Errorf("unknown input format '%s'", format)
+}
@@ -0,0 +1,105 @@
+package seq2xls
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+const convertTestJSON = `{
+  "version": 1,
+  "lifelines": [{"id": "a", "name": "client"}, {"id": "b", "name": "server"}],
+  "messages": [{"id": "m", "from": "a", "to": "b", "text": "GET"}]
+}`
+
+func TestConvert(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := Convert(strings.NewReader(convertTestJSON), buf, WithSheetName("api"))
+	if err != nil {
+		t.Fatalf("Convert error %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Output is not a zip archive: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "xl/workbook.xml" {
+			continue
+		}
+		r, _ := f.Open()
+		b, _ := ioutil.ReadAll(r)
+		r.Close()
+		if !bytes.Contains(b, []byte(`name="api"`)) {
+			t.Errorf("Sheet name is not set: %s", b)
+		}
+		return
+	}
+	t.Fatalf("xl/workbook.xml is not found")
+}
+
+func TestRenderWithLayout(t *testing.T) {
+	anchorOf := func(opts ...Option) string {
+		wb, err := Render([]byte(convertTestJSON), opts...)
+		if err != nil {
+			t.Fatalf("Render error %v", err)
+		}
+		for _, s := range wb.Shapes() {
+			anchor, _ := xlsx.MarshalAnchor(s)
+			if anchor.Descr == ShapeDescrPrefix+"lifeline-1" {
+				return string(anchor.XML)
+			}
+		}
+		t.Fatalf("lifeline-1 is not drawn")
+		return ""
+	}
+
+	if anchorOf() == anchorOf(WithLayout(Layout{LifelineSpan: 400})) {
+		t.Errorf("Layout does not move the lifeline")
+	}
+	if anchorOf() != anchorOf(WithLayout(Layout{})) {
+		t.Errorf("Empty layout is not the default")
+	}
+}
+
+const convertTestMarkdown = "# Overview\n\n" +
+	"```plantuml\n@startuml\na -> b\nref over a, b : Login\n@enduml\n```\n\n" +
+	"# Login\n\n" +
+	"```plantuml\n@startuml\na -> b : password\n@enduml\n```\n"
+
+func TestRenderMarkdownRef(t *testing.T) {
+	wb, err := Render([]byte(convertTestMarkdown), WithFormat(FormatMarkdown))
+	if err != nil {
+		t.Fatalf("Render error %v", err)
+	}
+	for _, s := range wb.Shapes() {
+		ds, ok := s.(*xlsx.Shape)
+		if !ok || ds.Hyperlink() == "" {
+			continue
+		}
+		if ds.Hyperlink() != "#'Login'!A1" {
+			t.Errorf("The reference links to the wrong target %s", ds.Hyperlink())
+		}
+		return
+	}
+	t.Errorf("The reference does not link to the sheet below it")
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"@startuml\na -> b\n@enduml": FormatPlantUML,
+		"  sequenceDiagram\n":        FormatMermaid,
+		convertTestJSON:              FormatJSON,
+		"{ a -> b }":                 FormatSeqdiag,
+		"seqdiag { a -> b }":         FormatSeqdiag,
+	}
+	for src, expected := range tests {
+		if actual := DetectFormat([]byte(src)); actual != expected {
+			t.Errorf("Mismatches format of %q: expect %s, actual %s", src, expected, actual)
+		}
+	}
+}
@@ -5,80 +5,286 @@ import (
 	"strings"
 
 	"github.com/golang-collections/collections/stack"
-	"github.com/rsp9u/go-xlsshape/oxml"
 	"github.com/rsp9u/go-xlsshape/oxml/shape"
 	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
 )
 
+// Canvas is a drawing area where the shapes are added, such as *oxml.Spreadsheet and *xlsx.Workbook.
+type Canvas interface {
+	AddShape(s shape.Shape)
+	UnshiftShape(s shape.Shape)
+}
+
 type fragmentReserve struct {
 	left, right, top, bottom int
+	operandTops              []int
 	leftLifeline             *model.Lifeline
 	rightLifeline            *model.Lifeline
 	body                     *model.Fragment
 }
 
+type refReserve struct {
+	left, right, top, bottom int
+	body                     *model.Fragment
+}
+
 const (
 	marginX     = 20
 	marginY     = 20
 	sizeX       = 6 * 20
 	sizeY       = 3 * 20
-	spanX       = 192
-	spanY       = 40
-	tailY       = spanY * 3 / 2
-	fragOffsetX = spanX / 3
 	fragMarginX = 8
 	fragMarginY = 24
 	fragGuardX  = 48
 	fragGuardY  = 24
+	execWidth   = 12
+	// constraintMarginX is the distance of the brackets of the constraints from the diagram and each other,
+	// and constraintTickX is the length of the ticks at their ends.
+	constraintMarginX = 24
+	constraintTickX   = 8
 )
 
+// ShapeDescrPrefix is the prefix of the description of the shapes drawn by seq2xls.
+// The identifier of the model element follows it.
+const ShapeDescrPrefix = "seq2xls:"
+
+// Layout is the spacing of the diagram in pixels.
+type Layout struct {
+	// LifelineSpan is the horizontal distance between the centers of the adjacent lifelines.
+	LifelineSpan int
+	// MessageSpan is the vertical distance between the messages.
+	MessageSpan int
+	// MaxLabelWidth is the maximum width of the labels of the messages, which are wrapped to fit in it.
+	// If it is not positive, the labels fit in the distance between the lifelines of the message, or LifelineSpan.
+	MaxLabelWidth int
+	// DelaySpan is the height of the gap of the delays.
+	DelaySpan int
+}
+
+// DefaultLayout is the layout used by DrawSequenceDiagram.
+var DefaultLayout = Layout{LifelineSpan: 192, MessageSpan: 40, DelaySpan: 60}
+
+// delayGap is the vertical range of a delay, over which the lifelines are dotted.
+type delayGap struct {
+	top, bottom int
+}
+
+// drawer holds the drawing area and the settings while drawing a diagram.
+type drawer struct {
+	ss           Canvas
+	spanX, spanY int
+	labelWidth   int
+	delaySpan    int
+	theme        *Theme
+	// delays is the gaps of the delays drawn in the timeline from top to bottom.
+	delays []delayGap
+	// fontFamily and lang are the defaults of the text which the diagram gives.
+	fontFamily, lang string
+}
+
 // DrawSequenceDiagram draws a sequence diagram into the given spreadsheet.
-func DrawSequenceDiagram(ss *oxml.Spreadsheet, seq *model.SequenceDiagram) {
-	bottom := drawTimeline(ss, seq)
-	drawLifelines(ss, seq.Lifelines, bottom)
+func DrawSequenceDiagram(ss Canvas, seq *model.SequenceDiagram) {
+	DrawSequenceDiagramWithLayout(ss, seq, DefaultLayout)
+}
+
+// DrawSequenceDiagramWithLayout draws a sequence diagram into the given spreadsheet with the layout and DefaultTheme.
+// The spans in the layout which are not positive are replaced with the defaults.
+func DrawSequenceDiagramWithLayout(ss Canvas, seq *model.SequenceDiagram, layout Layout) {
+	DrawSequenceDiagramWithTheme(ss, seq, layout, nil)
+}
+
+// DrawSequenceDiagramWithTheme draws a sequence diagram into the given spreadsheet with the layout and the theme,
+// or DefaultTheme if it is nil. The theme which the diagram selects is not looked up here, so SelectTheme
+// gives the theme to draw the diagram with its own one.
+func DrawSequenceDiagramWithTheme(ss Canvas, seq *model.SequenceDiagram, layout Layout, theme *Theme) {
+	if theme == nil {
+		theme = &DefaultTheme
+	}
+	d := &drawer{
+		ss:         ss,
+		spanX:      layout.LifelineSpan,
+		spanY:      layout.MessageSpan,
+		labelWidth: layout.MaxLabelWidth,
+		delaySpan:  layout.DelaySpan,
+		theme:      theme,
+		fontFamily: seq.FontFamily,
+		lang:       seq.Lang,
+	}
+	if d.spanX <= 0 {
+		d.spanX = DefaultLayout.LifelineSpan
+	}
+	if d.spanY <= 0 {
+		d.spanY = DefaultLayout.MessageSpan
+	}
+	if d.delaySpan <= 0 {
+		d.delaySpan = DefaultLayout.DelaySpan
+	}
+
+	bottom, msgTops := d.drawTimeline(seq)
+	d.drawExecSpecs(seq.ExecSpecs, msgTops)
+	d.drawConstraints(seq, msgTops)
+	d.drawLifelines(seq.Lifelines, bottom)
 }
 
 // drawLifelines adds the shapes which composes 'Lifeline' into the spreadsheet.
 //
-// 'Lifeline' is composed of a rectangle and a dashed line.
-func drawLifelines(ss *oxml.Spreadsheet, lls []*model.Lifeline, bottom int) {
+// 'Lifeline' is composed of a rectangle and a dashed line, which is dotted over the gaps of the delays.
+func (d *drawer) drawLifelines(lls []*model.Lifeline, bottom int) {
 	for _, ll := range lls {
 		i := ll.Index
 		rect := shape.NewRectangle()
-		rect.SetLeftTop(marginX+spanX*i, marginY)
+		rect.SetLeftTop(marginX+d.spanX*i, marginY)
 		rect.SetSize(sizeX, sizeY)
-		rect.SetText(ll.Name, "en-US")
 		rect.SetHAlign("ctr")
 		rect.SetVAlign("ctr")
-		ss.AddShape(rect)
+		d.ss.AddShape(tag(d.styleRect(rect, ll.Name, d.theme.Lifeline, ""), ll.ID()))
+
+		rectXCenter := d.lifelineCenterX(ll)
+		top := marginY + sizeY
+		dotted := d.theme.LifelineLine
+		dotted.Dash = "dot"
+		for _, gap := range d.delays {
+			d.drawLifelineLine(ll, rectXCenter, top, gap.top, d.theme.LifelineLine)
+			d.drawLifelineLine(ll, rectXCenter, gap.top, gap.bottom, dotted)
+			top = gap.bottom
+		}
+		d.drawLifelineLine(ll, rectXCenter, top, bottom+d.spanY*3/2, d.theme.LifelineLine)
+	}
+}
 
-		rectXCenter := calcLifelineCenterX(ll)
-		rectBottom := marginY + sizeY
-		line := shape.NewLine()
-		line.SetStartPos(rectXCenter, rectBottom)
-		line.SetEndPos(rectXCenter, bottom+tailY)
-		line.SetDashType("dash")
-		ss.UnshiftShape(line)
+// drawLifelineLine adds a part of the line of the lifeline under the other shapes.
+func (d *drawer) drawLifelineLine(ll *model.Lifeline, x, top, bottom int, style LineStyle) {
+	line := shape.NewLine()
+	line.SetStartPos(x, top)
+	line.SetEndPos(x, bottom)
+	d.ss.UnshiftShape(tag(styleLine(line, style), ll.ID()))
+}
+
+func (d *drawer) lifelineCenterX(ll *model.Lifeline) int {
+	return marginX + d.spanX*ll.Index + sizeX/2
+}
+
+// drawExecSpecs adds the shapes of the execution specifications into the spreadsheet.
+//
+// The nested execution specifications on the same lifeline are shifted to the right.
+func (d *drawer) drawExecSpecs(specs []*model.ExecSpec, msgTops map[*model.Message]int) {
+	type placedSpec struct {
+		top, bottom int
+		body        *model.ExecSpec
+	}
+	placed := []*placedSpec{}
+
+	for _, spec := range specs {
+		top := msgTops[spec.Begin] + d.spanY/2
+		bottom := msgTops[spec.End] + d.spanY/2
+		if spec.End.Type == model.SelfReference {
+			bottom += d.spanY / 3
+		}
+		if bottom-top < d.spanY/2 {
+			bottom = top + d.spanY/2
+		}
+
+		level := 0
+		for _, p := range placed {
+			if p.body.Assoc == spec.Assoc && p.top <= top && top <= p.bottom {
+				level++
+			}
+		}
+		placed = append(placed, &placedSpec{top, bottom, spec})
+
+		rect := shape.NewRectangle()
+		rect.SetLeftTop(d.lifelineCenterX(spec.Assoc)-execWidth/2+level*execWidth/2, top)
+		rect.SetSize(execWidth, bottom-top)
+		d.ss.UnshiftShape(tag(d.styleRect(rect, "", d.theme.ExecSpec, spec.ColorHex), spec.ID()))
 	}
 }
 
-func calcLifelineCenterX(ll *model.Lifeline) int {
-	return marginX + spanX*ll.Index + sizeX/2
+// drawConstraints adds the brackets of the duration constraints with their text on the right of the diagram.
+//
+// The bracket spans from the line of the earlier message to the one of the later message, and
+// the overlapping constraints are shifted to the right of the former ones with their text.
+func (d *drawer) drawConstraints(seq *model.SequenceDiagram, msgTops map[*model.Message]int) {
+	type placedConstraint struct {
+		top, bottom, right int
+	}
+	placed := []*placedConstraint{}
+	edge := marginX + d.spanX*(len(seq.Lifelines)-1) + sizeX
+
+	for _, c := range seq.Constraints {
+		top := msgTops[c.Begin] + d.spanY/2
+		bottom := msgTops[c.End] + d.spanY/2
+		if c.End.Type == model.SelfReference {
+			bottom += d.spanY / 3
+		}
+
+		left := edge
+		for i := c.Begin.Index; i <= c.End.Index; i++ {
+			msg := seq.Messages[i]
+			if msg.Text == "" {
+				continue
+			}
+			boxLeft, _, w, _ := d.labelBox(msg, msgTops[msg])
+			if boxLeft+w > left {
+				left = boxLeft + w
+			}
+		}
+		for _, p := range placed {
+			if p.top <= bottom && top <= p.bottom && p.right > left {
+				left = p.right
+			}
+		}
+		x := left + constraintMarginX
+
+		style := d.theme.Constraint
+		bracket := shape.NewLine()
+		bracket.SetStartPos(x, top)
+		bracket.SetEndPos(x, bottom)
+		bracket.SetHeadType("arrow")
+		bracket.SetTailType("arrow")
+		d.ss.AddShape(tag(styleLine(bracket, style.Line), c.ID()))
+		for _, y := range []int{top, bottom} {
+			tick := shape.NewLine()
+			tick.SetStartPos(x-constraintTickX, y)
+			tick.SetEndPos(x+constraintTickX, y)
+			d.ss.AddShape(tag(styleLine(tick, style.Line), c.ID()))
+		}
+
+		right := x + constraintTickX
+		if c.Text != "" {
+			w, h := textSize(c.Text, style.Font.Size)
+			w, h = w+labelPaddingX*2, h+labelPaddingY*2
+			textbox := shape.NewRectangle()
+			textbox.SetNoFill(true)
+			textbox.SetNoLine(true)
+			textbox.SetLeftTop(x, (top+bottom)/2-h/2)
+			textbox.SetSize(w, h)
+			textbox.SetVAlign("ctr")
+			d.ss.AddShape(tag(d.styleText(textbox, c.Text, style.Font), c.ID()))
+			right = x + w
+		}
+		placed = append(placed, &placedConstraint{top, bottom, right})
+	}
 }
 
 // drawTimeline adds the shapes of the time series elements into the spreadsheet.
-func drawTimeline(ss *oxml.Spreadsheet, seq *model.SequenceDiagram) (y int) {
-	y = marginY + sizeY + spanY
+//
+// It returns the bottom of the timeline and the top of the area of each message.
+func (d *drawer) drawTimeline(seq *model.SequenceDiagram) (y int, msgTops map[*model.Message]int) {
+	y = marginY + sizeY + d.spanY
+	msgTops = map[*model.Message]int{}
 	fragRsvs := stack.New()
+	openFrags := map[*model.Fragment]*fragmentReserve{}
 	fragLimitLeft := 0
 	fragLimitRight := math.MaxInt32
 
 	for _, sep := range seq.Separators {
 		if sep.Before == nil {
-			deltaY := drawSeparator(ss, sep, y, len(seq.Lifelines))
+			deltaY := d.drawSeparator(sep, y, len(seq.Lifelines))
 			y += deltaY
 		}
 	}
+	y += d.drawRefs(seq, nil, y, fragLimitLeft, fragLimitRight)
 
 	for _, msg := range seq.Messages {
 		// fragment opening
@@ -86,36 +292,55 @@ func drawTimeline(ss *oxml.Spreadsheet, seq *model.SequenceDiagram) (y int) {
 			if frag.Begin == msg {
 				leftll, rightll := getBothEndsLifeline(frag, seq.Messages)
 
-				left := calcLifelineCenterX(leftll) - fragOffsetX
+				left := d.lifelineCenterX(leftll) - d.spanX/3
 				if left <= fragLimitLeft {
 					left = fragLimitLeft + fragMarginX
 				}
 				fragLimitLeft = left
 
-				right := calcLifelineCenterX(rightll) + fragOffsetX
+				right := d.lifelineCenterX(rightll) + d.spanX/3
 				if right >= fragLimitRight {
 					right = fragLimitRight - fragMarginX
 				}
 				fragLimitRight = right
 
-				fragRsvs.Push(&fragmentReserve{
+				rsv := &fragmentReserve{
 					top:           y,
 					left:          left,
 					right:         right,
 					leftLifeline:  leftll,
 					rightLifeline: rightll,
 					body:          frag,
-				})
+				}
+				fragRsvs.Push(rsv)
+				openFrags[frag] = rsv
 				y += fragMarginY
 			}
 		}
 
+		// operand partitioning
+		for _, frag := range seq.Fragments {
+			rsv, ok := openFrags[frag]
+			if !ok {
+				continue
+			}
+			for _, op := range frag.Operands {
+				if op.Begin == msg {
+					rsv.operandTops = append(rsv.operandTops, y)
+					y += fragMarginY
+				}
+			}
+		}
+
 		// proceed a message
+		above, _ := d.labelOverflow(msg)
+		y += above
+		msgTops[msg] = y
 		deltaY := 0
-		deltaY += drawMessage(ss, msg, y)
+		deltaY += d.drawMessage(msg, y)
 		for _, note := range seq.Notes {
 			if note.Assoc == msg {
-				deltaY += drawNote(ss, note, y)
+				deltaY += d.drawNote(note, y)
 			}
 		}
 
@@ -126,10 +351,11 @@ func drawTimeline(ss *oxml.Spreadsheet, seq *model.SequenceDiagram) (y int) {
 				break
 			}
 			fragRsvs.Pop()
+			delete(openFrags, frag.body)
 			y += fragMarginY
 			frag.bottom = y
 
-			drawFragment(ss, frag)
+			d.drawFragment(frag)
 		}
 		if fragRsvs.Len() != 0 {
 			frag, ok := fragRsvs.Peek().(*fragmentReserve)
@@ -146,96 +372,183 @@ func drawTimeline(ss *oxml.Spreadsheet, seq *model.SequenceDiagram) (y int) {
 
 		for _, sep := range seq.Separators {
 			if sep.Before == msg {
-				deltaY := drawSeparator(ss, sep, y, len(seq.Lifelines))
+				deltaY := d.drawSeparator(sep, y, len(seq.Lifelines))
 				y += deltaY
 			}
 		}
+		y += d.drawRefs(seq, msg, y, fragLimitLeft, fragLimitRight)
 	}
 
 	return
 }
 
-func drawMessage(ss *oxml.Spreadsheet, msg *model.Message, y int) (deltaY int) {
-	y += spanY / 2
+func (d *drawer) drawMessage(msg *model.Message, y int) (deltaY int) {
+	y += d.spanY / 2
+	style := d.theme.Messages.Of(msg.Type)
 	if msg.Type != model.SelfReference {
 		line := shape.NewLine()
-		line.SetStartPos(calcLifelineCenterX(msg.From), y)
-		line.SetEndPos(calcLifelineCenterX(msg.To), y)
+		line.SetStartPos(d.lifelineCenterX(msg.From), y)
+		line.SetEndPos(d.lifelineCenterX(msg.To), y)
 		switch msg.Type {
-		case model.Asynchronous:
-			line.SetTailType("arrow")
-		case model.Reply:
+		case model.Asynchronous, model.Reply:
 			line.SetTailType("arrow")
-			line.SetDashType("dash")
 		default:
 			line.SetTailType("triangle")
 		}
-		ss.AddShape(line)
+		d.ss.AddShape(tag(styleLine(line, style), msg.ID()))
 	} else {
-		w := spanX / 3
-		h := spanY / 3
+		w := d.spanX / 3
+		h := d.spanY / 3
 		line1 := shape.NewLine()
 		line2 := shape.NewLine()
 		line3 := shape.NewLine()
-		line1.SetStartPos(calcLifelineCenterX(msg.From), y)
-		line1.SetEndPos(calcLifelineCenterX(msg.From)+w, y)
-		line2.SetStartPos(calcLifelineCenterX(msg.From)+w, y)
-		line2.SetEndPos(calcLifelineCenterX(msg.From)+w, y+h)
-		line3.SetStartPos(calcLifelineCenterX(msg.From)+w, y+h)
-		line3.SetEndPos(calcLifelineCenterX(msg.From), y+h)
+		line1.SetStartPos(d.lifelineCenterX(msg.From), y)
+		line1.SetEndPos(d.lifelineCenterX(msg.From)+w, y)
+		line2.SetStartPos(d.lifelineCenterX(msg.From)+w, y)
+		line2.SetEndPos(d.lifelineCenterX(msg.From)+w, y+h)
+		line3.SetStartPos(d.lifelineCenterX(msg.From)+w, y+h)
+		line3.SetEndPos(d.lifelineCenterX(msg.From), y+h)
 		line3.SetTailType("triangle")
-		ss.AddShape(line1)
-		ss.AddShape(line2)
-		ss.AddShape(line3)
+		d.ss.AddShape(tag(styleLine(line1, style), msg.ID()))
+		d.ss.AddShape(tag(styleLine(line2, style), msg.ID()))
+		d.ss.AddShape(tag(styleLine(line3, style), msg.ID()))
 	}
 
 	if msg.Text != "" {
-		var c int
-		if msg.From.Index < msg.To.Index {
-			c = calcLifelineCenterX(msg.From)
-		} else {
-			c = calcLifelineCenterX(msg.To)
-		}
+		left, top, w, h := d.labelBox(msg, y-d.spanY/2)
 		textbox := shape.NewRectangle()
 		textbox.SetNoFill(true)
 		textbox.SetNoLine(true)
-		textbox.SetText(msg.Text, "en-US")
-		textbox.SetLeftTop(c, y-20)
-		textbox.SetSize(spanX, spanY)
-		ss.AddShape(textbox)
+		textbox.SetLeftTop(left, top)
+		textbox.SetSize(w, h)
+		// the label keeps its own line breaks, and the spreadsheet applications wrap it in the box
+		textbox.SetWrapType("square")
+		if msg.Type != model.SelfReference {
+			switch msg.LabelPosition {
+			case model.LabelCenter:
+				textbox.SetHAlign("ctr")
+			case model.LabelRight:
+				textbox.SetHAlign("r")
+			}
+		}
+		d.ss.AddShape(tag(d.styleText(textbox, msg.Text, d.theme.MessageText), msg.ID()))
 	}
 
+	_, below := d.labelOverflow(msg)
+	return d.messageHeight(msg) + below
+}
+
+// messageHeight returns the height of the area of the message without the overflow of its label.
+func (d *drawer) messageHeight(msg *model.Message) int {
 	if msg.Type == model.SelfReference {
-		return spanY + spanY/3
+		return d.spanY + d.spanY/3
+	}
+	return d.spanY
+}
+
+// labelBox returns the text box of the label of the message when the area of the message begins at top,
+// which is large enough for the label wrapped to fit in the maximum width.
+//
+// The label is put above or below the line by the label position, and the label of the self-reference
+// is put on the right of the loop.
+func (d *drawer) labelBox(msg *model.Message, top int) (boxLeft, boxTop, w, h int) {
+	left, right := d.lifelineCenterX(msg.From), d.lifelineCenterX(msg.To)
+	if left > right {
+		left, right = right, left
+	}
+	loopW, loopH := d.spanX/3, d.spanY/3
+
+	maxWidth := d.labelWidth
+	switch {
+	case maxWidth > 0:
+	case msg.Type == model.SelfReference:
+		maxWidth = d.spanX - loopW
+	case right-left < d.spanX:
+		maxWidth = d.spanX
+	default:
+		maxWidth = right - left
+	}
+	size := d.theme.MessageText.Size
+	w, h = textSize(WrapText(msg.Text, maxWidth-labelPaddingX*2, size), size)
+	w, h = w+labelPaddingX*2, h+labelPaddingY*2
+
+	lineY := top + d.spanY/2
+	// the last line of the label is placed right above the line by default
+	boxLeft, boxTop = left, lineY-h+labelPaddingY
+	switch {
+	case msg.Type == model.SelfReference:
+		boxLeft, boxTop = left+loopW, lineY+loopH/2-h/2
+	case msg.LabelPosition == model.LabelCenter:
+		boxLeft = (left+right)/2 - w/2
+	case msg.LabelPosition == model.LabelRight:
+		boxLeft = right - w
+	case msg.LabelPosition == model.LabelBelow:
+		boxTop = lineY
+	}
+	return
+}
+
+// labelOverflow returns the heights by which the label of the message exceeds the area of the message
+// above and below. The message is moved down by the former, and the next one by the latter,
+// so that the label overlaps neither of the adjacent messages.
+func (d *drawer) labelOverflow(msg *model.Message) (above, below int) {
+	if msg.Text == "" {
+		return 0, 0
+	}
+	_, top, _, h := d.labelBox(msg, 0)
+	if top < 0 {
+		above = -top
 	}
-	return spanY
+	if bottom := top + h - d.messageHeight(msg); bottom > 0 {
+		below = bottom
+	}
+	return above, below
 }
 
-func drawNote(ss *oxml.Spreadsheet, note *model.Note, y int) (deltaY int) {
-	w := maxLine(note.Text) * 8
-	h := (len(strings.Split(note.Text, "\n"))+1)*15 + 8
+func (d *drawer) drawNote(note *model.Note, y int) (deltaY int) {
+	w, h := textSize(note.Text, d.theme.Note.Font.Size)
+	w += labelPaddingX * 2
+	h += labelPaddingY * 2
 
 	rect := shape.NewRectangle()
-	rect.SetSize(w, h)
-	rect.SetText(note.Text, "en-US")
-	rect.SetFillColor(note.ColorHex)
-	if note.OnLeft {
-		rect.SetLeftTop(calcLifelineCenterX(note.Assoc.From)-12-w, y)
-	} else {
-		rect.SetLeftTop(calcLifelineCenterX(note.Assoc.To)+12, y)
+	switch {
+	case note.Over && len(note.Lifelines) > 0:
+		left, right := math.MaxInt32, 0
+		for _, ll := range note.Lifelines {
+			c := d.lifelineCenterX(ll)
+			if c-sizeX/2 < left {
+				left = c - sizeX/2
+			}
+			if c+sizeX/2 > right {
+				right = c + sizeX/2
+			}
+		}
+		if right-left < w {
+			left = (left+right)/2 - w/2
+			right = left + w
+		}
+		w = right - left
+		rect.SetLeftTop(left, y)
+	case len(note.Lifelines) > 0 && note.OnLeft:
+		rect.SetLeftTop(d.lifelineCenterX(note.Lifelines[0])-12-w, y)
+	case len(note.Lifelines) > 0:
+		rect.SetLeftTop(d.lifelineCenterX(note.Lifelines[0])+12, y)
+	case note.OnLeft:
+		rect.SetLeftTop(d.lifelineCenterX(note.Assoc.From)-12-w, y)
+	default:
+		rect.SetLeftTop(d.lifelineCenterX(note.Assoc.To)+12, y)
 	}
-	ss.AddShape(rect)
+	rect.SetSize(w, h)
+	d.ss.AddShape(tag(d.styleRect(rect, note.Text, d.theme.Note, note.ColorHex), note.ID()))
 
 	return 0
 }
 
-func drawFragment(ss *oxml.Spreadsheet, frag *fragmentReserve) {
+func (d *drawer) drawFragment(frag *fragmentReserve) {
 	rect := shape.NewRectangle()
 	rect.SetLeftTop(frag.left, frag.top)
 	rect.SetSize(frag.right-frag.left, frag.bottom-frag.top)
-	rect.SetNoFill(true)
-	rect.SetText(frag.body.Type.String(), "en-US")
-	ss.AddShape(rect)
+	d.ss.AddShape(tag(d.styleRect(rect, frag.body.Type.String(), d.theme.Fragment, ""), frag.body.ID()))
 
 	line1 := shape.NewLine()
 	line2 := shape.NewLine()
@@ -243,45 +556,276 @@ func drawFragment(ss *oxml.Spreadsheet, frag *fragmentReserve) {
 	line1.SetEndPos(frag.left+fragGuardX, frag.top+fragGuardY)
 	line2.SetStartPos(frag.left+fragGuardX, frag.top+fragGuardY)
 	line2.SetEndPos(frag.left+fragGuardX+12, frag.top)
-	ss.AddShape(line1)
-	ss.AddShape(line2)
+	d.ss.AddShape(tag(styleLine(line1, d.theme.Fragment.Line), frag.body.ID()))
+	d.ss.AddShape(tag(styleLine(line2, d.theme.Fragment.Line), frag.body.ID()))
+
+	if frag.body.Text != "" {
+		text := "[" + frag.body.Text + "]"
+		if frag.body.Type == model.Group {
+			text = frag.body.Text
+		}
+		d.drawGuard(frag, frag.left+fragGuardX+16, frag.top, text)
+	}
+
+	for i, top := range frag.operandTops {
+		line := shape.NewLine()
+		line.SetStartPos(frag.left, top)
+		line.SetEndPos(frag.right, top)
+		line.SetDashType("dash")
+		d.ss.AddShape(tag(styleLine(line, d.theme.Fragment.Line), frag.body.ID()))
+
+		if text := frag.body.Operands[i].Text; text != "" {
+			d.drawGuard(frag, frag.left+fragMarginX, top, "["+text+"]")
+		}
+	}
+}
+
+// drawGuard adds the text of the guard condition or the label of the fragment.
+func (d *drawer) drawGuard(frag *fragmentReserve, left, top int, text string) {
+	textbox := shape.NewRectangle()
+	textbox.SetNoFill(true)
+	textbox.SetNoLine(true)
+	textbox.SetLeftTop(left, top)
+	textbox.SetSize(frag.right-left, fragGuardY)
+	d.ss.AddShape(tag(d.styleText(textbox, text, d.theme.Fragment.Font), frag.body.ID()))
 }
 
-func drawSeparator(ss *oxml.Spreadsheet, sep *model.Separator, y, nLls int) (deltaY int) {
+// drawRefs adds the references which are put after the message, or at the top if it is nil,
+// within the limits of the enclosing fragment.
+func (d *drawer) drawRefs(seq *model.SequenceDiagram, before *model.Message, y, limitLeft, limitRight int) (deltaY int) {
+	for _, frag := range seq.Fragments {
+		if frag.Type != model.Ref || frag.Before != before {
+			continue
+		}
+		leftll, rightll := getRefBothEndsLifeline(frag, seq.Lifelines)
+		if leftll == nil {
+			continue
+		}
+
+		left := d.lifelineCenterX(leftll) - d.spanX/3
+		if left <= limitLeft {
+			left = limitLeft + fragMarginX
+		}
+		right := d.lifelineCenterX(rightll) + d.spanX/3
+		if right >= limitRight {
+			right = limitRight - fragMarginX
+		}
+
+		size := d.theme.Fragment.Font.Size
+		_, h := textSize(WrapText(frag.Text, right-left-labelPaddingX*2, size), size)
+		h += labelPaddingY * 2
+		if h < fragGuardY {
+			h = fragGuardY
+		}
+
+		top := y + deltaY + fragMarginX
+		d.drawRef(&refReserve{left, right, top, top + fragGuardY + h, frag})
+		deltaY += fragGuardY + h + fragMarginX*2
+	}
+	return
+}
+
+// drawRef adds the frame of the reference, which is filled to hide the lifelines under it.
+// The frame and the name link to the referred diagram.
+func (d *drawer) drawRef(ref *refReserve) {
+	link := d.refLink(ref.body)
+
+	rect := shape.NewRectangle()
+	rect.SetLeftTop(ref.left, ref.top)
+	rect.SetSize(ref.right-ref.left, ref.bottom-ref.top)
+	frame := d.styleRect(rect, ref.body.Type.String(), d.theme.Fragment, "FFFFFF")
+	frame.SetHyperlink(link)
+	d.ss.AddShape(tag(frame, ref.body.ID()))
+
+	line1 := shape.NewLine()
+	line2 := shape.NewLine()
+	line1.SetStartPos(ref.left, ref.top+fragGuardY)
+	line1.SetEndPos(ref.left+fragGuardX, ref.top+fragGuardY)
+	line2.SetStartPos(ref.left+fragGuardX, ref.top+fragGuardY)
+	line2.SetEndPos(ref.left+fragGuardX+12, ref.top)
+	d.ss.AddShape(tag(styleLine(line1, d.theme.Fragment.Line), ref.body.ID()))
+	d.ss.AddShape(tag(styleLine(line2, d.theme.Fragment.Line), ref.body.ID()))
+
+	textbox := shape.NewRectangle()
+	textbox.SetNoFill(true)
+	textbox.SetNoLine(true)
+	textbox.SetLeftTop(ref.left, ref.top+fragGuardY)
+	textbox.SetSize(ref.right-ref.left, ref.bottom-ref.top-fragGuardY)
+	textbox.SetHAlign("ctr")
+	textbox.SetVAlign("ctr")
+	textbox.SetWrapType("square")
+	name := d.styleText(textbox, ref.body.Text, d.theme.Fragment.Font)
+	name.SetHyperlink(link)
+	d.ss.AddShape(tag(name, ref.body.ID()))
+}
+
+// refLink returns the target of the link of the reference. It is the worksheet named by the link or the name
+// of the reference if the workbook has it, and otherwise the path given by the link, or the workbook named
+// after the reference next to this one. The line breaks in the name are taken as spaces.
+func (d *drawer) refLink(frag *model.Fragment) string {
+	name := frag.Link
+	if name == "" {
+		name = frag.Text
+	}
+	name = strings.Join(strings.FieldsFunc(name, func(r rune) bool { return r == '\n' || r == '\r' }), " ")
+	if name == "" {
+		return ""
+	}
+
+	var wb *xlsx.Workbook
+	switch c := d.ss.(type) {
+	case *xlsx.Sheet:
+		wb = c.Workbook()
+	case *xlsx.Workbook:
+		wb = c
+	}
+	if wb != nil {
+		if sheet := wb.SheetByName(name); sheet != nil {
+			return sheet.Location()
+		}
+	}
+	if frag.Link != "" {
+		return name
+	}
+	return name + ".xlsx"
+}
+
+func (d *drawer) drawSeparator(sep *model.Separator, y, nLls int) (deltaY int) {
+	if sep.Type == model.Delay {
+		return d.drawDelay(sep, y, nLls)
+	}
 	left := marginX
-	right := marginX + spanX*(nLls-1) + sizeX
+	right := marginX + d.spanX*(nLls-1) + sizeX
 	center := (right-left)/2 + left
 
+	// the double lines are put at the middle of the label, which is taller than them for the large fonts
+	w, h := textSize(sep.Text, d.theme.Separator.Font.Size)
+	w += labelPaddingX * 2
+	h += labelPaddingY * 2
+	deltaY = 12 + 6 + 12
+	if h > deltaY {
+		deltaY = h
+	}
+	middle := y + deltaY/2
+
 	line1 := shape.NewLine()
 	line2 := shape.NewLine()
-	line1.SetStartPos(left, y+12)
-	line1.SetEndPos(right, y+12)
-	line2.SetStartPos(left, y+18)
-	line2.SetEndPos(right, y+18)
-	ss.AddShape(line1)
-	ss.AddShape(line2)
-
-	w := len(sep.Text) * 12
-	h := 20
+	line1.SetStartPos(left, middle-3)
+	line1.SetEndPos(right, middle-3)
+	line2.SetStartPos(left, middle+3)
+	line2.SetEndPos(right, middle+3)
+	d.ss.AddShape(tag(styleLine(line1, d.theme.Separator.Line), sep.ID()))
+	d.ss.AddShape(tag(styleLine(line2, d.theme.Separator.Line), sep.ID()))
+
 	rect := shape.NewRectangle()
-	rect.SetLeftTop(center-w/2, y+15-h/2)
+	rect.SetLeftTop(center-w/2, middle-h/2)
 	rect.SetSize(w, h)
-	rect.SetText(sep.Text, "en-US")
 	rect.SetHAlign("ctr")
 	rect.SetVAlign("ctr")
-	ss.AddShape(rect)
+	d.ss.AddShape(tag(d.styleRect(rect, sep.Text, d.theme.Separator, ""), sep.ID()))
+
+	return deltaY
+}
+
+// drawDelay adds the text of the delay at the center of its gap, and reserves the gap to dot the lifelines over.
+func (d *drawer) drawDelay(sep *model.Separator, y, nLls int) (deltaY int) {
+	d.delays = append(d.delays, delayGap{y, y + d.delaySpan})
+
+	if sep.Text != "" {
+		left := marginX
+		right := marginX + d.spanX*(nLls-1) + sizeX
+		_, h := textSize(sep.Text, d.theme.Separator.Font.Size)
+		h += labelPaddingY * 2
+
+		rect := shape.NewRectangle()
+		rect.SetNoFill(true)
+		rect.SetNoLine(true)
+		rect.SetLeftTop(left, y+d.delaySpan/2-h/2)
+		rect.SetSize(right-left, h)
+		rect.SetHAlign("ctr")
+		rect.SetVAlign("ctr")
+		d.ss.AddShape(tag(d.styleText(rect, sep.Text, d.theme.Separator.Font), sep.ID()))
+	}
+
+	return d.delaySpan
+}
+
+// tag sets the identifier of the model element which the shape comes from to the decorated shape.
+func tag(ds *xlsx.Shape, id string) shape.Shape {
+	ds.SetName(id)
+	ds.SetDescr(ShapeDescrPrefix + id)
+	return ds
+}
+
+// styleRect sets the text to the rectangle, applies the box style and decorates it.
+// The rectangle is filled with fill if the style has no fill color.
+func (d *drawer) styleRect(rect *shape.Rectangle, text string, style BoxStyle, fill string) *xlsx.Shape {
+	if style.Fill != "" {
+		fill = style.Fill
+	}
+	if fill != "" {
+		rect.SetFillColor(fill)
+	} else {
+		rect.SetNoFill(true)
+	}
+	if style.Line.Color != "" {
+		rect.SetLineColor(style.Line.Color)
+	} else {
+		rect.SetNoLine(true)
+	}
+	ds := d.styleText(rect, text, style.Font)
+	ds.SetLineWidth(style.Line.Width)
+	return ds
+}
 
-	return 12 + 6 + 12
+// styleText sets the text to the rectangle, applies the font style and decorates it.
+// The language tag and the typefaces are chosen by the scripts of the text.
+func (d *drawer) styleText(rect *shape.Rectangle, text string, font FontStyle) *xlsx.Shape {
+	if font.Size > 0 {
+		rect.SetFontSize(font.Size * 100)
+	}
+	ds := xlsx.Decorate(rect)
+	if text != "" {
+		lang := textLang(text, d.lang)
+		rect.SetText(text, lang)
+		ds.SetFonts(textFonts(lang, font, d.fontFamily))
+	}
+	ds.SetTextColor(font.Color)
+	return ds
+}
+
+// styleLine applies the line style to the line and decorates it.
+// The dash type which the line already has is kept if the style has none.
+func styleLine(line *shape.Line, style LineStyle) *xlsx.Shape {
+	if style.Color != "" {
+		line.SetColor(style.Color)
+	}
+	if style.Dash != "" {
+		line.SetDashType(style.Dash)
+	}
+	ds := xlsx.Decorate(line)
+	ds.SetLineWidth(style.Width)
+	return ds
 }
 
-func maxLine(text string) int {
-	max := 0
-	for _, line := range strings.Split(text, "\n") {
-		if len(line) > max {
-			max = len(line)
+// getRefBothEndsLifeline returns the most left and the most right lifelines which the reference covers,
+// or nil if the diagram has no lifeline.
+func getRefBothEndsLifeline(frag *model.Fragment, lls []*model.Lifeline) (mostLeft, mostRight *model.Lifeline) {
+	if len(frag.Lifelines) == 0 {
+		if len(lls) == 0 {
+			return nil, nil
 		}
+		return lls[0], lls[len(lls)-1]
 	}
-	return max
+	for _, ll := range frag.Lifelines {
+		if mostLeft == nil || ll.Index < mostLeft.Index {
+			mostLeft = ll
+		}
+		if mostRight == nil || ll.Index > mostRight.Index {
+			mostRight = ll
+		}
+	}
+	return
 }
 
 func getBothEndsLifeline(frag *model.Fragment, msgs []*model.Message) (mostLeft, mostRight *model.Lifeline) {
@@ -0,0 +1,341 @@
+package seq2xls
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+func TestDrawMessageLabel(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0}
+	b := &model.Lifeline{Name: "b", Index: 1}
+	seq := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{a, b},
+		Messages: []*model.Message{
+			{Index: 0, From: a, To: b, Type: model.Synchronous, Text: "a long label which is wrapped into lines"},
+			{Index: 1, From: b, To: a, Type: model.Reply, Text: "ok"},
+		},
+	}
+	wb := newTestWorkbook(t, seq, DefaultLayout)
+
+	lineY := map[string]int{}
+	labels := map[string]*xlsx.DrawnShape{}
+	for _, s := range wb {
+		switch {
+		case s.IsHorizontal():
+			lineY[s.Descr] = s.Y1
+		case !s.IsLine && s.Text != "":
+			labels[s.Descr] = s
+		}
+	}
+
+	first := labels[ShapeDescrPrefix+"message-0"]
+	if first == nil || first.X2-first.X1 > DefaultLayout.LifelineSpan {
+		t.Fatalf("The label is not wrapped into the span of the lifelines %+v", first)
+	}
+	if first.Y2-labelPaddingY != lineY[ShapeDescrPrefix+"message-0"] {
+		t.Errorf("The label is not placed above the line %+v", first)
+	}
+	if top := marginY + sizeY + DefaultLayout.MessageSpan; first.Y1 < top {
+		t.Errorf("The label overlaps the lifelines: %d < %d", first.Y1, top)
+	}
+	if second := labels[ShapeDescrPrefix+"message-1"]; second == nil || second.Y1 < lineY[ShapeDescrPrefix+"message-0"] {
+		t.Errorf("The next label overlaps the previous message %+v", second)
+	}
+
+	wb = newTestWorkbook(t, seq, Layout{MaxLabelWidth: 400})
+	for _, s := range wb {
+		if !s.IsLine && s.Descr == ShapeDescrPrefix+"message-0" && s.Height() != lineHeight+labelPaddingY*2 {
+			t.Errorf("The label is wrapped within the maximum width %+v", s)
+		}
+	}
+}
+
+func TestDrawLabelPosition(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0}
+	b := &model.Lifeline{Name: "b", Index: 1}
+	seq := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{a, b},
+		Messages: []*model.Message{
+			{Index: 0, From: a, To: b, Type: model.Synchronous, Text: "center", LabelPosition: model.LabelCenter},
+			{Index: 1, From: a, To: b, Type: model.Synchronous, Text: "right", LabelPosition: model.LabelRight},
+			{Index: 2, From: b, To: a, Type: model.Reply, Text: "below", LabelPosition: model.LabelBelow},
+			{Index: 3, From: b, To: b, Type: model.SelfReference, Text: "self"},
+			{Index: 4, From: b, To: a, Type: model.Reply},
+		},
+	}
+	left, right := marginX+sizeX/2, marginX+DefaultLayout.LifelineSpan+sizeX/2
+
+	lines := map[string]*xlsx.DrawnShape{}
+	labels := map[string]*xlsx.DrawnShape{}
+	for _, s := range newTestWorkbook(t, seq, DefaultLayout) {
+		switch {
+		case s.IsLine && (lines[s.Descr] == nil || s.Y1 < lines[s.Descr].Y1):
+			lines[s.Descr] = s
+		case !s.IsLine && s.Text != "":
+			labels[s.Descr] = s
+		}
+	}
+	label := func(i int) (*xlsx.DrawnShape, int) {
+		id := fmt.Sprintf("%smessage-%d", ShapeDescrPrefix, i)
+		return labels[id], lines[id].Y1
+	}
+
+	if l, _ := label(0); (l.X1+l.X2)/2 != (left+right)/2 || l.HAlign != "ctr" {
+		t.Errorf("The label is not centered %+v", l)
+	}
+	if l, _ := label(1); l.X2 != right || l.HAlign != "r" {
+		t.Errorf("The label is not right-aligned %+v", l)
+	}
+	if l, y := label(2); l.X1 != left || l.Y1 != y {
+		t.Errorf("The label is not below the line %+v", l)
+	}
+	if l, y := label(3); l.X1 != right+DefaultLayout.LifelineSpan/3 || l.Y1 > y || l.Y2 < y {
+		t.Errorf("The label is not on the right of the loop %+v", l)
+	}
+	if _, y := label(4); y < labels[ShapeDescrPrefix+"message-3"].Y2 {
+		t.Errorf("The next message overlaps the label")
+	}
+}
+
+func TestDrawDelay(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0}
+	b := &model.Lifeline{Name: "b", Index: 1}
+	msgs := []*model.Message{
+		{Index: 0, From: a, To: b, Type: model.Synchronous},
+		{Index: 1, From: b, To: a, Type: model.Reply},
+	}
+	seq := &model.SequenceDiagram{
+		Lifelines:  []*model.Lifeline{a, b},
+		Messages:   msgs,
+		Separators: []*model.Separator{{Index: 0, Type: model.Delay, Text: "5 minutes later", Before: msgs[0]}},
+	}
+	layout := Layout{DelaySpan: 100}
+
+	var text *xlsx.DrawnShape
+	dotted := map[string]*xlsx.DrawnShape{}
+	lineY := map[string]int{}
+	for _, s := range newTestWorkbook(t, seq, layout) {
+		switch {
+		case s.IsLine && s.DashType == "dot":
+			dotted[s.Descr] = s
+		case s.IsHorizontal():
+			lineY[s.Descr] = s.Y1
+		case s.Descr == ShapeDescrPrefix+"separator-0":
+			text = s
+		}
+	}
+
+	for _, ll := range seq.Lifelines {
+		gap := dotted[ShapeDescrPrefix+ll.ID()]
+		if gap == nil || gap.Height() != layout.DelaySpan {
+			t.Fatalf("The lifeline is not dotted over the gap %+v", gap)
+		}
+		if gap.Y1 <= lineY[ShapeDescrPrefix+"message-0"] || gap.Y2 >= lineY[ShapeDescrPrefix+"message-1"] {
+			t.Errorf("The gap is not between the messages %+v", gap)
+		}
+	}
+	gap := dotted[ShapeDescrPrefix+a.ID()]
+	if text == nil || text.Filled || text.Lined || (text.Y1+text.Y2)/2 != (gap.Y1+gap.Y2)/2 {
+		t.Errorf("The text is not at the center of the gap %+v", text)
+	}
+}
+
+func TestDrawConstraint(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0}
+	b := &model.Lifeline{Name: "b", Index: 1}
+	msgs := []*model.Message{
+		{Index: 0, From: a, To: b, Type: model.Synchronous},
+		{Index: 1, From: b, To: b, Type: model.SelfReference, Text: "check"},
+		{Index: 2, From: b, To: a, Type: model.Reply},
+	}
+	seq := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{a, b},
+		Messages:  msgs,
+		Constraints: []*model.Constraint{
+			{Index: 0, Begin: msgs[0], End: msgs[2], Text: "{< 200ms}"},
+			{Index: 1, Begin: msgs[1], End: msgs[1], Text: "{< 1s}"},
+		},
+	}
+
+	lineY := map[string]int{}
+	brackets := map[string]*xlsx.DrawnShape{}
+	texts := map[string]*xlsx.DrawnShape{}
+	labelRight := 0
+	for _, s := range newTestWorkbook(t, seq, DefaultLayout) {
+		switch {
+		case s.IsVertical() && s.HeadType != "" && s.TailType != "":
+			brackets[s.Descr] = s
+		case s.IsHorizontal() && s.TailType != "":
+			lineY[s.Descr] = s.Y1
+		case !s.IsLine && s.Text == "check":
+			labelRight = s.X2
+		case !s.IsLine && s.Text != "":
+			texts[s.Descr] = s
+		}
+	}
+
+	outer := brackets[ShapeDescrPrefix+"constraint-0"]
+	if outer == nil || outer.Y1 != lineY[ShapeDescrPrefix+"message-0"] || outer.Y2 != lineY[ShapeDescrPrefix+"message-2"] {
+		t.Fatalf("The bracket does not span between the messages %+v", outer)
+	}
+	if outer.X1 <= labelRight {
+		t.Errorf("The bracket overlaps the label of the message: %d <= %d", outer.X1, labelRight)
+	}
+	if text := texts[ShapeDescrPrefix+"constraint-0"]; text == nil || text.Text != "{< 200ms}" || text.X1 != outer.X1 ||
+		(text.Y1+text.Y2)/2 != (outer.Y1+outer.Y2)/2 {
+		t.Errorf("The text is not beside the center of the bracket %+v", text)
+	}
+
+	inner := brackets[ShapeDescrPrefix+"constraint-1"]
+	if inner == nil || inner.Height() != DefaultLayout.MessageSpan/3 {
+		t.Fatalf("The bracket does not span the loop of the self-reference %+v", inner)
+	}
+	if text := texts[ShapeDescrPrefix+"constraint-0"]; inner.X1 <= text.X2 {
+		t.Errorf("The overlapping bracket is not shifted to the right: %d <= %d", inner.X1, text.X2)
+	}
+}
+
+func TestDrawRef(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0}
+	b := &model.Lifeline{Name: "b", Index: 1}
+	c := &model.Lifeline{Name: "c", Index: 2}
+	msgs := []*model.Message{
+		{Index: 0, From: a, To: b, Type: model.Synchronous},
+		{Index: 1, From: b, To: a, Type: model.Reply},
+	}
+	seq := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{a, b, c},
+		Messages:  msgs,
+		Fragments: []*model.Fragment{
+			{Index: 0, Type: model.Ref, Text: "Login", Lifelines: []*model.Lifeline{c, b}, Before: msgs[0]},
+			{Index: 1, Type: model.Ref, Text: "Logout", Before: msgs[1], Link: "logout.xlsx"},
+			{Index: 2, Type: model.Ref, Text: "Sign\nout", Before: msgs[1]},
+		},
+	}
+
+	wb := xlsx.NewWorkbook()
+	wb.AddSheet("login")
+	DrawSequenceDiagram(wb, seq)
+
+	lineY := map[string]int{}
+	frames := map[string]*xlsx.DrawnShape{}
+	links := map[string]string{}
+	for _, s := range wb.Shapes() {
+		anchor, err := xlsx.MarshalAnchor(s)
+		if err != nil {
+			t.Fatalf("MarshalAnchor error %v", err)
+		}
+		drawn, err := xlsx.ParseDrawnShape(anchor)
+		if err != nil {
+			t.Fatalf("ParseDrawnShape error %v", err)
+		}
+		switch {
+		case drawn.IsHorizontal() && drawn.TailType != "":
+			lineY[drawn.Descr] = drawn.Y1
+		case !drawn.IsLine && drawn.Text == "ref":
+			frames[drawn.Descr] = drawn
+			links[drawn.Descr] = s.(*xlsx.Shape).Hyperlink()
+			if !drawn.Filled {
+				t.Errorf("The frame does not hide the lifelines")
+			}
+		}
+	}
+
+	center := func(i int) int { return marginX + DefaultLayout.LifelineSpan*i + sizeX/2 }
+	login := frames[ShapeDescrPrefix+"fragment-0"]
+	if login == nil || login.X1 != center(1)-DefaultLayout.LifelineSpan/3 || login.X2 != center(2)+DefaultLayout.LifelineSpan/3 {
+		t.Fatalf("The frame does not cover the lifelines %+v", login)
+	}
+	if login.Y1 <= lineY[ShapeDescrPrefix+"message-0"] || login.Y2 >= lineY[ShapeDescrPrefix+"message-1"] {
+		t.Errorf("The frame is not put between the messages %+v", login)
+	}
+	logout := frames[ShapeDescrPrefix+"fragment-1"]
+	if logout == nil || logout.X1 != center(0)-DefaultLayout.LifelineSpan/3 || logout.Y1 <= lineY[ShapeDescrPrefix+"message-1"] {
+		t.Fatalf("The frame does not cover all the lifelines after the message %+v", logout)
+	}
+
+	if link := links[ShapeDescrPrefix+"fragment-0"]; link != "#'login'!A1" {
+		t.Errorf("The reference does not link to the worksheet: %s", link)
+	}
+	if link := links[ShapeDescrPrefix+"fragment-1"]; link != "logout.xlsx" {
+		t.Errorf("The reference does not link to the workbook: %s", link)
+	}
+	if link := links[ShapeDescrPrefix+"fragment-2"]; link != "Sign out.xlsx" {
+		t.Errorf("The reference does not link to the workbook named after it: %s", link)
+	}
+}
+
+func TestDrawNoteAndSeparatorSize(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0, ColorHex: "FFFFFF"}
+	b := &model.Lifeline{Name: "b", Index: 1, ColorHex: "FFFFFF"}
+	msgs := []*model.Message{{Index: 0, From: a, To: b, Type: model.Synchronous}}
+	seq := &model.SequenceDiagram{
+		Lifelines:  []*model.Lifeline{a, b},
+		Messages:   msgs,
+		Notes:      []*model.Note{{Index: 0, Assoc: msgs[0], Text: "memo", ColorHex: "FFFFFF"}},
+		Separators: []*model.Separator{{Index: 0, Type: model.Divider, Text: "日本語"}},
+	}
+	large := DefaultTheme
+	large.Note.Font.Size = 22
+	large.Separator.Font.Size = 22
+
+	boxes := func(theme *Theme) (note, sep *xlsx.DrawnShape, msgY int) {
+		for _, s := range newThemedTestWorkbook(t, seq, DefaultLayout, theme) {
+			switch {
+			case s.Descr == ShapeDescrPrefix+"note-0":
+				note = s
+			case s.Descr == ShapeDescrPrefix+"separator-0" && !s.IsLine:
+				sep = s
+			case s.Descr == ShapeDescrPrefix+"message-0" && s.IsLine:
+				msgY = s.Y1
+			}
+		}
+		return
+	}
+
+	note, sep, _ := boxes(&DefaultTheme)
+	w, h := textSize("memo", 0)
+	if note.X2-note.X1 != w+labelPaddingX*2 || note.Y2-note.Y1 != h+labelPaddingY*2 {
+		t.Errorf("The note is not sized to the text %+v", note)
+	}
+	w, _ = textSize("日本語", 0)
+	if sep.X2-sep.X1 != w+labelPaddingX*2 {
+		t.Errorf("The separator is not sized to the text %+v", sep)
+	}
+
+	largeNote, largeSep, msgY := boxes(&large)
+	if largeNote.Y2-largeNote.Y1 <= note.Y2-note.Y1 || largeSep.X2-largeSep.X1 <= sep.X2-sep.X1 {
+		t.Errorf("The note %+v and the separator %+v are not sized to the font", largeNote, largeSep)
+	}
+	if largeSep.Y2 > msgY {
+		t.Errorf("The separator %+v overlaps the message at %d", largeSep, msgY)
+	}
+}
+
+// newTestWorkbook draws the diagram and returns the drawn shapes.
+func newTestWorkbook(t *testing.T, seq *model.SequenceDiagram, layout Layout) []*xlsx.DrawnShape {
+	return newThemedTestWorkbook(t, seq, layout, nil)
+}
+
+// newThemedTestWorkbook draws the diagram with the theme and returns the drawn shapes.
+func newThemedTestWorkbook(t *testing.T, seq *model.SequenceDiagram, layout Layout, theme *Theme) []*xlsx.DrawnShape {
+	wb := xlsx.NewWorkbook()
+	DrawSequenceDiagramWithTheme(wb, seq, layout, theme)
+
+	shapes := []*xlsx.DrawnShape{}
+	for _, s := range wb.Shapes() {
+		anchor, err := xlsx.MarshalAnchor(s)
+		if err != nil {
+			t.Fatalf("MarshalAnchor error %v", err)
+		}
+		drawn, err := xlsx.ParseDrawnShape(anchor)
+		if err != nil {
+			t.Fatalf("ParseDrawnShape error %v", err)
+		}
+		shapes = append(shapes, drawn)
+	}
+	return shapes
+}
@@ -5,4 +5,6 @@ go 1.12
 require (
 	github.com/golang-collections/collections v0.0.0-20130729185459-604e922904d3
 	github.com/rsp9u/go-xlsshape v0.0.3
+	golang.org/x/image v0.18.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,99 @@
+package seq2xls
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// langTypefaces is the typefaces used for the characters of the language when no typeface is given.
+// The East Asian languages use the slot of the East Asian characters, and the others the one of the complex scripts.
+var langTypefaces = map[string]string{
+	"ja-JP": "Yu Gothic",
+	"zh-CN": "Microsoft YaHei",
+	"zh-TW": "Microsoft JhengHei",
+	"ko-KR": "Malgun Gothic",
+	"ar-SA": "Arial",
+	"he-IL": "Arial",
+	"th-TH": "Tahoma",
+	"hi-IN": "Mangal",
+}
+
+// textLang returns the language tag of the text detected from its scripts.
+//
+// The text without a distinctive script takes defaultLang, or "en-US" if it is empty.
+// The text of Han characters alone takes defaultLang if it is an East Asian language, or "ja-JP" otherwise.
+func textLang(text, defaultLang string) string {
+	han := false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			return "ja-JP"
+		case unicode.Is(unicode.Hangul, r):
+			return "ko-KR"
+		case unicode.Is(unicode.Arabic, r):
+			return "ar-SA"
+		case unicode.Is(unicode.Hebrew, r):
+			return "he-IL"
+		case unicode.Is(unicode.Thai, r):
+			return "th-TH"
+		case unicode.Is(unicode.Devanagari, r):
+			return "hi-IN"
+		case unicode.Is(unicode.Han, r):
+			han = true
+		}
+	}
+
+	switch {
+	case han && isEastAsianLang(defaultLang):
+		return defaultLang
+	case han:
+		return "ja-JP"
+	case defaultLang != "":
+		return defaultLang
+	}
+	return "en-US"
+}
+
+// isEastAsianLang returns whether the language tag is of Chinese, Japanese or Korean.
+func isEastAsianLang(lang string) bool {
+	for _, prefix := range []string{"ja", "zh", "ko"} {
+		if lang == prefix || strings.HasPrefix(lang, prefix+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// textFonts returns the typefaces of the text in the language.
+//
+// Each kind of the characters takes the typeface of the style for it, the family of the style,
+// the default family of the diagram and the typeface for the language in this order.
+func textFonts(lang string, font FontStyle, defaultFamily string) xlsx.Fonts {
+	family := orFirst(font.Family, defaultFamily)
+	fonts := xlsx.Fonts{
+		Latin:         family,
+		EastAsian:     orFirst(font.EastAsianFamily, family),
+		ComplexScript: orFirst(font.ComplexScriptFamily, family),
+	}
+
+	typeface, ok := langTypefaces[lang]
+	switch {
+	case !ok:
+	case isEastAsianLang(lang) && fonts.EastAsian == "":
+		fonts.EastAsian = typeface
+	case !isEastAsianLang(lang) && fonts.ComplexScript == "":
+		fonts.ComplexScript = typeface
+	}
+	return fonts
+}
+
+func orFirst(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
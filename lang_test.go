@@ -0,0 +1,72 @@
+package seq2xls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+func TestTextLang(t *testing.T) {
+	tests := []struct {
+		text, defaultLang, lang string
+	}{
+		{"login", "", "en-US"},
+		{"login", "de-DE", "de-DE"},
+		{"ログイン request", "", "ja-JP"},
+		{"認証", "", "ja-JP"},
+		{"认证", "zh-CN", "zh-CN"},
+		{"認証", "en-US", "ja-JP"},
+		{"로그인", "ja-JP", "ko-KR"},
+		{"تسجيل", "", "ar-SA"},
+		{"เข้าสู่ระบบ", "", "th-TH"},
+	}
+	for _, tt := range tests {
+		if lang := textLang(tt.text, tt.defaultLang); lang != tt.lang {
+			t.Errorf("textLang(%q, %q) = %s, want %s", tt.text, tt.defaultLang, lang, tt.lang)
+		}
+	}
+}
+
+func TestTextFonts(t *testing.T) {
+	tests := []struct {
+		lang          string
+		font          FontStyle
+		defaultFamily string
+		fonts         xlsx.Fonts
+	}{
+		{"en-US", FontStyle{}, "", xlsx.Fonts{}},
+		{"ja-JP", FontStyle{}, "", xlsx.Fonts{EastAsian: "Yu Gothic"}},
+		{"ja-JP", FontStyle{}, "Meiryo", xlsx.Fonts{Latin: "Meiryo", EastAsian: "Meiryo", ComplexScript: "Meiryo"}},
+		{"ja-JP", FontStyle{Family: "Arial", EastAsianFamily: "MS Gothic"}, "Meiryo",
+			xlsx.Fonts{Latin: "Arial", EastAsian: "MS Gothic", ComplexScript: "Arial"}},
+		{"th-TH", FontStyle{EastAsianFamily: "MS Gothic"}, "", xlsx.Fonts{EastAsian: "MS Gothic", ComplexScript: "Tahoma"}},
+	}
+	for _, tt := range tests {
+		if fonts := textFonts(tt.lang, tt.font, tt.defaultFamily); fonts != tt.fonts {
+			t.Errorf("textFonts(%s, %+v, %q) = %+v, want %+v", tt.lang, tt.font, tt.defaultFamily, fonts, tt.fonts)
+		}
+	}
+}
+
+func TestRenderWithLang(t *testing.T) {
+	src := strings.Replace(convertTestJSON, `"version": 1,`, `"version": 1, "lang": "zh-TW",`, 1)
+	wb, err := Render([]byte(strings.Replace(src, `"name": "client"`, `"name": "認証"`, 1)))
+	if err != nil {
+		t.Fatalf("Render error %v", err)
+	}
+	for _, s := range wb.Shapes() {
+		anchor, _ := xlsx.MarshalAnchor(s)
+		drawn, err := xlsx.ParseDrawnShape(anchor)
+		if err != nil {
+			t.Fatalf("ParseDrawnShape error %v", err)
+		}
+		if drawn.Text == "認証" {
+			if drawn.Lang != "zh-TW" || drawn.EastAsianFontFamily != "Microsoft JhengHei" {
+				t.Errorf("Unexpected language of the lifeline %+v", drawn)
+			}
+			return
+		}
+	}
+	t.Errorf("The lifeline is not drawn")
+}
@@ -0,0 +1,88 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s+(.*?))?(?:\s+#+)?\s*$`)
+	fencePattern   = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})\\s*([^`\\s]*)")
+)
+
+// Block is a fenced code block in the Markdown document.
+type Block struct {
+	// Lang is the first word of the info string of the fence, such as 'seqdiag' and 'mermaid'.
+	Lang string
+	// Heading is the text of the nearest heading above the block, or empty if there is no heading.
+	Heading string
+	// Line is the line number of the opening fence.
+	Line int
+	Text []byte
+}
+
+// ExtractBlocks extracts all fenced code blocks from the Markdown document in order of appearance.
+//
+// Only the ATX headings ('# Title') are taken as the headings of the blocks.
+// A block which is not closed continues to the end of the document.
+func ExtractBlocks(b []byte) []*Block {
+	blocks := []*Block{}
+	heading := ""
+
+	var (
+		block       *Block
+		buf         *bytes.Buffer
+		fenceChar   string
+		fenceLength int
+		indent      int
+		lineNumber  int
+	)
+
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		line := sc.Text()
+		lineNumber++
+
+		if block != nil {
+			trimmed := strings.TrimSpace(line)
+			if len(trimmed) >= fenceLength && strings.Trim(trimmed, fenceChar) == "" {
+				block.Text = buf.Bytes()
+				blocks = append(blocks, block)
+				block = nil
+				continue
+			}
+			buf.WriteString(removeIndent(line, indent))
+			buf.WriteString("\n")
+			continue
+		}
+
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			indent = len(m[1])
+			fenceChar = m[2][:1]
+			fenceLength = len(m[2])
+			block = &Block{Lang: strings.ToLower(m[3]), Heading: heading, Line: lineNumber}
+			buf = new(bytes.Buffer)
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(line); m != nil {
+			heading = m[1]
+		}
+	}
+
+	if block != nil {
+		block.Text = buf.Bytes()
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// removeIndent removes the spaces up to the indentation of the opening fence.
+func removeIndent(line string, indent int) string {
+	for i := 0; i < indent && strings.HasPrefix(line, " "); i++ {
+		line = line[1:]
+	}
+	return line
+}
@@ -0,0 +1,45 @@
+package markdown
+
+import (
+	"testing"
+)
+
+const testDataDocument = "# Design\n" +
+	"\n" +
+	"## Login sequence ##\n" +
+	"\n" +
+	"```seqdiag\n" +
+	"seqdiag {\n" +
+	"  a -> b;\n" +
+	"}\n" +
+	"```\n" +
+	"\n" +
+	"  ~~~~ Mermaid {.diagram}\n" +
+	"  sequenceDiagram\n" +
+	"    a->>b: ```\n" +
+	"  ~~~~\n" +
+	"\n" +
+	"```\n" +
+	"# not a heading\n" +
+	"```\n" +
+	"### Logout\n" +
+	"```plantuml\n" +
+	"@startuml\n"
+
+func checkBlock(t *testing.T, b *Block, lang, heading string, line int, text string) {
+	if b.Lang != lang || b.Heading != heading || b.Line != line || string(b.Text) != text {
+		t.Fatalf("Wrong block [lang: %s, heading: %s, line: %d, text: %q]", b.Lang, b.Heading, b.Line, b.Text)
+	}
+}
+
+func TestExtractBlocks(t *testing.T) {
+	blocks := ExtractBlocks([]byte(testDataDocument))
+	if len(blocks) != 4 {
+		t.Fatalf("Wrong number of blocks %d", len(blocks))
+	}
+
+	checkBlock(t, blocks[0], "seqdiag", "Login sequence", 5, "seqdiag {\n  a -> b;\n}\n")
+	checkBlock(t, blocks[1], "mermaid", "Login sequence", 11, "sequenceDiagram\n  a->>b: ```\n")
+	checkBlock(t, blocks[2], "", "Login sequence", 16, "# not a heading\n")
+	checkBlock(t, blocks[3], "plantuml", "Logout", 20, "@startuml\n")
+}
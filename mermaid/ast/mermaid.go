@@ -0,0 +1,81 @@
+package ast
+
+// Pos is a position of the statement in the source text.
+type Pos struct {
+	Line int
+}
+
+// Position returns the position of the statement.
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Diagram is the root of the Mermaid sequence diagram.
+type Diagram struct {
+	Stmts []Stmt
+}
+
+// Stmt is a statement of the diagram.
+type Stmt interface {
+	Position() Pos
+}
+
+// ParticipantStmt declares a participant or an actor.
+//
+// The participant is referred by ID and shown as Alias if it is given.
+type ParticipantStmt struct {
+	Pos
+	Kind  string
+	ID    string
+	Alias string
+}
+
+// MessageStmt is an arrow between the participants.
+//
+// Arrow is one of "->", "-->", "->>", "-->>", "-x", "--x", "-)" and "--)".
+// Activate and Deactivate are set by the '+' and '-' shortcuts before the receiver.
+type MessageStmt struct {
+	Pos
+	Left       string
+	Right      string
+	Arrow      string
+	Activate   bool
+	Deactivate bool
+	Text       string
+}
+
+// ActivationStmt is 'activate', 'deactivate' or 'destroy' of the participant.
+type ActivationStmt struct {
+	Pos
+	Kind string
+	Name string
+}
+
+// AutonumberStmt starts the automatic numbering of the messages.
+type AutonumberStmt struct {
+	Pos
+}
+
+// BlockStmt is a block such as 'loop', 'alt' and 'rect'.
+type BlockStmt struct {
+	Pos
+	Type  string
+	Text  string
+	Stmts []Stmt
+	Elses []*ElseClause
+}
+
+// ElseClause is an 'else', 'and' or 'option' partition of the block.
+type ElseClause struct {
+	Pos
+	Text  string
+	Stmts []Stmt
+}
+
+// NoteStmt is a note beside or over the participants.
+type NoteStmt struct {
+	Pos
+	Place   string
+	Targets []string
+	Text    string
+}
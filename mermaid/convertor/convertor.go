@@ -0,0 +1,24 @@
+package convertor
+
+import (
+	"github.com/rsp9u/seq2xls/mermaid/ast"
+	"github.com/rsp9u/seq2xls/model"
+)
+
+// AstToModel converts from the sequence diagram AST of Mermaid to the drawable model.
+func AstToModel(d *ast.Diagram) (*model.SequenceDiagram, error) {
+	seq := &model.SequenceDiagram{}
+
+	lls, err := ExtractLifelines(d)
+	if err != nil {
+		return nil, err
+	}
+	seq.Lifelines = lls
+
+	err = ScanTimeline(d, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	return seq, nil
+}
@@ -0,0 +1,66 @@
+package convertor
+
+import (
+	"github.com/rsp9u/seq2xls/mermaid/ast"
+	"github.com/rsp9u/seq2xls/model"
+)
+
+// ExtractLifelines extracts lifeline elements from the diagram.
+//
+// The lifelines are ordered by their first appearance, whether they are declared or not.
+func ExtractLifelines(d *ast.Diagram) ([]*model.Lifeline, error) {
+	lls := []*model.Lifeline{}
+	extractLifelinesFromStmts(d.Stmts, &lls, map[string]*model.Lifeline{})
+	return lls, nil
+}
+
+func extractLifelinesFromStmts(stmts []ast.Stmt, lls *[]*model.Lifeline, ids map[string]*model.Lifeline) {
+	add := func(id, name string) {
+		if _, ok := ids[id]; ok {
+			return
+		}
+		ll := &model.Lifeline{Name: name, Index: len(*lls), ColorHex: "FFFFFF"}
+		*lls = append(*lls, ll)
+		ids[id] = ll
+	}
+
+	for _, stmt := range stmts {
+		switch v := stmt.(type) {
+		case *ast.ParticipantStmt:
+			name := v.Alias
+			if name == "" {
+				name = v.ID
+			}
+			add(v.ID, name)
+
+		case *ast.MessageStmt:
+			add(v.Left, v.Left)
+			add(v.Right, v.Right)
+
+		case *ast.ActivationStmt:
+			add(v.Name, v.Name)
+
+		case *ast.NoteStmt:
+			for _, target := range v.Targets {
+				add(target, target)
+			}
+
+		case *ast.BlockStmt:
+			extractLifelinesFromStmts(v.Stmts, lls, ids)
+			for _, clause := range v.Elses {
+				extractLifelinesFromStmts(clause.Stmts, lls, ids)
+			}
+		}
+	}
+}
+
+// lifelineRefs returns the lifelines by the identifiers which the statements refer to.
+func lifelineRefs(d *ast.Diagram, lls []*model.Lifeline) map[string]*model.Lifeline {
+	refs := map[string]*model.Lifeline{}
+	all := []*model.Lifeline{}
+	extractLifelinesFromStmts(d.Stmts, &all, refs)
+	for ref, ll := range refs {
+		refs[ref] = lls[ll.Index]
+	}
+	return refs
+}
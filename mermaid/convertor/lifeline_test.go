@@ -0,0 +1,49 @@
+package convertor
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/mermaid"
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const testDataLifeline = `
+sequenceDiagram
+  participant web as Web Server
+  actor browser
+  browser->>web: GET
+  web->>database: SELECT
+  loop
+    web->>cache: GET
+  end
+  Note over queue: idle
+`
+
+func checkLifeline(t *testing.T, ll *model.Lifeline, idx int, name string) {
+	if ll.Index != idx {
+		t.Fatalf("Invalid lifeline index %s[%d]", ll.Name, ll.Index)
+	}
+	if ll.Name != name {
+		t.Fatalf("Mismatches lifeline name [expect: %s, actual: %s]", name, ll.Name)
+	}
+}
+
+func TestExtractLifelines(t *testing.T) {
+	d, err := mermaid.ParseMermaid([]byte(testDataLifeline))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	lls, err := ExtractLifelines(d)
+	if err != nil {
+		t.Fatalf("Extract error %v", err)
+	}
+
+	if len(lls) != 5 {
+		t.Fatalf("Wrong number of lifelines %d", len(lls))
+	}
+	checkLifeline(t, lls[0], 0, "Web Server")
+	checkLifeline(t, lls[1], 1, "browser")
+	checkLifeline(t, lls[2], 2, "database")
+	checkLifeline(t, lls[3], 3, "cache")
+	checkLifeline(t, lls[4], 4, "queue")
+}
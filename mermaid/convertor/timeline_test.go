@@ -0,0 +1,131 @@
+package convertor
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/mermaid"
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const testDataTimeline = `
+sequenceDiagram
+  autonumber
+  a->>+b: request
+  b->>b: self
+  b-)c: notify
+  activate c
+  b-->>-a: reply
+  c--xb
+  deactivate c
+`
+
+const testDataFragment = `
+sequenceDiagram
+  Note over a: start
+  loop 10 times
+    a->>b: call
+    rect rgb(0, 0, 0)
+      alt ok
+        b->>c: one
+        b->>d: two
+      else error
+        b-->>a: fail
+      end
+    end
+  end
+  Note left of b: last
+`
+
+func convert(t *testing.T, src string) *model.SequenceDiagram {
+	d, err := mermaid.ParseMermaid([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	seq, err := AstToModel(d)
+	if err != nil {
+		t.Fatalf("Convert error %v", err)
+	}
+	return seq
+}
+
+func checkMessage(t *testing.T, msg *model.Message, from, to string, typ model.MessageType, text string) {
+	if msg.From.Name != from || msg.To.Name != to || msg.Type != typ || msg.Text != text {
+		t.Fatalf("Wrong message %d [%s -> %s (%s) '%s']", msg.Index, msg.From.Name, msg.To.Name, msg.Type, msg.Text)
+	}
+}
+
+func TestScanTimeline(t *testing.T) {
+	seq := convert(t, testDataTimeline)
+
+	if len(seq.Messages) != 5 {
+		t.Fatalf("Wrong number of messages %d", len(seq.Messages))
+	}
+	checkMessage(t, seq.Messages[0], "a", "b", model.Synchronous, "1 request")
+	checkMessage(t, seq.Messages[1], "b", "b", model.SelfReference, "2 self")
+	checkMessage(t, seq.Messages[2], "b", "c", model.Asynchronous, "3 notify")
+	checkMessage(t, seq.Messages[3], "b", "a", model.Reply, "4 reply")
+	checkMessage(t, seq.Messages[4], "c", "b", model.Reply, "5")
+
+	if len(seq.ExecSpecs) != 2 {
+		t.Fatalf("Wrong number of execution specifications %d", len(seq.ExecSpecs))
+	}
+	spec := seq.ExecSpecs[0]
+	if spec.Assoc.Name != "b" || spec.Begin != seq.Messages[0] || spec.End != seq.Messages[3] {
+		t.Fatalf("Wrong execution specification %d", spec.Index)
+	}
+	spec = seq.ExecSpecs[1]
+	if spec.Assoc.Name != "c" || spec.Begin != seq.Messages[2] || spec.End != seq.Messages[4] {
+		t.Fatalf("Wrong execution specification %d", spec.Index)
+	}
+}
+
+func TestScanFragment(t *testing.T) {
+	seq := convert(t, testDataFragment)
+
+	if len(seq.Fragments) != 2 {
+		t.Fatalf("Wrong number of fragments %d", len(seq.Fragments))
+	}
+	loop, alt := seq.Fragments[0], seq.Fragments[1]
+	if loop.Type != model.Loop || loop.Text != "10 times" || loop.Begin != seq.Messages[0] || loop.End != seq.Messages[3] {
+		t.Fatalf("Wrong loop fragment %+v", loop)
+	}
+	if alt.Type != model.Alt || alt.Text != "ok" || alt.Begin != seq.Messages[1] || alt.End != seq.Messages[3] {
+		t.Fatalf("Wrong alt fragment %+v", alt)
+	}
+	if len(alt.Operands) != 1 || alt.Operands[0].Begin != seq.Messages[3] || alt.Operands[0].Text != "error" {
+		t.Fatalf("Wrong operands of alt fragment")
+	}
+
+	if len(seq.Notes) != 2 {
+		t.Fatalf("Wrong number of notes %d", len(seq.Notes))
+	}
+	note := seq.Notes[0]
+	if !note.Over || note.Lifelines[0].Name != "a" || note.Assoc != seq.Messages[0] {
+		t.Fatalf("Wrong note %+v", note)
+	}
+	note = seq.Notes[1]
+	if !note.OnLeft || note.Lifelines[0].Name != "b" || note.Assoc != seq.Messages[3] {
+		t.Fatalf("Wrong note %+v", note)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{"sequenceDiagram\na->>b\nloop\nend", "line 3: empty 'loop' is not allowed"},
+		{"sequenceDiagram\nalt\na->>b\nelse\nend", "line 4: empty partition is not allowed"},
+		{"sequenceDiagram\na->>b\ndeactivate b", "line 3: 'b' is not activated"},
+	}
+
+	for _, tt := range tests {
+		d, err := mermaid.ParseMermaid([]byte(tt.src))
+		if err != nil {
+			t.Fatalf("Parse error %v", err)
+		}
+		_, err = AstToModel(d)
+		if err == nil || err.Error() != tt.msg {
+			t.Fatalf("Wrong error [expect: %s, actual: %v]", tt.msg, err)
+		}
+	}
+}
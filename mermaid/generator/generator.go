@@ -0,0 +1,219 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const indentUnit = "  "
+
+var (
+	idPattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+	// elseKeywords is the keyword to partition each type of the block.
+	// The operands of the other types are flattened into the preceding one.
+	elseKeywords = map[model.FragmentType]string{
+		model.Alt:      "else",
+		model.Par:      "and",
+		model.Critical: "option",
+	}
+	// blockTypes is the fragment types which Mermaid can express. The others are flattened.
+	blockTypes = map[model.FragmentType]bool{
+		model.Alt:      true,
+		model.Opt:      true,
+		model.Loop:     true,
+		model.Par:      true,
+		model.Break:    true,
+		model.Critical: true,
+	}
+)
+
+// Generate writes the given diagram model out as a Mermaid sequence diagram.
+//
+// Mermaid has no colors nor separators, so the colors are dropped and each separator
+// is written as a note over all participants. The references are written as notes over
+// their participants in the same way. Fragments which Mermaid cannot express
+// are flattened into plain messages.
+func Generate(w io.Writer, seq *model.SequenceDiagram) error {
+	g := &generator{buf: new(bytes.Buffer), seq: seq, refs: map[*model.Lifeline]string{}}
+	g.buf.WriteString("sequenceDiagram\n")
+
+	for _, ll := range seq.Lifelines {
+		g.writeParticipant(ll)
+	}
+
+	for _, sep := range seq.Separators {
+		if sep.Before == nil {
+			g.writeSeparator(sep, 1)
+		}
+	}
+	g.writeRefs(nil, 1)
+
+	depth := 1
+	for _, msg := range seq.Messages {
+		for _, frag := range seq.Fragments {
+			if elseKeywords[frag.Type] == "" {
+				continue
+			}
+			for _, op := range frag.Operands {
+				if op.Begin == msg {
+					fmt.Fprintf(g.buf, "%s%s\n", indent(depth-1), strings.TrimSpace(elseKeywords[frag.Type]+" "+escape(op.Text)))
+				}
+			}
+		}
+		for _, frag := range seq.Fragments {
+			if frag.Begin == msg && blockTypes[frag.Type] {
+				fmt.Fprintf(g.buf, "%s%s\n", indent(depth), strings.TrimSpace(frag.Type.String()+" "+escape(frag.Text)))
+				depth++
+			}
+		}
+
+		g.writeMessage(msg, depth)
+		for _, spec := range seq.ExecSpecs {
+			if spec.Begin == msg {
+				fmt.Fprintf(g.buf, "%sactivate %s\n", indent(depth), g.refs[spec.Assoc])
+			}
+		}
+		for _, spec := range seq.ExecSpecs {
+			if spec.End == msg {
+				fmt.Fprintf(g.buf, "%sdeactivate %s\n", indent(depth), g.refs[spec.Assoc])
+			}
+		}
+		for _, note := range seq.Notes {
+			if note.Assoc == msg {
+				g.writeNote(note, depth)
+			}
+		}
+
+		for i := len(seq.Fragments) - 1; i >= 0; i-- {
+			frag := seq.Fragments[i]
+			if frag.End == msg && blockTypes[frag.Type] {
+				depth--
+				fmt.Fprintf(g.buf, "%send\n", indent(depth))
+			}
+		}
+
+		for _, sep := range seq.Separators {
+			if sep.Before == msg {
+				g.writeSeparator(sep, depth)
+			}
+		}
+		g.writeRefs(msg, depth)
+	}
+
+	_, err := w.Write(g.buf.Bytes())
+	return err
+}
+
+type generator struct {
+	buf *bytes.Buffer
+	seq *model.SequenceDiagram
+	// refs is the IDs to refer to the participants in the statements.
+	refs map[*model.Lifeline]string
+}
+
+func (g *generator) writeParticipant(ll *model.Lifeline) {
+	if idPattern.MatchString(ll.Name) {
+		g.refs[ll] = ll.Name
+		fmt.Fprintf(g.buf, "%sparticipant %s\n", indentUnit, ll.Name)
+		return
+	}
+	g.refs[ll] = fmt.Sprintf("L%d", ll.Index)
+	fmt.Fprintf(g.buf, "%sparticipant %s as %s\n", indentUnit, g.refs[ll], escape(ll.Name))
+}
+
+func (g *generator) writeMessage(msg *model.Message, depth int) {
+	arrow := "->>"
+	switch msg.Type {
+	case model.Asynchronous:
+		arrow = "-)"
+	case model.Reply:
+		arrow = "-->>"
+	}
+	stmt := fmt.Sprintf("%s%s%s: %s", g.refs[msg.From], arrow, g.refs[msg.To], escape(msg.Text))
+	fmt.Fprintf(g.buf, "%s%s\n", indent(depth), strings.TrimSpace(stmt))
+}
+
+func (g *generator) writeNote(note *model.Note, depth int) {
+	var place string
+	switch {
+	case note.Over && len(note.Lifelines) > 0:
+		refs := []string{}
+		for _, ll := range note.Lifelines {
+			refs = append(refs, g.refs[ll])
+		}
+		place = "over " + strings.Join(refs, ",")
+	case len(note.Lifelines) > 0 && note.OnLeft:
+		place = "left of " + g.refs[note.Lifelines[0]]
+	case len(note.Lifelines) > 0:
+		place = "right of " + g.refs[note.Lifelines[0]]
+	case note.OnLeft:
+		place = "left of " + g.refs[note.Assoc.From]
+	default:
+		place = "right of " + g.refs[note.Assoc.To]
+	}
+	fmt.Fprintf(g.buf, "%sNote %s: %s\n", indent(depth), place, escape(note.Text))
+}
+
+func (g *generator) writeSeparator(sep *model.Separator, depth int) {
+	lls := g.seq.Lifelines
+	if len(lls) == 0 {
+		return
+	}
+	place := g.refs[lls[0]]
+	if len(lls) > 1 {
+		place += "," + g.refs[lls[len(lls)-1]]
+	}
+	fmt.Fprintf(g.buf, "%sNote over %s: %s\n", indent(depth), place, escape(sep.Text))
+}
+
+// writeRefs writes the references which are put after the message, or at the top if it is nil,
+// as the notes over the most left and the most right of their participants.
+func (g *generator) writeRefs(before *model.Message, depth int) {
+	for _, frag := range g.seq.Fragments {
+		if frag.Type != model.Ref || frag.Before != before {
+			continue
+		}
+		lls := frag.Lifelines
+		if len(lls) == 0 {
+			lls = g.seq.Lifelines
+		}
+		if len(lls) == 0 {
+			continue
+		}
+		left, right := lls[0], lls[0]
+		for _, ll := range lls {
+			if ll.Index < left.Index {
+				left = ll
+			}
+			if ll.Index > right.Index {
+				right = ll
+			}
+		}
+		place := g.refs[left]
+		if right != left {
+			place += "," + g.refs[right]
+		}
+		fmt.Fprintf(g.buf, "%sNote over %s: ref %s\n", indent(depth), place, escape(frag.Text))
+	}
+}
+
+// escape puts the text into a single line. The semicolons are replaced
+// because Mermaid takes them as the end of the statement.
+func escape(s string) string {
+	s = strings.Replace(s, "\r\n", "<br/>", -1)
+	s = strings.Replace(s, "\n", "<br/>", -1)
+	return strings.Replace(s, ";", ",", -1)
+}
+
+func indent(depth int) string {
+	if depth < 0 {
+		depth = 0
+	}
+	return strings.Repeat(indentUnit, depth)
+}
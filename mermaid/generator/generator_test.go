@@ -0,0 +1,115 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/mermaid"
+	"github.com/rsp9u/seq2xls/mermaid/convertor"
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const expectedMermaid = `sequenceDiagram
+  participant browser
+  participant L1 as web server
+  participant db
+  Note over browser,db: begin
+  browser->>L1: GET /index.html
+  activate L1
+  Note left of browser: left
+  Note over browser,L1: ref Login
+  loop retry
+    L1-)db: query<br/>async
+    alt found
+      db-->>L1:
+    else not found
+      db-->>L1: error
+      Note over L1,db: multi<br/>line
+    end
+  end
+  L1->>L1:
+  deactivate L1
+  Note over browser,db: end
+`
+
+func testModel() *model.SequenceDiagram {
+	browser := &model.Lifeline{Name: "browser", Index: 0}
+	web := &model.Lifeline{Name: "web server", Index: 1}
+	db := &model.Lifeline{Name: "db", Index: 2}
+	msgs := []*model.Message{
+		{Index: 0, From: browser, To: web, Type: model.Synchronous, Text: "GET /index.html"},
+		{Index: 1, From: web, To: db, Type: model.Asynchronous, Text: "query\nasync"},
+		{Index: 2, From: db, To: web, Type: model.Reply},
+		{Index: 3, From: db, To: web, Type: model.Reply, Text: "error"},
+		{Index: 4, From: web, To: web, Type: model.SelfReference},
+	}
+	return &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{browser, web, db},
+		ExecSpecs: []*model.ExecSpec{
+			{Index: 0, Assoc: web, Begin: msgs[0], End: msgs[4]},
+		},
+		Messages: msgs,
+		Fragments: []*model.Fragment{
+			{Index: 0, Begin: msgs[1], End: msgs[3], Type: model.Loop, Text: "retry"},
+			{Index: 1, Begin: msgs[2], End: msgs[3], Type: model.Alt, Text: "found",
+				Operands: []*model.Operand{{Begin: msgs[3], Text: "not found"}}},
+			{Index: 2, Begin: msgs[4], End: msgs[4], Type: model.Group, Text: "flattened"},
+			{Index: 3, Type: model.Ref, Text: "Login", Lifelines: []*model.Lifeline{web, browser}, Before: msgs[0]},
+		},
+		Notes: []*model.Note{
+			{Index: 0, Assoc: msgs[0], OnLeft: true, Text: "left"},
+			{Index: 1, Assoc: msgs[3], Over: true, Lifelines: []*model.Lifeline{web, db}, Text: "multi\nline"},
+		},
+		Separators: []*model.Separator{
+			{Index: 0, Text: "begin"},
+			{Index: 1, Text: "end", Before: msgs[4]},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Generate(buf, testModel()); err != nil {
+		t.Fatalf("Generate error %v", err)
+	}
+	if buf.String() != expectedMermaid {
+		t.Fatalf("Mismatches generated text\n[expect]\n%s\n[actual]\n%s", expectedMermaid, buf.String())
+	}
+}
+
+func TestGenerateRoundTrip(t *testing.T) {
+	expected := testModel()
+	buf := new(bytes.Buffer)
+	if err := Generate(buf, expected); err != nil {
+		t.Fatalf("Generate error %v", err)
+	}
+	d, err := mermaid.ParseMermaid(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParseMermaid error %v", err)
+	}
+	seq, err := convertor.AstToModel(d)
+	if err != nil {
+		t.Fatalf("AstToModel error %v", err)
+	}
+
+	for i, ll := range seq.Lifelines {
+		if ll.Name != expected.Lifelines[i].Name {
+			t.Errorf("Mismatches lifeline[%d]: %+v", i, ll)
+		}
+	}
+	if len(seq.Messages) != len(expected.Messages) {
+		t.Fatalf("Mismatches the number of messages: %d", len(seq.Messages))
+	}
+	for i, msg := range seq.Messages {
+		e := expected.Messages[i]
+		if msg.From.Name != e.From.Name || msg.To.Name != e.To.Name || msg.Type != e.Type || msg.Text != e.Text {
+			t.Errorf("Mismatches message[%d]: %+v", i, msg)
+		}
+	}
+	if len(seq.Fragments) != 2 || seq.Fragments[1].Operands[0].Begin.Index != 3 {
+		t.Errorf("Mismatches fragments: %+v", seq.Fragments)
+	}
+	if len(seq.ExecSpecs) != 1 || seq.ExecSpecs[0].Begin.Index != 0 || seq.ExecSpecs[0].End.Index != 4 {
+		t.Errorf("Mismatches exec specs: %+v", seq.ExecSpecs)
+	}
+}
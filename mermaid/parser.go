@@ -0,0 +1,186 @@
+package mermaid
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/mermaid/ast"
+)
+
+var (
+	headerPattern      = regexp.MustCompile(`^sequenceDiagram\b`)
+	participantPattern = regexp.MustCompile(`^(?:create\s+)?(participant|actor)\s+(\S+?)(?:\s+as\s+(.+))?$`)
+	messagePattern     = regexp.MustCompile(`^([^\s:+\-<>]+)\s*(--?(?:>>|>|x|\)))\s*([+-]?)\s*([^\s:+\-<>]+)\s*(?::(.*))?$`)
+	activationPattern  = regexp.MustCompile(`^(activate|deactivate|destroy)\s+(\S+)$`)
+	blockPattern       = regexp.MustCompile(`^(loop|alt|opt|par|critical|break|rect|box)(?:\s+(.*))?$`)
+	elsePattern        = regexp.MustCompile(`^(else|and|option)(?:\s+(.*))?$`)
+	notePattern        = regexp.MustCompile(`(?i)^note\s+(left of|right of|over)\s+([^:]+?)\s*:(.*)$`)
+	breakPattern       = regexp.MustCompile(`(?i)<br\s*/?>`)
+	ignoredPattern     = regexp.MustCompile(`^(title|accTitle|accDescr|link|links|properties|details)\b`)
+
+	// elseKeywords is the keyword to partition each type of the block.
+	elseKeywords = map[string]string{
+		"alt":      "else",
+		"par":      "and",
+		"critical": "option",
+	}
+)
+
+// frame is a statement list which is being parsed.
+type frame struct {
+	block *ast.BlockStmt
+	stmts *[]ast.Stmt
+}
+
+type lineParser struct {
+	lines  []string
+	next   int
+	frames []*frame
+}
+
+// ParseMermaid parses the given Mermaid sequence diagram text and converts into Go structures.
+//
+// The text must begin with 'sequenceDiagram'. The statements which do not affect
+// the drawable model, such as 'title' and 'link', are skipped.
+func ParseMermaid(b []byte) (*ast.Diagram, error) {
+	p := &lineParser{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		p.lines = append(p.lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	line, _, ok := p.readLine()
+	if !ok || !headerPattern.MatchString(line) {
+		return nil, fmt.Errorf("this is not a Mermaid sequence diagram")
+	}
+
+	d := &ast.Diagram{Stmts: []ast.Stmt{}}
+	p.frames = []*frame{{stmts: &d.Stmts}}
+	for {
+		line, pos, ok := p.readLine()
+		if !ok {
+			break
+		}
+		if err := p.parseLine(line, pos); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(p.frames) > 1 {
+		block := p.frames[len(p.frames)-1].block
+		return nil, fmt.Errorf("line %d: '%s' is not closed with 'end'", block.Line, block.Type)
+	}
+	return d, nil
+}
+
+// readLine returns the next line which is not blank nor a comment.
+func (p *lineParser) readLine() (string, ast.Pos, bool) {
+	for p.next < len(p.lines) {
+		line := strings.TrimSpace(p.lines[p.next])
+		p.next++
+		if line == "" || strings.HasPrefix(line, "%%") {
+			continue
+		}
+		return strings.TrimSuffix(line, ";"), ast.Pos{Line: p.next}, true
+	}
+	return "", ast.Pos{}, false
+}
+
+func (p *lineParser) add(stmt ast.Stmt) {
+	f := p.frames[len(p.frames)-1]
+	*f.stmts = append(*f.stmts, stmt)
+}
+
+func (p *lineParser) parseLine(line string, pos ast.Pos) error {
+	if m := participantPattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.ParticipantStmt{Pos: pos, Kind: m[1], ID: m[2], Alias: unescape(strings.TrimSpace(m[3]))})
+		return nil
+	}
+
+	if m := activationPattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.ActivationStmt{Pos: pos, Kind: m[1], Name: m[2]})
+		return nil
+	}
+
+	if line == "autonumber" {
+		p.add(&ast.AutonumberStmt{Pos: pos})
+		return nil
+	}
+
+	if m := blockPattern.FindStringSubmatch(line); m != nil {
+		block := &ast.BlockStmt{Pos: pos, Type: m[1], Text: unescape(m[2]), Stmts: []ast.Stmt{}}
+		p.add(block)
+		p.frames = append(p.frames, &frame{block: block, stmts: &block.Stmts})
+		return nil
+	}
+
+	if m := elsePattern.FindStringSubmatch(line); m != nil {
+		f := p.frames[len(p.frames)-1]
+		if f.block == nil || elseKeywords[f.block.Type] != m[1] {
+			return fmt.Errorf("line %d: '%s' is out of the block which it partitions", pos.Line, m[1])
+		}
+		clause := &ast.ElseClause{Pos: pos, Text: unescape(m[2]), Stmts: []ast.Stmt{}}
+		f.block.Elses = append(f.block.Elses, clause)
+		f.stmts = &clause.Stmts
+		return nil
+	}
+
+	if line == "end" {
+		if len(p.frames) == 1 {
+			return fmt.Errorf("line %d: 'end' is out of any block", pos.Line)
+		}
+		p.frames = p.frames[:len(p.frames)-1]
+		return nil
+	}
+
+	if m := notePattern.FindStringSubmatch(line); m != nil {
+		note := &ast.NoteStmt{
+			Pos:     pos,
+			Place:   strings.Fields(strings.ToLower(m[1]))[0],
+			Targets: []string{},
+			Text:    unescape(strings.TrimSpace(m[3])),
+		}
+		for _, target := range strings.Split(m[2], ",") {
+			note.Targets = append(note.Targets, strings.TrimSpace(target))
+		}
+		p.add(note)
+		return nil
+	}
+
+	if m := ignoredPattern.FindStringSubmatch(line); m != nil {
+		if m[1] == "accDescr" && strings.HasSuffix(line, "{") {
+			for {
+				l, _, ok := p.readLine()
+				if !ok || l == "}" {
+					break
+				}
+			}
+		}
+		return nil
+	}
+
+	if m := messagePattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.MessageStmt{
+			Pos:        pos,
+			Left:       m[1],
+			Right:      m[4],
+			Arrow:      m[2],
+			Activate:   m[3] == "+",
+			Deactivate: m[3] == "-",
+			Text:       unescape(strings.TrimSpace(m[5])),
+		})
+		return nil
+	}
+
+	return fmt.Errorf("line %d: unknown statement '%s'", pos.Line, line)
+}
+
+func unescape(s string) string {
+	return breakPattern.ReplaceAllString(s, "\n")
+}
@@ -0,0 +1,96 @@
+package mermaid
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/mermaid/ast"
+)
+
+const testDataSimple = `
+sequenceDiagram
+  %% comment
+  participant A as Alice<br/>Smith
+  actor B
+  autonumber
+  A->>+B: Hello
+  B-->>-A: Hi
+  A-xB: lost
+  A--)B
+  loop Every minute
+    A->B: ping
+  end
+  par Alice to Bob
+    A->>B: one
+  and Alice to Carol
+    A->>C: two
+  end
+  rect rgb(191, 223, 255)
+    Note over A,B: over
+  end
+  Note right of B: right
+`
+
+func TestParseSimple(t *testing.T) {
+	d, err := ParseMermaid([]byte(testDataSimple))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	if len(d.Stmts) != 11 {
+		t.Fatalf("Wrong number of statements %d", len(d.Stmts))
+	}
+
+	p := d.Stmts[0].(*ast.ParticipantStmt)
+	if p.Kind != "participant" || p.ID != "A" || p.Alias != "Alice\nSmith" {
+		t.Fatalf("Wrong participant %+v", p)
+	}
+	if p := d.Stmts[1].(*ast.ParticipantStmt); p.Kind != "actor" || p.ID != "B" || p.Alias != "" {
+		t.Fatalf("Wrong participant %+v", p)
+	}
+
+	m := d.Stmts[3].(*ast.MessageStmt)
+	if m.Left != "A" || m.Right != "B" || m.Arrow != "->>" || !m.Activate || m.Text != "Hello" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+	m = d.Stmts[4].(*ast.MessageStmt)
+	if m.Arrow != "-->>" || !m.Deactivate || m.Text != "Hi" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+	if m := d.Stmts[5].(*ast.MessageStmt); m.Arrow != "-x" || m.Right != "B" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+	if m := d.Stmts[6].(*ast.MessageStmt); m.Arrow != "--)" || m.Text != "" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+
+	b := d.Stmts[8].(*ast.BlockStmt)
+	if b.Type != "par" || b.Text != "Alice to Bob" || len(b.Elses) != 1 || b.Elses[0].Text != "Alice to Carol" {
+		t.Fatalf("Wrong block %+v", b)
+	}
+
+	b = d.Stmts[9].(*ast.BlockStmt)
+	n := b.Stmts[0].(*ast.NoteStmt)
+	if n.Place != "over" || len(n.Targets) != 2 || n.Targets[1] != "B" || n.Text != "over" {
+		t.Fatalf("Wrong note %+v", n)
+	}
+	if n := d.Stmts[10].(*ast.NoteStmt); n.Place != "right" || n.Targets[0] != "B" || n.Line != 22 {
+		t.Fatalf("Wrong note %+v", n)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{"graph TD\nA --> B", "this is not a Mermaid sequence diagram"},
+		{"sequenceDiagram\nA->>B\nfoo", "line 3: unknown statement 'foo'"},
+		{"sequenceDiagram\nloop\nA->>B", "line 2: 'loop' is not closed with 'end'"},
+		{"sequenceDiagram\nloop\nA->>B\nelse\nA->>B\nend", "line 4: 'else' is out of the block which it partitions"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseMermaid([]byte(tt.src))
+		if err == nil || err.Error() != tt.msg {
+			t.Fatalf("Wrong error [expect: %s, actual: %v]", tt.msg, err)
+		}
+	}
+}
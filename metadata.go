@@ -0,0 +1,144 @@
+package seq2xls
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/rsp9u/go-xlsshape/oxml"
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+const (
+	metadataNamespace = "https://github.com/rsp9u/seq2xls"
+	metadataVersion   = "1"
+)
+
+// metadataPart is a custom xml part which holds the source text and the model of the diagram.
+type metadataPart struct {
+	XMLName   xml.Name          `xml:"seq2xls"`
+	Namespace string            `xml:"xmlns,attr"`
+	Version   string            `xml:"version,attr"`
+	Sheet     string            `xml:"sheet,attr,omitempty"`
+	Source    string            `xml:"source"`
+	Model     string            `xml:"model"`
+	Elements  []metadataElement `xml:"elements>element"`
+	path      string
+}
+
+// metadataElement is a digest of the shapes drawn for the model element.
+type metadataElement struct {
+	ID     string `xml:"id,attr"`
+	Digest string `xml:"digest,attr"`
+}
+
+// EmbedMetadata embeds the source text and the model of the diagram into the workbook.
+//
+// Together with the identifiers in the description of each shape, it allows the
+// tools to locate which model element a shape comes from.
+func EmbedMetadata(wb *xlsx.Workbook, src []byte, seq *model.SequenceDiagram) error {
+	return EmbedSheetMetadata(wb, wb.Sheets()[0], src, seq)
+}
+
+// EmbedSheetMetadata embeds the source text and the model of the diagram drawn in the worksheet into the workbook.
+func EmbedSheetMetadata(wb *xlsx.Workbook, sheet *xlsx.Sheet, src []byte, seq *model.SequenceDiagram) error {
+	anchors, err := marshalAnchors(sheet.Shapes())
+	if err != nil {
+		return err
+	}
+	part, err := newMetadataPart(src, seq, anchors)
+	if err != nil {
+		return err
+	}
+	part.Sheet = sheet.Name()
+	part.path = fmt.Sprintf("customXml/item%d.xml", sheet.Index()+1)
+
+	wb.AddCustomXML(part)
+	return nil
+}
+
+func newMetadataPart(src []byte, seq *model.SequenceDiagram, anchors []*xlsx.RawAnchor) (*metadataPart, error) {
+	b, err := json.Marshal(model.NewDocument(seq))
+	if err != nil {
+		return nil, err
+	}
+
+	elems := []metadataElement{}
+	for _, id := range elementIDs(anchors) {
+		elems = append(elems, metadataElement{id, digestAnchors(anchors, id)})
+	}
+
+	return &metadataPart{
+		Namespace: metadataNamespace,
+		Version:   metadataVersion,
+		Source:    string(src),
+		Model:     string(b),
+		Elements:  elems,
+	}, nil
+}
+
+func marshalAnchors(shapes []shape.Shape) ([]*xlsx.RawAnchor, error) {
+	anchors := []*xlsx.RawAnchor{}
+	for _, s := range shapes {
+		anchor, err := xlsx.MarshalAnchor(s)
+		if err != nil {
+			return nil, err
+		}
+		anchors = append(anchors, anchor)
+	}
+	return anchors, nil
+}
+
+// elementID returns the identifier of the model element which the anchored shape comes from.
+// It returns an empty string if the shape is not drawn by seq2xls.
+func elementID(anchor *xlsx.RawAnchor) string {
+	if !strings.HasPrefix(anchor.Descr, ShapeDescrPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(anchor.Descr, ShapeDescrPrefix)
+}
+
+// elementIDs returns the identifiers of the model elements in order of appearance.
+func elementIDs(anchors []*xlsx.RawAnchor) []string {
+	ids := []string{}
+	seen := map[string]bool{}
+	for _, anchor := range anchors {
+		id := elementID(anchor)
+		if id != "" && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+	return ids
+}
+
+// digestAnchors returns the digest of all shapes drawn for the model element.
+func digestAnchors(anchors []*xlsx.RawAnchor, id string) string {
+	h := sha1.New()
+	for _, anchor := range anchors {
+		if elementID(anchor) == id {
+			h.Write(anchor.XML)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Path returns the file path in the archive.
+func (m *metadataPart) Path() string {
+	return m.path
+}
+
+// Content returns an xml string generated from object contents.
+func (m *metadataPart) Content() string {
+	content, err := oxml.DefaultEncode(m)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return content
+}
@@ -0,0 +1,18 @@
+package model
+
+import "fmt"
+
+// Constraint is a data model of the duration constraint between two messages, such as "{< 200ms}".
+//
+// Begin is the earlier message and End is the later one, which may be the same message.
+type Constraint struct {
+	Index      int
+	Begin, End *Message
+	Text       string
+	Pos        Pos
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (c *Constraint) ID() string {
+	return fmt.Sprintf("constraint-%d", c.Index)
+}
@@ -8,4 +8,12 @@ type SequenceDiagram struct {
 	Fragments  []*Fragment
 	Notes      []*Note
 	Separators []*Separator
+	// Constraints is the duration constraints between the messages.
+	Constraints []*Constraint
+	// Theme is the name of the built-in theme which the diagram selects, or empty for the default.
+	Theme string
+	// FontFamily is the default typeface of the text, or empty to choose by the language.
+	FontFamily string
+	// Lang is the default language tag of the text such as "ja-JP", or empty to detect from the text.
+	Lang string
 }
@@ -0,0 +1,548 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SchemaVersion is the version of the serialized form of the diagram model.
+// It is incremented when the form changes incompatibly.
+const SchemaVersion = 1
+
+// Document is a serializable form of the diagram model for JSON and YAML.
+// The elements refer to each other by their identifiers instead of pointers.
+type Document struct {
+	Version    int            `json:"version" yaml:"version"`
+	Lifelines  []DocLifeline  `json:"lifelines" yaml:"lifelines"`
+	ExecSpecs  []DocExecSpec  `json:"execSpecs" yaml:"execSpecs"`
+	Messages   []DocMessage   `json:"messages" yaml:"messages"`
+	Fragments  []DocFragment  `json:"fragments" yaml:"fragments"`
+	Notes      []DocNote      `json:"notes" yaml:"notes"`
+	Separators []DocSeparator `json:"separators" yaml:"separators"`
+	Theme      string         `json:"theme,omitempty" yaml:"theme,omitempty"`
+	FontFamily string         `json:"fontFamily,omitempty" yaml:"fontFamily,omitempty"`
+	Lang       string         `json:"lang,omitempty" yaml:"lang,omitempty"`
+	// Constraints is omitted if the diagram has no constraint.
+	Constraints []DocConstraint `json:"constraints,omitempty" yaml:"constraints,omitempty"`
+}
+
+// DocLifeline is a serializable form of Lifeline.
+type DocLifeline struct {
+	ID    string `json:"id" yaml:"id"`
+	Name  string `json:"name" yaml:"name"`
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// DocExecSpec is a serializable form of ExecSpec.
+type DocExecSpec struct {
+	ID       string `json:"id" yaml:"id"`
+	Lifeline string `json:"lifeline" yaml:"lifeline"`
+	Begin    string `json:"begin" yaml:"begin"`
+	End      string `json:"end" yaml:"end"`
+	Color    string `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// DocMessage is a serializable form of Message.
+type DocMessage struct {
+	ID    string `json:"id" yaml:"id"`
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+	Type  string `json:"type,omitempty" yaml:"type,omitempty"`
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	// LabelPosition is the name of the label position, or empty for "left".
+	LabelPosition string `json:"labelPosition,omitempty" yaml:"labelPosition,omitempty"`
+}
+
+// DocFragment is a serializable form of Fragment.
+// Begin and End of the reference are empty, and Lifelines, Before and Link are only for it.
+type DocFragment struct {
+	ID        string       `json:"id" yaml:"id"`
+	Type      string       `json:"type" yaml:"type"`
+	Begin     string       `json:"begin" yaml:"begin"`
+	End       string       `json:"end" yaml:"end"`
+	Text      string       `json:"text,omitempty" yaml:"text,omitempty"`
+	Operands  []DocOperand `json:"operands,omitempty" yaml:"operands,omitempty"`
+	Lifelines []string     `json:"lifelines,omitempty" yaml:"lifelines,omitempty"`
+	Before    string       `json:"before,omitempty" yaml:"before,omitempty"`
+	Link      string       `json:"link,omitempty" yaml:"link,omitempty"`
+}
+
+// DocOperand is a serializable form of Operand.
+type DocOperand struct {
+	Begin string `json:"begin" yaml:"begin"`
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+}
+
+// DocNote is a serializable form of Note.
+type DocNote struct {
+	ID        string   `json:"id" yaml:"id"`
+	Message   string   `json:"message" yaml:"message"`
+	OnLeft    bool     `json:"onLeft,omitempty" yaml:"onLeft,omitempty"`
+	Over      bool     `json:"over,omitempty" yaml:"over,omitempty"`
+	Lifelines []string `json:"lifelines,omitempty" yaml:"lifelines,omitempty"`
+	Text      string   `json:"text" yaml:"text"`
+	Color     string   `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// DocSeparator is a serializable form of Separator.
+type DocSeparator struct {
+	ID     string `json:"id" yaml:"id"`
+	Text   string `json:"text" yaml:"text"`
+	Before string `json:"before,omitempty" yaml:"before,omitempty"`
+	// Type is the name of the separator type, or empty for "divider".
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
+
+// DocConstraint is a serializable form of Constraint.
+type DocConstraint struct {
+	ID    string `json:"id" yaml:"id"`
+	Begin string `json:"begin" yaml:"begin"`
+	End   string `json:"end" yaml:"end"`
+	Text  string `json:"text,omitempty" yaml:"text,omitempty"`
+}
+
+// NewDocument converts the diagram model to the serializable form.
+func NewDocument(seq *SequenceDiagram) *Document {
+	doc := &Document{
+		Version:    SchemaVersion,
+		Lifelines:  []DocLifeline{},
+		ExecSpecs:  []DocExecSpec{},
+		Messages:   []DocMessage{},
+		Fragments:  []DocFragment{},
+		Notes:      []DocNote{},
+		Separators: []DocSeparator{},
+		Theme:      seq.Theme,
+		FontFamily: seq.FontFamily,
+		Lang:       seq.Lang,
+	}
+
+	for _, ll := range seq.Lifelines {
+		doc.Lifelines = append(doc.Lifelines, DocLifeline{ll.ID(), ll.Name, ll.ColorHex})
+	}
+	for _, spec := range seq.ExecSpecs {
+		doc.ExecSpecs = append(doc.ExecSpecs, DocExecSpec{spec.ID(), spec.Assoc.ID(), spec.Begin.ID(), spec.End.ID(), spec.ColorHex})
+	}
+	for _, msg := range seq.Messages {
+		pos := ""
+		if msg.LabelPosition != LabelLeft {
+			pos = msg.LabelPosition.String()
+		}
+		doc.Messages = append(doc.Messages, DocMessage{msg.ID(), msg.From.ID(), msg.To.ID(), msg.Type.String(), msg.Text, msg.ColorHex, pos})
+	}
+	for _, frag := range seq.Fragments {
+		if frag.Type == Ref {
+			lls := []string{}
+			for _, ll := range frag.Lifelines {
+				lls = append(lls, ll.ID())
+			}
+			before := ""
+			if frag.Before != nil {
+				before = frag.Before.ID()
+			}
+			doc.Fragments = append(doc.Fragments, DocFragment{ID: frag.ID(), Type: frag.Type.String(), Text: frag.Text,
+				Lifelines: lls, Before: before, Link: frag.Link})
+			continue
+		}
+		ops := []DocOperand{}
+		for _, op := range frag.Operands {
+			ops = append(ops, DocOperand{op.Begin.ID(), op.Text})
+		}
+		doc.Fragments = append(doc.Fragments, DocFragment{ID: frag.ID(), Type: frag.Type.String(), Begin: frag.Begin.ID(),
+			End: frag.End.ID(), Text: frag.Text, Operands: ops})
+	}
+	for _, note := range seq.Notes {
+		lls := []string{}
+		for _, ll := range note.Lifelines {
+			lls = append(lls, ll.ID())
+		}
+		doc.Notes = append(doc.Notes, DocNote{note.ID(), note.Assoc.ID(), note.OnLeft, note.Over, lls, note.Text, note.ColorHex})
+	}
+	for _, sep := range seq.Separators {
+		before := ""
+		if sep.Before != nil {
+			before = sep.Before.ID()
+		}
+		sepType := ""
+		if sep.Type != Divider {
+			sepType = sep.Type.String()
+		}
+		doc.Separators = append(doc.Separators, DocSeparator{sep.ID(), sep.Text, before, sepType})
+	}
+	for _, c := range seq.Constraints {
+		doc.Constraints = append(doc.Constraints, DocConstraint{c.ID(), c.Begin.ID(), c.End.ID(), c.Text})
+	}
+
+	return doc
+}
+
+// Model converts the serializable form to the diagram model.
+//
+// The identifiers can be any strings unique in each kind of element, and the indices
+// of the elements are given in order of appearance. The colors which are omitted are
+// filled with the defaults, and a message without the type is regarded as synchronous,
+// or self-reference if it is sent to the sender itself.
+//
+// The colors must be 6 hex digits. A fragment and its operands must begin and end in order
+// of the messages, and the fragments must be listed outer-first without overlapping each other.
+func (doc *Document) Model() (*SequenceDiagram, error) {
+	if doc.Version == 0 {
+		return nil, fmt.Errorf("missing schema version")
+	}
+	if doc.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", doc.Version)
+	}
+
+	seq := &SequenceDiagram{
+		Lifelines:  []*Lifeline{},
+		ExecSpecs:  []*ExecSpec{},
+		Messages:   []*Message{},
+		Fragments:  []*Fragment{},
+		Notes:      []*Note{},
+		Separators: []*Separator{},
+		Theme:      doc.Theme,
+		FontFamily: doc.FontFamily,
+		Lang:       doc.Lang,
+	}
+
+	lls := map[string]*Lifeline{}
+	for _, d := range doc.Lifelines {
+		if _, ok := lls[d.ID]; ok || d.ID == "" {
+			return nil, fmt.Errorf("lifeline id '%s' is empty or duplicated", d.ID)
+		}
+		color, err := parseColor("lifeline", d.ID, d.Color, "FFFFFF")
+		if err != nil {
+			return nil, err
+		}
+		ll := &Lifeline{Name: d.Name, Index: len(seq.Lifelines), ColorHex: color}
+		lls[d.ID] = ll
+		seq.Lifelines = append(seq.Lifelines, ll)
+	}
+	lifeline := func(kind, id, ref string) (*Lifeline, error) {
+		if ll, ok := lls[ref]; ok {
+			return ll, nil
+		}
+		return nil, fmt.Errorf("%s '%s' refers to unknown lifeline '%s'", kind, id, ref)
+	}
+
+	msgs := map[string]*Message{}
+	for _, d := range doc.Messages {
+		if _, ok := msgs[d.ID]; ok || d.ID == "" {
+			return nil, fmt.Errorf("message id '%s' is empty or duplicated", d.ID)
+		}
+		from, err := lifeline("message", d.ID, d.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := lifeline("message", d.ID, d.To)
+		if err != nil {
+			return nil, err
+		}
+		msgType, err := parseMessageType(d.Type, from == to)
+		if err != nil {
+			return nil, fmt.Errorf("message '%s': %v", d.ID, err)
+		}
+		labelPos, ok := ParseLabelPosition(orDefault(d.LabelPosition, "left"))
+		if !ok {
+			return nil, fmt.Errorf("message '%s': unknown label position '%s'", d.ID, d.LabelPosition)
+		}
+		color, err := parseColor("message", d.ID, d.Color, "000000")
+		if err != nil {
+			return nil, err
+		}
+		msg := &Message{
+			Index:         len(seq.Messages),
+			From:          from,
+			To:            to,
+			Type:          msgType,
+			ColorHex:      color,
+			Text:          d.Text,
+			LabelPosition: labelPos,
+		}
+		msgs[d.ID] = msg
+		seq.Messages = append(seq.Messages, msg)
+	}
+	message := func(kind, id, ref string) (*Message, error) {
+		if msg, ok := msgs[ref]; ok {
+			return msg, nil
+		}
+		return nil, fmt.Errorf("%s '%s' refers to unknown message '%s'", kind, id, ref)
+	}
+
+	for _, d := range doc.ExecSpecs {
+		ll, err := lifeline("execSpec", d.ID, d.Lifeline)
+		if err != nil {
+			return nil, err
+		}
+		begin, err := message("execSpec", d.ID, d.Begin)
+		if err != nil {
+			return nil, err
+		}
+		end, err := message("execSpec", d.ID, d.End)
+		if err != nil {
+			return nil, err
+		}
+		if begin.Index > end.Index {
+			return nil, fmt.Errorf("execSpec '%s' ends before its beginning", d.ID)
+		}
+		color, err := parseColor("execSpec", d.ID, d.Color, "FFFFFF")
+		if err != nil {
+			return nil, err
+		}
+		seq.ExecSpecs = append(seq.ExecSpecs, &ExecSpec{
+			Assoc:    ll,
+			Index:    len(seq.ExecSpecs),
+			Begin:    begin,
+			End:      end,
+			ColorHex: color,
+		})
+	}
+
+	fragIDs := map[*Fragment]string{}
+	for _, d := range doc.Fragments {
+		fragType, err := parseFragmentType(d.Type)
+		if err != nil {
+			return nil, fmt.Errorf("fragment '%s': %v", d.ID, err)
+		}
+		if fragType == Ref {
+			frag := &Fragment{Index: len(seq.Fragments), Type: Ref, Text: d.Text, Lifelines: []*Lifeline{}, Link: d.Link}
+			for _, ref := range d.Lifelines {
+				ll, err := lifeline("fragment", d.ID, ref)
+				if err != nil {
+					return nil, err
+				}
+				frag.Lifelines = append(frag.Lifelines, ll)
+			}
+			if d.Before != "" {
+				before, err := message("fragment", d.ID, d.Before)
+				if err != nil {
+					return nil, err
+				}
+				frag.Before = before
+			}
+			seq.Fragments = append(seq.Fragments, frag)
+			continue
+		}
+		begin, err := message("fragment", d.ID, d.Begin)
+		if err != nil {
+			return nil, err
+		}
+		end, err := message("fragment", d.ID, d.End)
+		if err != nil {
+			return nil, err
+		}
+		if begin.Index > end.Index {
+			return nil, fmt.Errorf("fragment '%s' ends before its beginning", d.ID)
+		}
+		frag := &Fragment{Index: len(seq.Fragments), Begin: begin, End: end, Type: fragType, Text: d.Text}
+		for _, op := range d.Operands {
+			opBegin, err := message("fragment", d.ID, op.Begin)
+			if err != nil {
+				return nil, err
+			}
+			if opBegin.Index < begin.Index || opBegin.Index > end.Index {
+				return nil, fmt.Errorf("fragment '%s' has the operand beginning with '%s' outside of it", d.ID, op.Begin)
+			}
+			frag.Operands = append(frag.Operands, &Operand{Begin: opBegin, Text: op.Text})
+		}
+		for _, prev := range seq.Fragments {
+			if prev.Type == Ref {
+				continue
+			}
+			if err := checkNesting(prev, frag, fragIDs[prev], d.ID); err != nil {
+				return nil, err
+			}
+		}
+		fragIDs[frag] = d.ID
+		seq.Fragments = append(seq.Fragments, frag)
+	}
+
+	for _, d := range doc.Notes {
+		assoc, err := message("note", d.ID, d.Message)
+		if err != nil {
+			return nil, err
+		}
+		color, err := parseColor("note", d.ID, d.Color, "ffb6c1")
+		if err != nil {
+			return nil, err
+		}
+		note := &Note{
+			Index:     len(seq.Notes),
+			Assoc:     assoc,
+			OnLeft:    d.OnLeft,
+			Over:      d.Over,
+			Lifelines: []*Lifeline{},
+			Text:      d.Text,
+			ColorHex:  color,
+		}
+		for _, ref := range d.Lifelines {
+			ll, err := lifeline("note", d.ID, ref)
+			if err != nil {
+				return nil, err
+			}
+			note.Lifelines = append(note.Lifelines, ll)
+		}
+		seq.Notes = append(seq.Notes, note)
+	}
+
+	for _, d := range doc.Separators {
+		sepType, err := parseSeparatorType(d.Type)
+		if err != nil {
+			return nil, fmt.Errorf("separator '%s': %v", d.ID, err)
+		}
+		sep := &Separator{Index: len(seq.Separators), Type: sepType, Text: d.Text}
+		if d.Before != "" {
+			before, err := message("separator", d.ID, d.Before)
+			if err != nil {
+				return nil, err
+			}
+			sep.Before = before
+		}
+		seq.Separators = append(seq.Separators, sep)
+	}
+
+	for _, d := range doc.Constraints {
+		begin, err := message("constraint", d.ID, d.Begin)
+		if err != nil {
+			return nil, err
+		}
+		end, err := message("constraint", d.ID, d.End)
+		if err != nil {
+			return nil, err
+		}
+		if begin.Index > end.Index {
+			begin, end = end, begin
+		}
+		seq.Constraints = append(seq.Constraints, &Constraint{Index: len(seq.Constraints), Begin: begin, End: end, Text: d.Text})
+	}
+
+	return seq, nil
+}
+
+// EncodeJSON writes the diagram model out to w as an indented JSON document.
+func EncodeJSON(w io.Writer, seq *SequenceDiagram) error {
+	b, err := json.MarshalIndent(NewDocument(seq), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// DecodeJSON reads a JSON document from r and converts it to the diagram model.
+func DecodeJSON(r io.Reader) (*SequenceDiagram, error) {
+	doc := &Document{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Model()
+}
+
+// EncodeYAML writes the diagram model out to w as a YAML document.
+func EncodeYAML(w io.Writer, seq *SequenceDiagram) error {
+	b, err := yaml.Marshal(NewDocument(seq))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// DecodeYAML reads a YAML document from r and converts it to the diagram model.
+func DecodeYAML(r io.Reader) (*SequenceDiagram, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	err = yaml.UnmarshalStrict(b, doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Model()
+}
+
+func parseMessageType(s string, self bool) (MessageType, error) {
+	if s == "" {
+		if self {
+			return SelfReference, nil
+		}
+		return Synchronous, nil
+	}
+	for t := Synchronous; t <= SelfReference; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return Synchronous, fmt.Errorf("unknown message type '%s'", s)
+}
+
+func parseFragmentType(s string) (FragmentType, error) {
+	for t := Ref; t <= UnknownFragment; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return UnknownFragment, fmt.Errorf("unknown fragment type '%s'", s)
+}
+
+func parseSeparatorType(s string) (SeparatorType, error) {
+	if s == "" {
+		return Divider, nil
+	}
+	for t := Divider; t <= Delay; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return Divider, fmt.Errorf("unknown separator type '%s'", s)
+}
+
+// checkNesting returns an error if the fragment encloses the preceding one, which must be listed after it,
+// or overlaps it.
+func checkNesting(prev, frag *Fragment, prevID, id string) error {
+	b, e := frag.Begin.Index, frag.End.Index
+	pb, pe := prev.Begin.Index, prev.End.Index
+	if b == pb && e == pe || e < pb || pe < b {
+		return nil
+	}
+	if b <= pb && pe <= e {
+		return fmt.Errorf("fragment '%s' encloses the preceding fragment '%s'", id, prevID)
+	}
+	if pb <= b && e <= pe {
+		return nil
+	}
+	return fmt.Errorf("fragment '%s' overlaps the fragment '%s'", id, prevID)
+}
+
+// parseColor returns the color of 6 hex digits without '#', or def if it is omitted.
+func parseColor(kind, id, color, def string) (string, error) {
+	if color == "" {
+		return def, nil
+	}
+	hex := strings.TrimPrefix(color, "#")
+	if len(hex) != 6 {
+		return "", fmt.Errorf("%s '%s': invalid color '%s', which must be 6 hex digits", kind, id, color)
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", fmt.Errorf("%s '%s': invalid color '%s', which must be 6 hex digits", kind, id, color)
+		}
+	}
+	return hex, nil
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func testDiagram() *SequenceDiagram {
+	a := &Lifeline{Name: "a", Index: 0, ColorHex: "FFFFFF"}
+	b := &Lifeline{Name: "b", Index: 1, ColorHex: "ADD8E6"}
+	msgs := []*Message{
+		{Index: 0, From: a, To: b, Type: Synchronous, ColorHex: "000000", Text: "call", LabelPosition: LabelCenter},
+		{Index: 1, From: b, To: b, Type: SelfReference, ColorHex: "000000"},
+		{Index: 2, From: b, To: a, Type: Reply, ColorHex: "FF0000", Text: "multi\nline"},
+	}
+	return &SequenceDiagram{
+		Lifelines: []*Lifeline{a, b},
+		ExecSpecs: []*ExecSpec{{Assoc: b, Index: 0, Begin: msgs[0], End: msgs[2], ColorHex: "FFFFFF"}},
+		Messages:  msgs,
+		Fragments: []*Fragment{
+			{Index: 0, Begin: msgs[0], End: msgs[2], Type: Alt, Text: "ok", Operands: []*Operand{{Begin: msgs[2], Text: "ng"}}},
+			{Index: 1, Type: Ref, Text: "login", Lifelines: []*Lifeline{b}, Before: msgs[0], Link: "login.xlsx"},
+		},
+		Notes: []*Note{
+			{Index: 0, Assoc: msgs[1], Over: true, Lifelines: []*Lifeline{a, b}, Text: "note", ColorHex: "ffb6c1"},
+		},
+		Separators: []*Separator{
+			{Index: 0, Text: "begin"},
+			{Index: 1, Type: Delay, Text: "5 minutes later", Before: msgs[2]},
+		},
+		Constraints: []*Constraint{{Index: 0, Begin: msgs[0], End: msgs[2], Text: "{< 200ms}"}},
+		Theme:       "monochrome",
+		FontFamily:  "Meiryo",
+		Lang:        "ja-JP",
+	}
+}
+
+func checkDiagram(t *testing.T, seq *SequenceDiagram) {
+	expected := testDiagram()
+	if len(seq.Lifelines) != 2 || seq.Lifelines[1].Name != "b" || seq.Lifelines[1].ColorHex != "ADD8E6" {
+		t.Errorf("Mismatches lifelines: %+v", seq.Lifelines)
+	}
+	if len(seq.Messages) != len(expected.Messages) {
+		t.Fatalf("Mismatches the number of messages: %d", len(seq.Messages))
+	}
+	for i, msg := range seq.Messages {
+		e := expected.Messages[i]
+		if msg.Index != i || msg.From.Index != e.From.Index || msg.To.Index != e.To.Index ||
+			msg.Type != e.Type || msg.Text != e.Text || msg.ColorHex != e.ColorHex || msg.LabelPosition != e.LabelPosition {
+			t.Errorf("Mismatches message[%d]: %+v", i, msg)
+		}
+	}
+	if len(seq.ExecSpecs) != 1 || seq.ExecSpecs[0].Assoc != seq.Lifelines[1] || seq.ExecSpecs[0].End != seq.Messages[2] {
+		t.Errorf("Mismatches exec specs: %+v", seq.ExecSpecs)
+	}
+	if len(seq.Fragments) != 2 || seq.Fragments[0].Type != Alt || seq.Fragments[0].Operands[0].Begin != seq.Messages[2] {
+		t.Fatalf("Mismatches fragments: %+v", seq.Fragments)
+	}
+	if ref := seq.Fragments[1]; ref.Type != Ref || ref.Begin != nil || ref.Before != seq.Messages[0] ||
+		len(ref.Lifelines) != 1 || ref.Lifelines[0] != seq.Lifelines[1] || ref.Text != "login" || ref.Link != "login.xlsx" {
+		t.Errorf("Mismatches reference: %+v", ref)
+	}
+	if len(seq.Notes) != 1 || seq.Notes[0].Assoc != seq.Messages[1] || len(seq.Notes[0].Lifelines) != 2 || !seq.Notes[0].Over {
+		t.Errorf("Mismatches notes: %+v", seq.Notes)
+	}
+	if len(seq.Separators) != 2 || seq.Separators[0].Before != nil || seq.Separators[1].Before != seq.Messages[2] ||
+		seq.Separators[0].Type != Divider || seq.Separators[1].Type != Delay {
+		t.Errorf("Mismatches separators: %+v", seq.Separators)
+	}
+	if len(seq.Constraints) != 1 || seq.Constraints[0].Begin != seq.Messages[0] || seq.Constraints[0].End != seq.Messages[2] ||
+		seq.Constraints[0].Text != "{< 200ms}" {
+		t.Errorf("Mismatches constraints: %+v", seq.Constraints)
+	}
+	if seq.Theme != expected.Theme || seq.FontFamily != expected.FontFamily || seq.Lang != expected.Lang {
+		t.Errorf("Mismatches theme or fonts: %s, %s, %s", seq.Theme, seq.FontFamily, seq.Lang)
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := EncodeJSON(buf, testDiagram()); err != nil {
+		t.Fatalf("EncodeJSON error %v", err)
+	}
+	seq, err := DecodeJSON(buf)
+	if err != nil {
+		t.Fatalf("DecodeJSON error %v", err)
+	}
+	checkDiagram(t, seq)
+}
+
+func TestYAMLRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := EncodeYAML(buf, testDiagram()); err != nil {
+		t.Fatalf("EncodeYAML error %v", err)
+	}
+	seq, err := DecodeYAML(buf)
+	if err != nil {
+		t.Fatalf("DecodeYAML error %v", err)
+	}
+	checkDiagram(t, seq)
+}
+
+func TestDecodeJSONDefaults(t *testing.T) {
+	src := `{
+  "version": 1,
+  "lifelines": [{"id": "client", "name": "Client"}, {"id": "server", "name": "Server"}],
+  "messages": [
+    {"id": "req", "from": "client", "to": "server", "text": "GET"},
+    {"id": "loop", "from": "server", "to": "server"}
+  ],
+  "notes": [{"id": "n", "message": "req", "text": "hello"}]
+}`
+	seq, err := DecodeJSON(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("DecodeJSON error %v", err)
+	}
+	if seq.Lifelines[0].ColorHex != "FFFFFF" || seq.Messages[0].ColorHex != "000000" {
+		t.Errorf("Mismatches default colors")
+	}
+	if seq.Messages[0].Type != Synchronous || seq.Messages[1].Type != SelfReference {
+		t.Errorf("Mismatches default message types: %v, %v", seq.Messages[0].Type, seq.Messages[1].Type)
+	}
+	if seq.Notes[0].Assoc != seq.Messages[0] {
+		t.Errorf("Mismatches note reference")
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	msgs := `{"version": 1, "lifelines": [{"id": "a", "name": "a"}], "messages": [
+		{"id": "m0", "from": "a", "to": "a"}, {"id": "m1", "from": "a", "to": "a"}, {"id": "m2", "from": "a", "to": "a"}],`
+	tests := []struct {
+		src, err string
+	}{
+		{`{"lifelines": []}`, "missing schema version"},
+		{`{"version": 99}`, "unsupported schema version 99"},
+		{`{"version": 1, "messages": [{"id": "m", "from": "x", "to": "x"}]}`, "message 'm' refers to unknown lifeline 'x'"},
+		{`{"version": 1, "notes": [{"id": "n", "message": "m", "text": ""}]}`, "note 'n' refers to unknown message 'm'"},
+		{`{"version": 1, "fragments": [{"id": "f", "type": "foo", "begin": "", "end": ""}]}`, "fragment 'f': unknown fragment type 'foo'"},
+		{`{"version": 1, "constraints": [{"id": "c", "begin": "m", "end": "m"}]}`, "constraint 'c' refers to unknown message 'm'"},
+		{`{"version": 1, "lifelines": [{"id": "a", "name": "a", "color": "red"}]}`, "lifeline 'a': invalid color 'red', which must be 6 hex digits"},
+		{msgs + `"notes": [{"id": "n", "message": "m0", "text": "", "color": "#12345G"}]}`, "note 'n': invalid color '#12345G', which must be 6 hex digits"},
+		{msgs + `"execSpecs": [{"id": "e", "lifeline": "a", "begin": "m1", "end": "m0"}]}`, "execSpec 'e' ends before its beginning"},
+		{msgs + `"fragments": [{"id": "f", "type": "loop", "begin": "m1", "end": "m0"}]}`, "fragment 'f' ends before its beginning"},
+		{msgs + `"fragments": [{"id": "f", "type": "alt", "begin": "m0", "end": "m1", "operands": [{"begin": "m2"}]}]}`,
+			"fragment 'f' has the operand beginning with 'm2' outside of it"},
+		{msgs + `"fragments": [{"id": "f", "type": "loop", "begin": "m1", "end": "m1"}, {"id": "g", "type": "loop", "begin": "m0", "end": "m2"}]}`,
+			"fragment 'g' encloses the preceding fragment 'f'"},
+		{msgs + `"fragments": [{"id": "f", "type": "loop", "begin": "m0", "end": "m1"}, {"id": "g", "type": "loop", "begin": "m1", "end": "m2"}]}`,
+			"fragment 'g' overlaps the fragment 'f'"},
+	}
+	for _, tt := range tests {
+		_, err := DecodeJSON(strings.NewReader(tt.src))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Mismatches error of %s: expect %q, actual %v", tt.src, tt.err, err)
+		}
+	}
+}
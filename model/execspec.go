@@ -1,8 +1,17 @@
 package model
 
+import "fmt"
+
 // ExecSpec is a data model of the execution specification.
 type ExecSpec struct {
-	Assoc    *Lifeline
-	Index    int
-	ColorHex string
+	Assoc      *Lifeline
+	Index      int
+	Begin, End *Message
+	ColorHex   string
+	Pos        Pos
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (spec *ExecSpec) ID() string {
+	return fmt.Sprintf("execspec-%d", spec.Index)
 }
@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 // FragmentType is a type of the kind of fragment.
 type FragmentType int
 
@@ -26,15 +28,34 @@ const (
 	Ignore
 	// Consider is the consider fragment type.
 	Consider
+	// Group is the fragment type which only groups the messages with a label.
+	Group
 	// UnknownFragment is the unknown type of fragment.
 	UnknownFragment
 )
 
 // Fragment is a data model of the fragment.
+//
+// The reference (Ref) encloses no message, so Begin and End of it are nil. It refers to
+// the diagram named by Text instead, and is put after Before, or at the top if it is nil.
 type Fragment struct {
 	Index      int
 	Begin, End *Message
 	Type       FragmentType
+	Text       string
+	Operands   []*Operand
+	Pos        Pos
+	// Lifelines is the lifelines which the reference covers, or empty for all of them.
+	Lifelines []*Lifeline
+	Before    *Message
+	// Link is the worksheet or the path of the workbook of the referred diagram, or empty to find it by Text.
+	Link string
+}
+
+// Operand is a data model of the second or later operand of the fragment, such as 'else' of the alternatives.
+type Operand struct {
+	Begin *Message
+	Text  string
 }
 
 func (t FragmentType) String() string {
@@ -61,6 +82,13 @@ func (t FragmentType) String() string {
 		return "ignore"
 	case Consider:
 		return "consider"
+	case Group:
+		return "group"
 	}
 	return "unknown"
 }
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (frag *Fragment) ID() string {
+	return fmt.Sprintf("fragment-%d", frag.Index)
+}
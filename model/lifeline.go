@@ -1,8 +1,16 @@
 package model
 
+import "fmt"
+
 // Lifeline is a data model of the lifeline.
 type Lifeline struct {
 	Name     string
 	Index    int
 	ColorHex string
+	Pos      Pos
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (ll *Lifeline) ID() string {
+	return fmt.Sprintf("lifeline-%d", ll.Index)
 }
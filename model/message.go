@@ -1,5 +1,7 @@
 package model
 
+import "fmt"
+
 // MessageType is a type of the kind of message.
 type MessageType int
 
@@ -18,12 +20,76 @@ const (
 	SelfReference
 )
 
+// LabelPosition is the position of the label of a message relative to its line.
+// The labels of the self-reference messages are always put on the right of the loops.
+type LabelPosition int
+
+const (
+	// LabelLeft puts the label above the line from its left end.
+	LabelLeft LabelPosition = iota
+	// LabelCenter puts the label above the center of the line.
+	LabelCenter
+	// LabelRight puts the label above the line up to its right end.
+	LabelRight
+	// LabelBelow puts the label below the line from its left end.
+	LabelBelow
+)
+
 // Message is a data model of the message.
 type Message struct {
-	Index    int
-	From     *Lifeline
-	To       *Lifeline
-	Type     MessageType
-	ColorHex string
-	Text     string
+	Index         int
+	From          *Lifeline
+	To            *Lifeline
+	Type          MessageType
+	ColorHex      string
+	Text          string
+	LabelPosition LabelPosition
+	Pos           Pos
+}
+
+func (t MessageType) String() string {
+	switch t {
+	case Synchronous:
+		return "synchronous"
+	case Asynchronous:
+		return "asynchronous"
+	case Reply:
+		return "reply"
+	case Found:
+		return "found"
+	case Lost:
+		return "lost"
+	case SelfReference:
+		return "self-reference"
+	}
+	return "unknown"
+}
+
+func (p LabelPosition) String() string {
+	switch p {
+	case LabelLeft:
+		return "left"
+	case LabelCenter:
+		return "center"
+	case LabelRight:
+		return "right"
+	case LabelBelow:
+		return "below"
+	}
+	return "unknown"
+}
+
+// ParseLabelPosition returns the label position of the name, or false if it is unknown.
+func ParseLabelPosition(s string) (LabelPosition, bool) {
+	for p := LabelLeft; p <= LabelBelow; p++ {
+		if p.String() == s {
+			return p, true
+		}
+	}
+	return LabelLeft, false
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (msg *Message) ID() string {
+	return fmt.Sprintf("message-%d", msg.Index)
 }
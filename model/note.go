@@ -1,9 +1,22 @@
 package model
 
+import "fmt"
+
 // Note is a data model of the note.
+//
+// The note is put beside the associated message, or beside or over the lifelines if they are given.
 type Note struct {
-	Assoc    *Message
-	OnLeft   bool
-	Text     string
-	ColorHex string
+	Index     int
+	Assoc     *Message
+	OnLeft    bool
+	Over      bool
+	Lifelines []*Lifeline
+	Text      string
+	ColorHex  string
+	Pos       Pos
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (note *Note) ID() string {
+	return fmt.Sprintf("note-%d", note.Index)
 }
@@ -0,0 +1,18 @@
+package model
+
+import "fmt"
+
+// Pos is a position in the source text which the element comes from.
+// The zero value means that the position is unknown, e.g. for the elements given as JSON.
+type Pos struct {
+	Line, Column int
+}
+
+// IsValid returns whether the position is known.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
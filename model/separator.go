@@ -1,7 +1,37 @@
 package model
 
+import "fmt"
+
+// SeparatorType is a type of the kind of separator.
+type SeparatorType int
+
+const (
+	// Divider is the separator which divides the timeline into sections, drawn as double lines.
+	Divider SeparatorType = iota
+	// Delay is the separator which shows the elapsed time, drawn as a gap of dotted lifelines.
+	Delay
+)
+
 // Separator is a data model of the separator line.
 type Separator struct {
+	Index  int
+	Type   SeparatorType
 	Text   string
 	Before *Message
+	Pos    Pos
+}
+
+func (t SeparatorType) String() string {
+	switch t {
+	case Divider:
+		return "divider"
+	case Delay:
+		return "delay"
+	}
+	return "unknown"
+}
+
+// ID returns the identifier of this, which is unique in the diagram.
+func (sep *Separator) ID() string {
+	return fmt.Sprintf("separator-%d", sep.Index)
 }
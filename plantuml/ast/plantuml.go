@@ -0,0 +1,111 @@
+package ast
+
+// Pos is a position of the statement in the source text.
+type Pos struct {
+	Line int
+}
+
+// Position returns the position of the statement.
+func (p Pos) Position() Pos {
+	return p
+}
+
+// Diagram is the root of the PlantUML sequence diagram.
+type Diagram struct {
+	Stmts []Stmt
+}
+
+// Stmt is a statement of the diagram.
+type Stmt interface {
+	Position() Pos
+}
+
+// ParticipantStmt declares a participant such as 'participant', 'actor' and 'database'.
+//
+// The participant is referred by Alias if it is given, otherwise by Name.
+type ParticipantStmt struct {
+	Pos
+	Kind  string
+	Name  string
+	Alias string
+	Color string
+}
+
+// MessageStmt is an arrow between the participants.
+//
+// Arrow is the arrow text without the style, such as "->", "-->>" and "<-".
+// Activate and Deactivate are set by the '++' and '--' shortcuts.
+type MessageStmt struct {
+	Pos
+	Left       string
+	Right      string
+	Arrow      string
+	Color      string
+	Activate   bool
+	Deactivate bool
+	Text       string
+}
+
+// ActivationStmt is 'activate', 'deactivate' or 'destroy' of the participant.
+type ActivationStmt struct {
+	Pos
+	Kind  string
+	Name  string
+	Color string
+}
+
+// AutonumberStmt starts, stops or resumes the automatic numbering of the messages.
+//
+// Start and Step are zero if they are not given.
+type AutonumberStmt struct {
+	Pos
+	Command string
+	Start   int
+	Step    int
+}
+
+// BlockStmt is a grouping block such as 'alt', 'loop' and 'group'.
+type BlockStmt struct {
+	Pos
+	Type  string
+	Text  string
+	Stmts []Stmt
+	Elses []*ElseClause
+}
+
+// ElseClause is an 'else' partition of the block.
+type ElseClause struct {
+	Pos
+	Text  string
+	Stmts []Stmt
+}
+
+// NoteStmt is a note beside or over the participants.
+//
+// The note is attached to the previous message if Targets is empty.
+type NoteStmt struct {
+	Pos
+	Place   string
+	Targets []string
+	Color   string
+	Text    string
+}
+
+// RefStmt is a reference to another diagram over the participants.
+type RefStmt struct {
+	Pos
+	Targets []string
+	Text    string
+}
+
+// DividerStmt is a divider such as '== Initialization =='.
+type DividerStmt struct {
+	Pos
+	Text string
+}
+
+// DelayStmt is a delay such as '...' and '... 5 minutes later ...'.
+type DelayStmt struct {
+	Pos
+	Text string
+}
@@ -0,0 +1,77 @@
+package convertor
+
+import (
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml/ast"
+)
+
+// namedColors is the hex codes of the color names commonly used in PlantUML.
+var namedColors = map[string]string{
+	"black":       "000000",
+	"white":       "FFFFFF",
+	"gray":        "808080",
+	"grey":        "808080",
+	"lightgray":   "D3D3D3",
+	"lightgrey":   "D3D3D3",
+	"red":         "FF0000",
+	"pink":        "FFC0CB",
+	"orange":      "FFA500",
+	"yellow":      "FFFF00",
+	"lightyellow": "FFFFE0",
+	"green":       "008000",
+	"lightgreen":  "90EE90",
+	"blue":        "0000FF",
+	"lightblue":   "ADD8E6",
+	"skyblue":     "87CEEB",
+	"purple":      "800080",
+}
+
+// AstToModel converts from the sequence diagram AST of PlantUML to the drawable model.
+func AstToModel(d *ast.Diagram) (*model.SequenceDiagram, error) {
+	seq := &model.SequenceDiagram{}
+
+	lls, err := ExtractLifelines(d)
+	if err != nil {
+		return nil, err
+	}
+	seq.Lifelines = lls
+
+	err = ScanTimeline(d, seq)
+	if err != nil {
+		return nil, err
+	}
+
+	return seq, nil
+}
+
+// colorHex converts the color of PlantUML such as '#FF0000' and '#red' to the hex code.
+// It returns the default if the color is not given or unknown.
+func colorHex(color, def string) string {
+	color = strings.TrimPrefix(color, "#")
+	if hex, ok := namedColors[strings.ToLower(color)]; ok {
+		return hex
+	}
+	switch len(color) {
+	case 6:
+		if isHex(color) {
+			return strings.ToUpper(color)
+		}
+	case 3:
+		if isHex(color) {
+			c := strings.ToUpper(color)
+			return string([]byte{c[0], c[0], c[1], c[1], c[2], c[2]})
+		}
+	}
+	return def
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
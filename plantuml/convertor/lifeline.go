@@ -0,0 +1,72 @@
+package convertor
+
+import (
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml/ast"
+)
+
+// ExtractLifelines extracts lifeline elements from the diagram.
+//
+// The lifelines are ordered by their first appearance, whether they are declared or not.
+func ExtractLifelines(d *ast.Diagram) ([]*model.Lifeline, error) {
+	lls := []*model.Lifeline{}
+	aliases := map[string]*model.Lifeline{}
+	extractLifelinesFromStmts(d.Stmts, &lls, aliases)
+	return lls, nil
+}
+
+func extractLifelinesFromStmts(stmts []ast.Stmt, lls *[]*model.Lifeline, aliases map[string]*model.Lifeline) {
+	add := func(ref, name, color string) {
+		if _, ok := aliases[ref]; ok {
+			return
+		}
+		ll := &model.Lifeline{Name: name, Index: len(*lls), ColorHex: colorHex(color, "FFFFFF")}
+		*lls = append(*lls, ll)
+		aliases[ref] = ll
+	}
+
+	for _, stmt := range stmts {
+		switch v := stmt.(type) {
+		case *ast.ParticipantStmt:
+			ref := v.Alias
+			if ref == "" {
+				ref = v.Name
+			}
+			add(ref, v.Name, v.Color)
+
+		case *ast.MessageStmt:
+			add(v.Left, v.Left, "")
+			add(v.Right, v.Right, "")
+
+		case *ast.ActivationStmt:
+			add(v.Name, v.Name, "")
+
+		case *ast.NoteStmt:
+			for _, target := range v.Targets {
+				add(target, target, "")
+			}
+
+		case *ast.RefStmt:
+			for _, target := range v.Targets {
+				add(target, target, "")
+			}
+
+		case *ast.BlockStmt:
+			extractLifelinesFromStmts(v.Stmts, lls, aliases)
+			for _, clause := range v.Elses {
+				extractLifelinesFromStmts(clause.Stmts, lls, aliases)
+			}
+		}
+	}
+}
+
+// lifelineRefs returns the lifelines by the names which the statements refer to.
+func lifelineRefs(d *ast.Diagram, lls []*model.Lifeline) map[string]*model.Lifeline {
+	refs := map[string]*model.Lifeline{}
+	all := []*model.Lifeline{}
+	extractLifelinesFromStmts(d.Stmts, &all, refs)
+	for ref, ll := range refs {
+		refs[ref] = lls[ll.Index]
+	}
+	return refs
+}
@@ -0,0 +1,53 @@
+package convertor
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml"
+)
+
+const testDataLifeline = `
+@startuml
+participant "Web Server" as web #FFAAAA
+actor browser
+browser -> web
+web -> database
+alt
+  web -> cache
+end
+note over queue : idle
+@enduml
+`
+
+func checkLifeline(t *testing.T, ll *model.Lifeline, idx int, name, color string) {
+	if ll.Index != idx {
+		t.Fatalf("Invalid lifeline index %s[%d]", ll.Name, ll.Index)
+	}
+	if ll.Name != name {
+		t.Fatalf("Mismatches lifeline name [expect: %s, actual: %s]", name, ll.Name)
+	}
+	if ll.ColorHex != color {
+		t.Fatalf("Mismatches lifeline color [expect: %s, actual: %s]", color, ll.ColorHex)
+	}
+}
+
+func TestExtractLifelines(t *testing.T) {
+	d, err := plantuml.ParsePlantUML([]byte(testDataLifeline))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	lls, err := ExtractLifelines(d)
+	if err != nil {
+		t.Fatalf("Extract error %v", err)
+	}
+
+	if len(lls) != 5 {
+		t.Fatalf("Wrong number of lifelines %d", len(lls))
+	}
+	checkLifeline(t, lls[0], 0, "Web Server", "FFAAAA")
+	checkLifeline(t, lls[1], 1, "browser", "FFFFFF")
+	checkLifeline(t, lls[2], 2, "database", "FFFFFF")
+	checkLifeline(t, lls[3], 3, "cache", "FFFFFF")
+	checkLifeline(t, lls[4], 4, "queue", "FFFFFF")
+}
@@ -0,0 +1,303 @@
+package convertor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml/ast"
+)
+
+// timelineScanner holds the state while scanning the statements in order.
+type timelineScanner struct {
+	seq  *model.SequenceDiagram
+	refs map[string]*model.Lifeline
+
+	// active is the activations not deactivated yet.
+	// The beginning of an activation is not fixed until a message is sent if no message precedes it.
+	active []*model.ExecSpec
+	// pendingNotes is the notes over the lifelines which precede the first message.
+	pendingNotes []*model.Note
+
+	numbering    bool
+	number, step int
+}
+
+// ScanTimeline extracts all time series elements from the diagram AST and puts them into the given diagram model.
+func ScanTimeline(d *ast.Diagram, seq *model.SequenceDiagram) error {
+	seq.ExecSpecs = []*model.ExecSpec{}
+	seq.Messages = []*model.Message{}
+	seq.Fragments = []*model.Fragment{}
+	seq.Notes = []*model.Note{}
+	seq.Separators = []*model.Separator{}
+
+	s := &timelineScanner{seq: seq, refs: lifelineRefs(d, seq.Lifelines)}
+	err := s.scanStmts(d.Stmts)
+	if err != nil {
+		return err
+	}
+	if len(s.pendingNotes) > 0 {
+		return fmt.Errorf("notes need at least one message")
+	}
+	s.closeActivations()
+	return nil
+}
+
+func (s *timelineScanner) lastMessage() *model.Message {
+	if len(s.seq.Messages) == 0 {
+		return nil
+	}
+	return s.seq.Messages[len(s.seq.Messages)-1]
+}
+
+func (s *timelineScanner) scanStmts(stmts []ast.Stmt) error {
+	for _, stmt := range stmts {
+		switch v := stmt.(type) {
+		case *ast.MessageStmt:
+			msg := s.newMessage(v)
+			s.seq.Messages = append(s.seq.Messages, msg)
+
+			for _, spec := range s.active {
+				if spec.Begin == nil {
+					spec.Begin = msg
+				}
+			}
+			for _, note := range s.pendingNotes {
+				note.Assoc = msg
+			}
+			s.pendingNotes = nil
+
+			if v.Deactivate {
+				if err := s.deactivate(msg.From, v.Pos, true); err != nil {
+					return err
+				}
+			}
+			if v.Activate {
+				s.activate(msg.To, "")
+			}
+
+		case *ast.ActivationStmt:
+			ll := s.refs[v.Name]
+			var err error
+			switch v.Kind {
+			case "activate":
+				s.activate(ll, v.Color)
+			case "deactivate":
+				err = s.deactivate(ll, v.Pos, true)
+			case "destroy":
+				err = s.deactivate(ll, v.Pos, false)
+			}
+			if err != nil {
+				return err
+			}
+
+		case *ast.AutonumberStmt:
+			switch v.Command {
+			case "stop":
+				s.numbering = false
+			case "resume":
+				s.numbering = true
+				if v.Step != 0 {
+					s.step = v.Step
+				}
+			default:
+				s.numbering = true
+				s.number, s.step = 1, 1
+				if v.Start != 0 {
+					s.number = v.Start
+				}
+				if v.Step != 0 {
+					s.step = v.Step
+				}
+			}
+
+		case *ast.BlockStmt:
+			frag := &model.Fragment{
+				Index: len(s.seq.Fragments),
+				Type:  getFragmentType(v.Type),
+				Text:  v.Text,
+			}
+			s.seq.Fragments = append(s.seq.Fragments, frag)
+
+			beginIndex := len(s.seq.Messages)
+			if err := s.scanStmts(v.Stmts); err != nil {
+				return err
+			}
+			if len(s.seq.Messages) == beginIndex {
+				return fmt.Errorf("line %d: empty '%s' is not allowed", v.Line, v.Type)
+			}
+			frag.Begin = s.seq.Messages[beginIndex]
+
+			for _, clause := range v.Elses {
+				opIndex := len(s.seq.Messages)
+				if err := s.scanStmts(clause.Stmts); err != nil {
+					return err
+				}
+				if len(s.seq.Messages) == opIndex {
+					return fmt.Errorf("line %d: empty 'else' is not allowed", clause.Line)
+				}
+				frag.Operands = append(frag.Operands, &model.Operand{
+					Begin: s.seq.Messages[opIndex],
+					Text:  clause.Text,
+				})
+			}
+			frag.End = s.lastMessage()
+
+		case *ast.NoteStmt:
+			note := &model.Note{
+				Index:     len(s.seq.Notes),
+				Assoc:     s.lastMessage(),
+				OnLeft:    v.Place == "left",
+				Over:      v.Place == "over",
+				Lifelines: []*model.Lifeline{},
+				Text:      v.Text,
+				ColorHex:  colorHex(v.Color, "FBFB77"),
+			}
+			for _, target := range v.Targets {
+				note.Lifelines = append(note.Lifelines, s.refs[target])
+			}
+			if note.Assoc == nil {
+				if len(note.Lifelines) == 0 {
+					return fmt.Errorf("line %d: note has no message to be attached to", v.Line)
+				}
+				s.pendingNotes = append(s.pendingNotes, note)
+			}
+			s.seq.Notes = append(s.seq.Notes, note)
+
+		case *ast.RefStmt:
+			ref := &model.Fragment{
+				Index:     len(s.seq.Fragments),
+				Type:      model.Ref,
+				Text:      v.Text,
+				Lifelines: []*model.Lifeline{},
+				Before:    s.lastMessage(),
+			}
+			for _, target := range v.Targets {
+				ref.Lifelines = append(ref.Lifelines, s.refs[target])
+			}
+			s.seq.Fragments = append(s.seq.Fragments, ref)
+
+		case *ast.DividerStmt:
+			s.addSeparator(model.Divider, v.Text)
+
+		case *ast.DelayStmt:
+			s.addSeparator(model.Delay, v.Text)
+		}
+	}
+
+	return nil
+}
+
+func (s *timelineScanner) newMessage(stmt *ast.MessageStmt) *model.Message {
+	from, to := s.refs[stmt.Left], s.refs[stmt.Right]
+	if strings.HasPrefix(stmt.Arrow, "<") {
+		from, to = to, from
+	}
+
+	msgType := getMessageType(stmt.Arrow)
+	if from == to {
+		msgType = model.SelfReference
+	}
+
+	text := stmt.Text
+	if s.numbering {
+		text = strings.TrimSpace(fmt.Sprintf("%d %s", s.number, text))
+		s.number += s.step
+	}
+
+	return &model.Message{
+		Index:    len(s.seq.Messages),
+		From:     from,
+		To:       to,
+		Type:     msgType,
+		ColorHex: colorHex(stmt.Color, "000000"),
+		Text:     text,
+	}
+}
+
+// activate begins an activation of the lifeline from the last message.
+func (s *timelineScanner) activate(ll *model.Lifeline, color string) {
+	spec := &model.ExecSpec{
+		Assoc:    ll,
+		Index:    len(s.seq.ExecSpecs),
+		Begin:    s.lastMessage(),
+		ColorHex: colorHex(color, "FFFFFF"),
+	}
+	s.seq.ExecSpecs = append(s.seq.ExecSpecs, spec)
+	s.active = append(s.active, spec)
+}
+
+// deactivate ends the innermost activation of the lifeline at the last message.
+func (s *timelineScanner) deactivate(ll *model.Lifeline, pos ast.Pos, strict bool) error {
+	for i := len(s.active) - 1; i >= 0; i-- {
+		if s.active[i].Assoc == ll {
+			s.active[i].End = s.lastMessage()
+			s.active = append(s.active[:i], s.active[i+1:]...)
+			return nil
+		}
+	}
+	if strict {
+		return fmt.Errorf("line %d: '%s' is not activated", pos.Line, ll.Name)
+	}
+	return nil
+}
+
+// closeActivations ends the remaining activations at the last message and
+// drops the activations during which no message is sent.
+func (s *timelineScanner) closeActivations() {
+	for _, spec := range s.active {
+		spec.End = s.lastMessage()
+	}
+	s.active = nil
+
+	specs := []*model.ExecSpec{}
+	for _, spec := range s.seq.ExecSpecs {
+		if spec.Begin == nil || spec.End == nil || spec.End.Index < spec.Begin.Index {
+			continue
+		}
+		spec.Index = len(specs)
+		specs = append(specs, spec)
+	}
+	s.seq.ExecSpecs = specs
+}
+
+func (s *timelineScanner) addSeparator(sepType model.SeparatorType, text string) {
+	s.seq.Separators = append(s.seq.Separators, &model.Separator{
+		Index:  len(s.seq.Separators),
+		Type:   sepType,
+		Text:   text,
+		Before: s.lastMessage(),
+	})
+}
+
+func getMessageType(arrow string) model.MessageType {
+	switch {
+	case strings.Contains(arrow, "--"):
+		return model.Reply
+	case strings.Contains(arrow, ">>"), strings.Contains(arrow, "<<"):
+		return model.Asynchronous
+	default:
+		return model.Synchronous
+	}
+}
+
+func getFragmentType(t string) model.FragmentType {
+	switch t {
+	case "alt":
+		return model.Alt
+	case "opt":
+		return model.Opt
+	case "loop":
+		return model.Loop
+	case "par":
+		return model.Par
+	case "break":
+		return model.Break
+	case "critical":
+		return model.Critical
+	case "group":
+		return model.Group
+	default:
+		return model.UnknownFragment
+	}
+}
@@ -0,0 +1,170 @@
+package convertor
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml"
+)
+
+const testDataTimeline = `
+@startuml
+autonumber
+a -> b : request
+activate b
+b -> b : self
+b ->> c ++
+a <-- b : reply
+deactivate b
+autonumber stop
+c --> b --
+@enduml
+`
+
+const testDataFragment = `
+@startuml
+note over a : start
+loop 10 times
+  a -> b
+  alt ok
+    b -> c
+    b -> d
+  else error
+    b --> a
+  else
+    b ->> a
+  end
+end
+== Done ==
+note left : last
+@enduml
+`
+
+const testDataRef = `
+@startuml
+ref over a, c : Login
+a -> b
+ref over b
+  Logout
+end ref
+@enduml
+`
+
+func convert(t *testing.T, src string) *model.SequenceDiagram {
+	d, err := plantuml.ParsePlantUML([]byte(src))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	seq, err := AstToModel(d)
+	if err != nil {
+		t.Fatalf("Convert error %v", err)
+	}
+	return seq
+}
+
+func checkMessage(t *testing.T, msg *model.Message, from, to string, typ model.MessageType, text string) {
+	if msg.From.Name != from || msg.To.Name != to || msg.Type != typ || msg.Text != text {
+		t.Fatalf("Wrong message %d [%s -> %s (%s) '%s']", msg.Index, msg.From.Name, msg.To.Name, msg.Type, msg.Text)
+	}
+}
+
+func TestScanTimeline(t *testing.T) {
+	seq := convert(t, testDataTimeline)
+
+	if len(seq.Messages) != 5 {
+		t.Fatalf("Wrong number of messages %d", len(seq.Messages))
+	}
+	checkMessage(t, seq.Messages[0], "a", "b", model.Synchronous, "1 request")
+	checkMessage(t, seq.Messages[1], "b", "b", model.SelfReference, "2 self")
+	checkMessage(t, seq.Messages[2], "b", "c", model.Asynchronous, "3")
+	checkMessage(t, seq.Messages[3], "b", "a", model.Reply, "4 reply")
+	checkMessage(t, seq.Messages[4], "c", "b", model.Reply, "")
+
+	if len(seq.ExecSpecs) != 2 {
+		t.Fatalf("Wrong number of execution specifications %d", len(seq.ExecSpecs))
+	}
+	spec := seq.ExecSpecs[0]
+	if spec.Assoc.Name != "b" || spec.Begin != seq.Messages[0] || spec.End != seq.Messages[3] {
+		t.Fatalf("Wrong execution specification %d", spec.Index)
+	}
+	spec = seq.ExecSpecs[1]
+	if spec.Assoc.Name != "c" || spec.Begin != seq.Messages[2] || spec.End != seq.Messages[4] {
+		t.Fatalf("Wrong execution specification %d", spec.Index)
+	}
+}
+
+func TestScanFragment(t *testing.T) {
+	seq := convert(t, testDataFragment)
+
+	if len(seq.Fragments) != 2 {
+		t.Fatalf("Wrong number of fragments %d", len(seq.Fragments))
+	}
+	loop, alt := seq.Fragments[0], seq.Fragments[1]
+	if loop.Type != model.Loop || loop.Text != "10 times" || loop.Begin != seq.Messages[0] || loop.End != seq.Messages[4] {
+		t.Fatalf("Wrong loop fragment %+v", loop)
+	}
+	if alt.Type != model.Alt || alt.Text != "ok" || alt.Begin != seq.Messages[1] || alt.End != seq.Messages[4] {
+		t.Fatalf("Wrong alt fragment %+v", alt)
+	}
+	if len(alt.Operands) != 2 || alt.Operands[0].Begin != seq.Messages[3] || alt.Operands[0].Text != "error" ||
+		alt.Operands[1].Begin != seq.Messages[4] || alt.Operands[1].Text != "" {
+		t.Fatalf("Wrong operands of alt fragment")
+	}
+
+	if len(seq.Notes) != 2 {
+		t.Fatalf("Wrong number of notes %d", len(seq.Notes))
+	}
+	note := seq.Notes[0]
+	if !note.Over || len(note.Lifelines) != 1 || note.Lifelines[0].Name != "a" || note.Assoc != seq.Messages[0] {
+		t.Fatalf("Wrong note %+v", note)
+	}
+	note = seq.Notes[1]
+	if !note.OnLeft || len(note.Lifelines) != 0 || note.Assoc != seq.Messages[4] {
+		t.Fatalf("Wrong note %+v", note)
+	}
+
+	if len(seq.Separators) != 1 || seq.Separators[0].Text != "Done" || seq.Separators[0].Before != seq.Messages[4] {
+		t.Fatalf("Wrong separators")
+	}
+}
+
+func TestScanRef(t *testing.T) {
+	seq := convert(t, testDataRef)
+
+	if len(seq.Lifelines) != 3 || seq.Lifelines[1].Name != "c" {
+		t.Fatalf("The lifelines of the reference are not extracted")
+	}
+	if len(seq.Fragments) != 2 {
+		t.Fatalf("Wrong number of fragments %d", len(seq.Fragments))
+	}
+	ref := seq.Fragments[0]
+	if ref.Type != model.Ref || ref.Text != "Login" || ref.Before != nil || len(ref.Lifelines) != 2 || ref.Lifelines[1] != seq.Lifelines[1] {
+		t.Fatalf("Wrong reference %+v", ref)
+	}
+	ref = seq.Fragments[1]
+	if ref.Type != model.Ref || ref.Text != "Logout" || ref.Before != seq.Messages[0] || len(ref.Lifelines) != 1 || ref.Lifelines[0].Name != "b" {
+		t.Fatalf("Wrong reference %+v", ref)
+	}
+}
+
+func TestScanErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{"@startuml\na -> b\nloop\nend\n@enduml", "line 3: empty 'loop' is not allowed"},
+		{"@startuml\nalt\na -> b\nelse\nend\n@enduml", "line 4: empty 'else' is not allowed"},
+		{"@startuml\na -> b\ndeactivate b\n@enduml", "line 3: 'b' is not activated"},
+		{"@startuml\nnote left : x\na -> b\n@enduml", "line 2: note has no message to be attached to"},
+	}
+
+	for _, tt := range tests {
+		d, err := plantuml.ParsePlantUML([]byte(tt.src))
+		if err != nil {
+			t.Fatalf("Parse error %v", err)
+		}
+		_, err = AstToModel(d)
+		if err == nil || err.Error() != tt.msg {
+			t.Fatalf("Wrong error [expect: %s, actual: %v]", tt.msg, err)
+		}
+	}
+}
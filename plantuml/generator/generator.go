@@ -0,0 +1,236 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const indentUnit = "  "
+
+var (
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9_.]+$`)
+
+	// blockTypes is the fragment types which PlantUML has its own keyword for.
+	// The others are written as 'group' with the type name.
+	blockTypes = map[model.FragmentType]bool{
+		model.Alt:      true,
+		model.Opt:      true,
+		model.Loop:     true,
+		model.Par:      true,
+		model.Break:    true,
+		model.Critical: true,
+		model.Group:    true,
+	}
+)
+
+// Generate writes the given diagram model out as a PlantUML sequence diagram.
+//
+// The participants whose names are not valid bare names are declared with aliases.
+// Fragments which PlantUML has no keyword for are written as 'group' labeled with their types.
+// The references are written over their lifelines, or all of them, without the links.
+func Generate(w io.Writer, seq *model.SequenceDiagram) error {
+	g := &generator{buf: new(bytes.Buffer), refs: map[*model.Lifeline]string{}}
+	g.buf.WriteString("@startuml\n")
+
+	for _, ll := range seq.Lifelines {
+		g.writeParticipant(ll)
+	}
+	if len(seq.Lifelines) > 0 && len(seq.Messages) > 0 {
+		g.buf.WriteString("\n")
+	}
+
+	for _, sep := range seq.Separators {
+		if sep.Before == nil {
+			g.writeSeparator(sep, 0)
+		}
+	}
+	g.writeRefs(seq, nil, 0)
+
+	depth := 0
+	for _, msg := range seq.Messages {
+		for _, frag := range seq.Fragments {
+			for _, op := range frag.Operands {
+				if op.Begin == msg {
+					fmt.Fprintf(g.buf, "%s%s\n", indent(depth-1), strings.TrimSpace("else "+escape(op.Text)))
+				}
+			}
+		}
+		for _, frag := range seq.Fragments {
+			if frag.Begin == msg {
+				fmt.Fprintf(g.buf, "%s%s\n", indent(depth), blockHeader(frag))
+				depth++
+			}
+		}
+
+		g.writeMessage(msg, depth)
+		for _, spec := range seq.ExecSpecs {
+			if spec.Begin == msg {
+				g.writeActivation("activate", spec, depth)
+			}
+		}
+		for _, spec := range seq.ExecSpecs {
+			if spec.End == msg {
+				g.writeActivation("deactivate", spec, depth)
+			}
+		}
+		for _, note := range seq.Notes {
+			if note.Assoc == msg {
+				g.writeNote(note, depth)
+			}
+		}
+
+		for i := len(seq.Fragments) - 1; i >= 0; i-- {
+			if seq.Fragments[i].End == msg {
+				depth--
+				fmt.Fprintf(g.buf, "%send\n", indent(depth))
+			}
+		}
+
+		for _, sep := range seq.Separators {
+			if sep.Before == msg {
+				g.writeSeparator(sep, depth)
+			}
+		}
+		g.writeRefs(seq, msg, depth)
+	}
+
+	g.buf.WriteString("@enduml\n")
+
+	_, err := w.Write(g.buf.Bytes())
+	return err
+}
+
+type generator struct {
+	buf *bytes.Buffer
+	// refs is the names to refer to the lifelines in the statements.
+	refs map[*model.Lifeline]string
+}
+
+func (g *generator) writeParticipant(ll *model.Lifeline) {
+	decl := "participant " + ll.Name
+	g.refs[ll] = ll.Name
+	if !namePattern.MatchString(ll.Name) {
+		alias := fmt.Sprintf("L%d", ll.Index)
+		decl = fmt.Sprintf(`participant "%s" as %s`, escape(ll.Name), alias)
+		g.refs[ll] = alias
+	}
+	if c := color(ll.ColorHex, "FFFFFF"); c != "" {
+		decl += " " + c
+	}
+	g.buf.WriteString(decl + "\n")
+}
+
+func (g *generator) writeMessage(msg *model.Message, depth int) {
+	arrow := "->"
+	switch msg.Type {
+	case model.Asynchronous:
+		arrow = "->>"
+	case model.Reply:
+		arrow = "-->"
+	}
+	if c := color(msg.ColorHex, "000000"); c != "" {
+		arrow = arrow[:1] + "[" + c + "]" + arrow[1:]
+	}
+
+	stmt := fmt.Sprintf("%s %s %s", g.refs[msg.From], arrow, g.refs[msg.To])
+	if msg.Text != "" {
+		stmt += " : " + escape(msg.Text)
+	}
+	fmt.Fprintf(g.buf, "%s%s\n", indent(depth), stmt)
+}
+
+func (g *generator) writeActivation(kind string, spec *model.ExecSpec, depth int) {
+	stmt := kind + " " + g.refs[spec.Assoc]
+	if c := color(spec.ColorHex, "FFFFFF"); c != "" && kind == "activate" {
+		stmt += " " + c
+	}
+	fmt.Fprintf(g.buf, "%s%s\n", indent(depth), stmt)
+}
+
+func (g *generator) writeNote(note *model.Note, depth int) {
+	place := "right"
+	if note.OnLeft {
+		place = "left"
+	}
+	switch {
+	case note.Over && len(note.Lifelines) > 0:
+		place = "over " + g.joinRefs(note.Lifelines)
+	case len(note.Lifelines) > 0:
+		place += " of " + g.refs[note.Lifelines[0]]
+	}
+
+	stmt := "note " + place
+	if c := color(note.ColorHex, "FBFB77"); c != "" {
+		stmt += " " + c
+	}
+	fmt.Fprintf(g.buf, "%s%s : %s\n", indent(depth), stmt, escape(note.Text))
+}
+
+func (g *generator) writeSeparator(sep *model.Separator, depth int) {
+	if sep.Type == model.Delay {
+		if sep.Text == "" {
+			fmt.Fprintf(g.buf, "%s...\n", indent(depth))
+		} else {
+			fmt.Fprintf(g.buf, "%s... %s ...\n", indent(depth), escape(sep.Text))
+		}
+		return
+	}
+	text := strings.Replace(sep.Text, "=", "-", -1)
+	fmt.Fprintf(g.buf, "%s== %s ==\n", indent(depth), escape(text))
+}
+
+// writeRefs writes the references which are put after the message, or at the top if it is nil.
+func (g *generator) writeRefs(seq *model.SequenceDiagram, before *model.Message, depth int) {
+	for _, frag := range seq.Fragments {
+		if frag.Type != model.Ref || frag.Before != before {
+			continue
+		}
+		lls := frag.Lifelines
+		if len(lls) == 0 {
+			lls = seq.Lifelines
+		}
+		fmt.Fprintf(g.buf, "%sref over %s : %s\n", indent(depth), g.joinRefs(lls), escape(frag.Text))
+	}
+}
+
+func (g *generator) joinRefs(lls []*model.Lifeline) string {
+	refs := []string{}
+	for _, ll := range lls {
+		refs = append(refs, g.refs[ll])
+	}
+	return strings.Join(refs, ", ")
+}
+
+// blockHeader returns the line which opens the fragment.
+func blockHeader(frag *model.Fragment) string {
+	if blockTypes[frag.Type] {
+		return strings.TrimSpace(frag.Type.String() + " " + escape(frag.Text))
+	}
+	return strings.TrimSpace("group " + frag.Type.String() + " " + escape(frag.Text))
+}
+
+// color returns the color in the PlantUML notation, or empty if it is unset or the default.
+func color(hex, def string) string {
+	if hex == "" || strings.EqualFold(hex, def) {
+		return ""
+	}
+	return "#" + strings.ToUpper(hex)
+}
+
+// escape puts the text into a single line.
+func escape(s string) string {
+	s = strings.Replace(s, "\r\n", `\n`, -1)
+	return strings.Replace(s, "\n", `\n`, -1)
+}
+
+func indent(depth int) string {
+	if depth < 0 {
+		depth = 0
+	}
+	return strings.Repeat(indentUnit, depth)
+}
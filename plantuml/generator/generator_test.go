@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/plantuml"
+	"github.com/rsp9u/seq2xls/plantuml/convertor"
+)
+
+const expectedPlantUML = `@startuml
+participant browser
+participant "web server" as L1 #ADD8E6
+participant db
+
+== begin ==
+ref over browser, L1 : Login
+browser -> L1 : GET /index.html
+activate L1
+note left : left
+loop retry
+  L1 ->> db : query\nasync
+  alt found
+    db --> L1
+  else not found
+    db -[#FF0000]-> L1 : error
+    note over L1, db : multi\nline
+  end
+end
+L1 -> L1
+deactivate L1
+... end ...
+ref over browser, L1, db : Logout
+@enduml
+`
+
+func testModel() *model.SequenceDiagram {
+	browser := &model.Lifeline{Name: "browser", Index: 0, ColorHex: "FFFFFF"}
+	web := &model.Lifeline{Name: "web server", Index: 1, ColorHex: "ADD8E6"}
+	db := &model.Lifeline{Name: "db", Index: 2, ColorHex: "FFFFFF"}
+	msgs := []*model.Message{
+		{Index: 0, From: browser, To: web, Type: model.Synchronous, ColorHex: "000000", Text: "GET /index.html"},
+		{Index: 1, From: web, To: db, Type: model.Asynchronous, ColorHex: "000000", Text: "query\nasync"},
+		{Index: 2, From: db, To: web, Type: model.Reply, ColorHex: "000000"},
+		{Index: 3, From: db, To: web, Type: model.Reply, ColorHex: "FF0000", Text: "error"},
+		{Index: 4, From: web, To: web, Type: model.SelfReference, ColorHex: "000000"},
+	}
+	return &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{browser, web, db},
+		ExecSpecs: []*model.ExecSpec{
+			{Index: 0, Assoc: web, Begin: msgs[0], End: msgs[4], ColorHex: "FFFFFF"},
+		},
+		Messages: msgs,
+		Fragments: []*model.Fragment{
+			{Index: 0, Begin: msgs[1], End: msgs[3], Type: model.Loop, Text: "retry"},
+			{Index: 1, Begin: msgs[2], End: msgs[3], Type: model.Alt, Text: "found",
+				Operands: []*model.Operand{{Begin: msgs[3], Text: "not found"}}},
+			{Index: 2, Type: model.Ref, Text: "Login", Lifelines: []*model.Lifeline{browser, web}},
+			{Index: 3, Type: model.Ref, Text: "Logout", Before: msgs[4]},
+		},
+		Notes: []*model.Note{
+			{Index: 0, Assoc: msgs[0], OnLeft: true, Text: "left", ColorHex: "FBFB77"},
+			{Index: 1, Assoc: msgs[3], Over: true, Lifelines: []*model.Lifeline{web, db}, Text: "multi\nline", ColorHex: "FBFB77"},
+		},
+		Separators: []*model.Separator{
+			{Index: 0, Text: "begin"},
+			{Index: 1, Type: model.Delay, Text: "end", Before: msgs[4]},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := Generate(buf, testModel()); err != nil {
+		t.Fatalf("Generate error %v", err)
+	}
+	if buf.String() != expectedPlantUML {
+		t.Fatalf("Mismatches generated text\n[expect]\n%s\n[actual]\n%s", expectedPlantUML, buf.String())
+	}
+}
+
+func TestGenerateRoundTrip(t *testing.T) {
+	expected := testModel()
+	buf := new(bytes.Buffer)
+	if err := Generate(buf, expected); err != nil {
+		t.Fatalf("Generate error %v", err)
+	}
+	d, err := plantuml.ParsePlantUML(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ParsePlantUML error %v", err)
+	}
+	seq, err := convertor.AstToModel(d)
+	if err != nil {
+		t.Fatalf("AstToModel error %v", err)
+	}
+
+	for i, ll := range seq.Lifelines {
+		if ll.Name != expected.Lifelines[i].Name || ll.ColorHex != expected.Lifelines[i].ColorHex {
+			t.Errorf("Mismatches lifeline[%d]: %+v", i, ll)
+		}
+	}
+	if len(seq.Messages) != len(expected.Messages) {
+		t.Fatalf("Mismatches the number of messages: %d", len(seq.Messages))
+	}
+	for i, msg := range seq.Messages {
+		e := expected.Messages[i]
+		if msg.From.Name != e.From.Name || msg.To.Name != e.To.Name || msg.Type != e.Type || msg.Text != e.Text || msg.ColorHex != e.ColorHex {
+			t.Errorf("Mismatches message[%d]: %+v", i, msg)
+		}
+	}
+	if len(seq.Fragments) != 4 || seq.Fragments[2].Operands[0].Begin.Index != 3 ||
+		seq.Fragments[0].Type != model.Ref || len(seq.Fragments[0].Lifelines) != 2 || seq.Fragments[3].Before.Index != 4 {
+		t.Errorf("Mismatches fragments: %+v", seq.Fragments)
+	}
+	if len(seq.Notes) != 2 || seq.Notes[1].Assoc.Index != 3 || !seq.Notes[1].Over {
+		t.Errorf("Mismatches notes: %+v", seq.Notes)
+	}
+	if len(seq.ExecSpecs) != 1 || seq.ExecSpecs[0].Begin.Index != 0 || seq.ExecSpecs[0].End.Index != 4 {
+		t.Errorf("Mismatches exec specs: %+v", seq.ExecSpecs)
+	}
+	if len(seq.Separators) != 2 || seq.Separators[0].Before != nil || seq.Separators[1].Before.Index != 4 ||
+		seq.Separators[0].Type != model.Divider || seq.Separators[1].Type != model.Delay {
+		t.Errorf("Mismatches separators: %+v", seq.Separators)
+	}
+}
@@ -0,0 +1,357 @@
+package plantuml
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/plantuml/ast"
+)
+
+var (
+	participantPattern = regexp.MustCompile(`^(participant|actor|boundary|control|entity|database|collections|queue)\s+("[^"]*"|[^\s"]+)(?:\s+as\s+("[^"]*"|[^\s"]+))?(?:\s+order\s+-?\d+)?(?:\s+(#\S+))?$`)
+	messagePattern     = regexp.MustCompile(`^("[^"]*"|[^\s<>:"\-]+)\s*(<<?)?-(?:\[([^\]]*)\])?(-)?(>>?)?\s*("[^"]*"|[^\s<>:"\-]+)\s*([+\-*!]*)\s*(?::(.*))?$`)
+	activationPattern  = regexp.MustCompile(`^(activate|deactivate|destroy)\s+("[^"]*"|[^\s"]+)(?:\s+(#\S+))?$`)
+	autonumberPattern  = regexp.MustCompile(`^autonumber(?:\s+(stop|resume))?(?:\s+(\d+))?(?:\s+(\d+))?(?:\s+"[^"]*")?$`)
+	blockPattern       = regexp.MustCompile(`^(alt|opt|loop|par|break|critical|group)(?:\s+(.*))?$`)
+	elsePattern        = regexp.MustCompile(`^else(?:\s+(.*))?$`)
+	notePattern        = regexp.MustCompile(`^[hr]?note\s+(left|right|over)\b(.*)$`)
+	noteColorPattern   = regexp.MustCompile(`\s*(#\S+)$`)
+	noteEndPattern     = regexp.MustCompile(`^end\s*[hr]?note$`)
+	refPattern         = regexp.MustCompile(`^ref\s+over\s+([^:]*?)\s*(?::\s*(.*))?$`)
+	refEndPattern      = regexp.MustCompile(`^end\s*ref$`)
+	dividerPattern     = regexp.MustCompile(`^==+\s*(.*?)\s*==+$`)
+	delayPattern       = regexp.MustCompile(`^\.\.\.\s*(.*?)\s*(?:\.\.\.)?$`)
+	spacingPattern     = regexp.MustCompile(`^\|\|(\d*\||\|)$`)
+	ignoredPattern     = regexp.MustCompile(`^(title|header|footer|caption|scale|hide|show|newpage|mainframe|autoactivate|skinparam|box)\b|^end\s*box$|^!`)
+	blockEndPatterns   = map[string]*regexp.Regexp{
+		"legend":    regexp.MustCompile(`^end\s*legend$`),
+		"skinparam": regexp.MustCompile(`^}$`),
+		"title":     regexp.MustCompile(`^end\s*title$`),
+	}
+)
+
+// frame is a statement list which is being parsed.
+type frame struct {
+	block *ast.BlockStmt
+	stmts *[]ast.Stmt
+}
+
+type lineParser struct {
+	lines  []string
+	next   int
+	frames []*frame
+}
+
+// ParsePlantUML parses the given PlantUML sequence diagram text and converts into Go structures.
+//
+// Only the first diagram between '@startuml' and '@enduml' is parsed.
+// The statements which do not affect the drawable model, such as 'title' and
+// 'skinparam', are skipped.
+func ParsePlantUML(b []byte) (*ast.Diagram, error) {
+	p := &lineParser{}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		p.lines = append(p.lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	d := &ast.Diagram{Stmts: []ast.Stmt{}}
+	p.frames = []*frame{{stmts: &d.Stmts}}
+	for {
+		line, pos, ok := p.readLine()
+		if !ok || strings.HasPrefix(line, "@enduml") {
+			break
+		}
+		if err := p.parseLine(line, pos); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(p.frames) > 1 {
+		block := p.frames[len(p.frames)-1].block
+		return nil, fmt.Errorf("line %d: '%s' is not closed with 'end'", block.Line, block.Type)
+	}
+	return d, nil
+}
+
+// readLine returns the next line which is not blank nor a comment.
+func (p *lineParser) readLine() (string, ast.Pos, bool) {
+	for p.next < len(p.lines) {
+		line := strings.TrimSpace(p.lines[p.next])
+		p.next++
+		pos := ast.Pos{Line: p.next}
+
+		switch {
+		case line == "", strings.HasPrefix(line, "'"), strings.HasPrefix(line, "@startuml"):
+			continue
+		case strings.HasPrefix(line, "/'"):
+			for rest := line[2:]; !strings.Contains(rest, "'/") && p.next < len(p.lines); p.next++ {
+				rest = p.lines[p.next]
+			}
+			continue
+		}
+		return line, pos, true
+	}
+	return "", ast.Pos{}, false
+}
+
+// skipUntil skips the lines until the line which matches the pattern.
+func (p *lineParser) skipUntil(pattern *regexp.Regexp) {
+	for {
+		line, _, ok := p.readLine()
+		if !ok || pattern.MatchString(line) {
+			return
+		}
+	}
+}
+
+func (p *lineParser) add(stmt ast.Stmt) {
+	f := p.frames[len(p.frames)-1]
+	*f.stmts = append(*f.stmts, stmt)
+}
+
+func (p *lineParser) parseLine(line string, pos ast.Pos) error {
+	if m := participantPattern.FindStringSubmatch(line); m != nil {
+		p.add(newParticipantStmt(pos, m))
+		return nil
+	}
+
+	if m := activationPattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.ActivationStmt{Pos: pos, Kind: m[1], Name: unquote(m[2]), Color: m[3]})
+		return nil
+	}
+
+	if m := autonumberPattern.FindStringSubmatch(line); m != nil {
+		start, _ := strconv.Atoi(m[2])
+		step, _ := strconv.Atoi(m[3])
+		if m[1] == "resume" {
+			// 'autonumber resume' takes only the increment
+			start, step = 0, start
+		}
+		p.add(&ast.AutonumberStmt{Pos: pos, Command: m[1], Start: start, Step: step})
+		return nil
+	}
+
+	if m := blockPattern.FindStringSubmatch(line); m != nil {
+		block := &ast.BlockStmt{Pos: pos, Type: m[1], Text: unescape(m[2]), Stmts: []ast.Stmt{}}
+		p.add(block)
+		p.frames = append(p.frames, &frame{block: block, stmts: &block.Stmts})
+		return nil
+	}
+
+	if m := elsePattern.FindStringSubmatch(line); m != nil {
+		f := p.frames[len(p.frames)-1]
+		if f.block == nil {
+			return fmt.Errorf("line %d: 'else' is out of any block", pos.Line)
+		}
+		clause := &ast.ElseClause{Pos: pos, Text: unescape(m[1]), Stmts: []ast.Stmt{}}
+		f.block.Elses = append(f.block.Elses, clause)
+		f.stmts = &clause.Stmts
+		return nil
+	}
+
+	if line == "end" {
+		if len(p.frames) == 1 {
+			return fmt.Errorf("line %d: 'end' is out of any block", pos.Line)
+		}
+		p.frames = p.frames[:len(p.frames)-1]
+		return nil
+	}
+
+	if m := notePattern.FindStringSubmatch(line); m != nil {
+		return p.parseNote(pos, m[1], m[2])
+	}
+
+	if m := refPattern.FindStringSubmatch(line); m != nil {
+		return p.parseRef(pos, line, m)
+	}
+
+	if m := dividerPattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.DividerStmt{Pos: pos, Text: unescape(m[1])})
+		return nil
+	}
+
+	if m := delayPattern.FindStringSubmatch(line); m != nil {
+		p.add(&ast.DelayStmt{Pos: pos, Text: unescape(m[1])})
+		return nil
+	}
+
+	if spacingPattern.MatchString(line) {
+		return nil
+	}
+
+	if m := ignoredPattern.FindStringSubmatch(line); m != nil {
+		switch {
+		case m[1] == "skinparam" && strings.HasSuffix(line, "{"):
+			p.skipUntil(blockEndPatterns["skinparam"])
+		case m[1] == "title" && line == "title":
+			p.skipUntil(blockEndPatterns["title"])
+		}
+		return nil
+	}
+	if line == "legend" || strings.HasPrefix(line, "legend ") {
+		p.skipUntil(blockEndPatterns["legend"])
+		return nil
+	}
+
+	if m := messagePattern.FindStringSubmatch(line); m != nil {
+		stmt, err := newMessageStmt(pos, m)
+		if err != nil {
+			return err
+		}
+		p.add(stmt)
+		return nil
+	}
+
+	return fmt.Errorf("line %d: unknown statement '%s'", pos.Line, line)
+}
+
+// parseNote parses a note. The text of the note continues to 'end note' if it is not given after ':'.
+func (p *lineParser) parseNote(pos ast.Pos, place, rest string) error {
+	head, text := rest, ""
+	hasText := false
+	if i := strings.Index(rest, ":"); i >= 0 {
+		head, text = rest[:i], strings.TrimSpace(rest[i+1:])
+		hasText = true
+	}
+
+	note := &ast.NoteStmt{Pos: pos, Place: place, Targets: []string{}}
+	head = strings.TrimSpace(head)
+	if m := noteColorPattern.FindStringSubmatch(head); m != nil {
+		note.Color = m[1]
+		head = strings.TrimSpace(head[:len(head)-len(m[0])])
+	}
+	if head == "of" || strings.HasPrefix(head, "of ") {
+		head = strings.TrimSpace(head[2:])
+	}
+	for _, target := range strings.Split(head, ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			note.Targets = append(note.Targets, unquote(target))
+		}
+	}
+	if place == "over" && len(note.Targets) == 0 {
+		return fmt.Errorf("line %d: 'note over' needs participants", pos.Line)
+	}
+
+	if hasText {
+		note.Text = unescape(text)
+	} else {
+		var ok bool
+		note.Text, ok = p.readText(noteEndPattern)
+		if !ok {
+			return fmt.Errorf("line %d: 'note' is not closed with 'end note'", pos.Line)
+		}
+	}
+
+	p.add(note)
+	return nil
+}
+
+// parseRef parses a reference over the participants from the matches of refPattern.
+// The text of the reference continues to 'end ref' if it is not given after ':'.
+func (p *lineParser) parseRef(pos ast.Pos, line string, m []string) error {
+	ref := &ast.RefStmt{Pos: pos, Targets: []string{}}
+	for _, target := range strings.Split(m[1], ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			ref.Targets = append(ref.Targets, unquote(target))
+		}
+	}
+	if len(ref.Targets) == 0 {
+		return fmt.Errorf("line %d: 'ref over' needs participants", pos.Line)
+	}
+
+	if strings.Contains(line, ":") {
+		ref.Text = unescape(m[2])
+	} else {
+		var ok bool
+		ref.Text, ok = p.readText(refEndPattern)
+		if !ok {
+			return fmt.Errorf("line %d: 'ref' is not closed with 'end ref'", pos.Line)
+		}
+	}
+
+	p.add(ref)
+	return nil
+}
+
+// readText reads the lines of a text until the line which matches the pattern, and joins them.
+// It returns false if the text is not closed.
+func (p *lineParser) readText(end *regexp.Regexp) (string, bool) {
+	lines := []string{}
+	for {
+		if p.next >= len(p.lines) {
+			return "", false
+		}
+		line := strings.TrimSpace(p.lines[p.next])
+		p.next++
+		if end.MatchString(line) {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// newParticipantStmt creates a participant from the matches of participantPattern.
+//
+// Both of 'participant "Long Name" as L' and 'participant L as "Long Name"' are accepted.
+func newParticipantStmt(pos ast.Pos, m []string) *ast.ParticipantStmt {
+	name, alias := m[2], m[3]
+	if alias != "" && !isQuoted(name) && isQuoted(alias) {
+		name, alias = alias, name
+	}
+	return &ast.ParticipantStmt{
+		Pos:   pos,
+		Kind:  m[1],
+		Name:  unescape(unquote(name)),
+		Alias: unquote(alias),
+		Color: m[4],
+	}
+}
+
+// newMessageStmt creates a message from the matches of messagePattern.
+func newMessageStmt(pos ast.Pos, m []string) (*ast.MessageStmt, error) {
+	lhead, style, dash, rhead := m[2], m[3], m[4], m[5]
+	if (lhead == "") == (rhead == "") {
+		return nil, fmt.Errorf("line %d: arrow must have exactly one head", pos.Line)
+	}
+
+	color := ""
+	for _, s := range strings.Split(style, ",") {
+		if strings.HasPrefix(strings.TrimSpace(s), "#") {
+			color = strings.TrimSpace(s)
+		}
+	}
+
+	return &ast.MessageStmt{
+		Pos:        pos,
+		Left:       unquote(m[1]),
+		Right:      unquote(m[6]),
+		Arrow:      lhead + "-" + dash + rhead,
+		Color:      color,
+		Activate:   strings.Contains(m[7], "++"),
+		Deactivate: strings.Contains(m[7], "--"),
+		Text:       unescape(strings.TrimSpace(m[8])),
+	}, nil
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)
+}
+
+func unquote(s string) string {
+	if isQuoted(s) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func unescape(s string) string {
+	return strings.Replace(s, `\n`, "\n", -1)
+}
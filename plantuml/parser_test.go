@@ -0,0 +1,130 @@
+package plantuml
+
+import (
+	"testing"
+
+	"github.com/rsp9u/seq2xls/plantuml/ast"
+)
+
+const testDataSimple = `
+@startuml
+' comment
+participant "Web Browser" as browser #lightblue
+actor User
+/' block
+   comment '/
+skinparam sequence {
+  ArrowColor red
+}
+title Example
+User -> browser : open\nthe page
+browser -[#red]>> web ++ : GET /index.html
+activate web
+browser <-- web -- : 200 OK
+deactivate web
+alt cached
+  web -> web : hit
+else not cached
+  web -> db
+end
+note over browser, web #yellow : over note
+note left
+  multi
+  line
+end note
+== Divider ==
+... 5 minutes later ...
+ref over browser, "web" : Login
+ref over db
+  Backup
+end ref
+autonumber 10 5
+@enduml
+User -> browser
+`
+
+func TestParseSimple(t *testing.T) {
+	d, err := ParsePlantUML([]byte(testDataSimple))
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	if len(d.Stmts) != 15 {
+		t.Fatalf("Wrong number of statements %d", len(d.Stmts))
+	}
+
+	p := d.Stmts[0].(*ast.ParticipantStmt)
+	if p.Kind != "participant" || p.Name != "Web Browser" || p.Alias != "browser" || p.Color != "#lightblue" {
+		t.Fatalf("Wrong participant %+v", p)
+	}
+
+	m := d.Stmts[2].(*ast.MessageStmt)
+	if m.Left != "User" || m.Right != "browser" || m.Arrow != "->" || m.Text != "open\nthe page" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+	if m.Line != 12 {
+		t.Fatalf("Wrong line number %d", m.Line)
+	}
+
+	m = d.Stmts[3].(*ast.MessageStmt)
+	if m.Arrow != "->>" || m.Color != "#red" || !m.Activate || m.Deactivate {
+		t.Fatalf("Wrong message %+v", m)
+	}
+
+	m = d.Stmts[5].(*ast.MessageStmt)
+	if m.Arrow != "<--" || m.Activate || !m.Deactivate || m.Text != "200 OK" {
+		t.Fatalf("Wrong message %+v", m)
+	}
+
+	b := d.Stmts[7].(*ast.BlockStmt)
+	if b.Type != "alt" || b.Text != "cached" || len(b.Stmts) != 1 || len(b.Elses) != 1 || b.Elses[0].Text != "not cached" {
+		t.Fatalf("Wrong block %+v", b)
+	}
+
+	n := d.Stmts[8].(*ast.NoteStmt)
+	if n.Place != "over" || len(n.Targets) != 2 || n.Targets[1] != "web" || n.Color != "#yellow" || n.Text != "over note" {
+		t.Fatalf("Wrong note %+v", n)
+	}
+	n = d.Stmts[9].(*ast.NoteStmt)
+	if n.Place != "left" || len(n.Targets) != 0 || n.Text != "multi\nline" {
+		t.Fatalf("Wrong note %+v", n)
+	}
+
+	if v := d.Stmts[10].(*ast.DividerStmt); v.Text != "Divider" {
+		t.Fatalf("Wrong divider %+v", v)
+	}
+	if v := d.Stmts[11].(*ast.DelayStmt); v.Text != "5 minutes later" {
+		t.Fatalf("Wrong delay %+v", v)
+	}
+	r := d.Stmts[12].(*ast.RefStmt)
+	if len(r.Targets) != 2 || r.Targets[1] != "web" || r.Text != "Login" {
+		t.Fatalf("Wrong ref %+v", r)
+	}
+	r = d.Stmts[13].(*ast.RefStmt)
+	if len(r.Targets) != 1 || r.Targets[0] != "db" || r.Text != "Backup" {
+		t.Fatalf("Wrong ref %+v", r)
+	}
+	if v := d.Stmts[14].(*ast.AutonumberStmt); v.Start != 10 || v.Step != 5 {
+		t.Fatalf("Wrong autonumber %+v", v)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src, msg string
+	}{
+		{"@startuml\nA -> B\nfoo bar\n@enduml", "line 3: unknown statement 'foo bar'"},
+		{"@startuml\nloop\nA -> B\n@enduml", "line 2: 'loop' is not closed with 'end'"},
+		{"@startuml\nend\n@enduml", "line 2: 'end' is out of any block"},
+		{"@startuml\nA <-> B\n@enduml", "line 2: arrow must have exactly one head"},
+		{"@startuml\nnote left\ntext", "line 2: 'note' is not closed with 'end note'"},
+		{"@startuml\nref over A\ntext", "line 2: 'ref' is not closed with 'end ref'"},
+		{"@startuml\nref over : text", "line 2: 'ref over' needs participants"},
+	}
+
+	for _, tt := range tests {
+		_, err := ParsePlantUML([]byte(tt.src))
+		if err == nil || err.Error() != tt.msg {
+			t.Fatalf("Wrong error [expect: %s, actual: %v]", tt.msg, err)
+		}
+	}
+}
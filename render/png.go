@@ -0,0 +1,238 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// The size of the ends of the lines in pixels.
+const (
+	markerLength    = 10
+	markerHalfWidth = 5
+)
+
+// PNG draws the diagram as a PNG image with the default layout.
+//
+// The text is drawn in a fixed size bitmap font, which has only the ASCII characters.
+func PNG(w io.Writer, seq *model.SequenceDiagram) error {
+	return PNGWithTheme(w, seq, nil)
+}
+
+// PNGWithTheme draws the diagram as a PNG image with the default layout and the theme.
+// If the theme is nil, the one which the diagram selects is used. The font family of the theme is ignored.
+func PNGWithTheme(w io.Writer, seq *model.SequenceDiagram, theme *seq2xls.Theme) error {
+	shapes, err := Shapes(seq, seq2xls.DefaultLayout, theme)
+	if err != nil {
+		return err
+	}
+
+	width, height := bounds(shapes)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	for _, s := range shapes {
+		if s.IsLine {
+			drawPNGLine(img, s)
+		} else {
+			drawPNGRect(img, s)
+		}
+	}
+	return png.Encode(w, img)
+}
+
+func drawPNGLine(img *image.RGBA, s *xlsx.DrawnShape) {
+	if !s.Lined {
+		return
+	}
+	c := pngColor(s.LineColor, "000000")
+	strokeWideLine(img, s.X1, s.Y1, s.X2, s.Y2, c, dashPatterns[s.DashType], pngLineWidth(s))
+	// the head is the start of the line and the tail is the end in DrawingML
+	if s.HeadType != "" {
+		drawMarker(img, s.X2, s.Y2, s.X1, s.Y1, s.HeadType, c)
+	}
+	if s.TailType != "" {
+		drawMarker(img, s.X1, s.Y1, s.X2, s.Y2, s.TailType, c)
+	}
+}
+
+func drawPNGRect(img *image.RGBA, s *xlsx.DrawnShape) {
+	if s.Filled {
+		c := pngColor(s.FillColor, "FFFFFF")
+		draw.Draw(img, image.Rect(s.X1, s.Y1, s.X2, s.Y2), &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	if s.Lined {
+		c := pngColor(s.LineColor, "000000")
+		pattern := dashPatterns[s.DashType]
+		width := pngLineWidth(s)
+		strokeWideLine(img, s.X1, s.Y1, s.X2, s.Y1, c, pattern, width)
+		strokeWideLine(img, s.X2, s.Y1, s.X2, s.Y2, c, pattern, width)
+		strokeWideLine(img, s.X2, s.Y2, s.X1, s.Y2, c, pattern, width)
+		strokeWideLine(img, s.X1, s.Y2, s.X1, s.Y1, c, pattern, width)
+	}
+
+	if s.Text == "" {
+		return
+	}
+	face := basicfont.Face7x13
+	d := &font.Drawer{Dst: img, Src: &image.Uniform{pngColor(s.TextColor, "000000")}, Face: face}
+	lineHeight := face.Height + 2
+	lines := textLines(s)
+	x, top := textPlacement(s, lineHeight, len(lines))
+	for i, line := range lines {
+		left := x
+		switch s.HAlign {
+		case "ctr":
+			left -= d.MeasureString(line).Ceil() / 2
+		case "r":
+			left -= d.MeasureString(line).Ceil()
+		}
+		d.Dot = fixed.P(left, top+lineHeight*i+face.Ascent+1)
+		d.DrawString(line)
+	}
+}
+
+// pngLineWidth returns the width of the line of the shape in whole pixels.
+func pngLineWidth(s *xlsx.DrawnShape) int {
+	return maxInt(1, round(lineWidth(s)))
+}
+
+// strokeWideLine draws the line of the width in pixels as the parallel lines of a pixel width,
+// which are shifted vertically for the lines closer to horizontal and horizontally for the others.
+func strokeWideLine(img *image.RGBA, x1, y1, x2, y2 int, c color.Color, pattern []int, width int) {
+	horizontal := abs(x2-x1) >= abs(y2-y1)
+	for o := -(width - 1) / 2; o <= width/2; o++ {
+		if horizontal {
+			strokeLine(img, x1, y1+o, x2, y2+o, c, pattern)
+		} else {
+			strokeLine(img, x1+o, y1, x2+o, y2, c, pattern)
+		}
+	}
+}
+
+// strokeLine draws the line of a pixel width by Bresenham's algorithm.
+// The pattern is the lengths of the dashes and the gaps, or nil for the solid line.
+func strokeLine(img *image.RGBA, x1, y1, x2, y2 int, c color.Color, pattern []int) {
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+
+	period := 0
+	for _, v := range pattern {
+		period += v
+	}
+
+	e := dx + dy
+	for n := 0; ; n++ {
+		if period == 0 || dashOn(pattern, n%period) {
+			img.Set(x1, y1, c)
+		}
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+// dashOn returns whether the n-th pixel of the pattern is painted.
+func dashOn(pattern []int, n int) bool {
+	for i, v := range pattern {
+		if n < v {
+			return i%2 == 0
+		}
+		n -= v
+	}
+	return true
+}
+
+// drawMarker draws the end of the line at (x2, y2), which comes from (x1, y1).
+func drawMarker(img *image.RGBA, x1, y1, x2, y2 int, endType string, c color.Color) {
+	length := math.Hypot(float64(x2-x1), float64(y2-y1))
+	if length == 0 {
+		return
+	}
+	ux, uy := float64(x2-x1)/length, float64(y2-y1)/length
+	bx, by := float64(x2)-ux*markerLength, float64(y2)-uy*markerLength
+	lx, ly := round(bx-uy*markerHalfWidth), round(by+ux*markerHalfWidth)
+	rx, ry := round(bx+uy*markerHalfWidth), round(by-ux*markerHalfWidth)
+
+	if endType == "triangle" {
+		fillTriangle(img, [3]image.Point{{x2, y2}, {lx, ly}, {rx, ry}}, c)
+		return
+	}
+	strokeLine(img, lx, ly, x2, y2, c, nil)
+	strokeLine(img, rx, ry, x2, y2, c, nil)
+}
+
+func fillTriangle(img *image.RGBA, ps [3]image.Point, c color.Color) {
+	lo, hi := ps[0], ps[0]
+	for _, p := range ps[1:] {
+		lo.X, lo.Y = minInt(lo.X, p.X), minInt(lo.Y, p.Y)
+		hi.X, hi.Y = maxInt(hi.X, p.X), maxInt(hi.Y, p.Y)
+	}
+	side := func(a, b image.Point, x, y int) int {
+		return (b.X-a.X)*(y-a.Y) - (b.Y-a.Y)*(x-a.X)
+	}
+	for y := lo.Y; y <= hi.Y; y++ {
+		for x := lo.X; x <= hi.X; x++ {
+			s1 := side(ps[0], ps[1], x, y)
+			s2 := side(ps[1], ps[2], x, y)
+			s3 := side(ps[2], ps[0], x, y)
+			if (s1 >= 0 && s2 >= 0 && s3 >= 0) || (s1 <= 0 && s2 <= 0 && s3 <= 0) {
+				img.Set(x, y, c)
+			}
+		}
+	}
+}
+
+// pngColor parses the color in hex, or the default if it is unset or invalid.
+func pngColor(hex, def string) color.RGBA {
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		v, _ = strconv.ParseUint(def, 16, 32)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}
+}
+
+func minInt(v int, vs ...int) int {
+	for _, x := range vs {
+		if x < v {
+			v = x
+		}
+	}
+	return v
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func round(v float64) int {
+	return int(math.Floor(v + 0.5))
+}
@@ -0,0 +1,139 @@
+package render
+
+import (
+	"strings"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// The margins around the diagram in pixels.
+const margin = 20
+
+// The insets of the text in the rectangles in pixels, which are the defaults of Excel.
+const (
+	insetX = 10
+	insetY = 5
+)
+
+// defaultFontSize is the font size in points used for the text without the size.
+const defaultFontSize = 11
+
+// dashPatterns is the lengths of the dashes and the gaps in pixels by the dash type.
+var dashPatterns = map[string][]int{
+	"dash":       {4, 3},
+	"sysDash":    {3, 1},
+	"sysDot":     {1, 1},
+	"dot":        {1, 3},
+	"lgDash":     {8, 3},
+	"dashDot":    {4, 3, 1, 3},
+	"sysDashDot": {3, 1, 1, 1},
+}
+
+// canvas collects the shapes in the painting order.
+type canvas struct {
+	shapes []shape.Shape
+}
+
+func (c *canvas) AddShape(s shape.Shape) {
+	c.shapes = append(c.shapes, s)
+}
+
+func (c *canvas) UnshiftShape(s shape.Shape) {
+	c.shapes = append([]shape.Shape{s}, c.shapes...)
+}
+
+// Shapes draws the diagram with the layout and the theme in the same way as the xlsx output,
+// and returns the drawn shapes in the painting order. If the theme is nil, the one which
+// the diagram selects is used.
+func Shapes(seq *model.SequenceDiagram, layout seq2xls.Layout, theme *seq2xls.Theme) ([]*xlsx.DrawnShape, error) {
+	theme, err := seq2xls.SelectTheme(seq, theme)
+	if err != nil {
+		return nil, err
+	}
+	c := &canvas{}
+	seq2xls.DrawSequenceDiagramWithTheme(c, seq, layout, theme)
+
+	shapes := []*xlsx.DrawnShape{}
+	for _, s := range c.shapes {
+		anchor, err := xlsx.MarshalAnchor(s)
+		if err != nil {
+			return nil, err
+		}
+		ds, err := xlsx.ParseDrawnShape(anchor)
+		if err != nil {
+			return nil, err
+		}
+		shapes = append(shapes, ds)
+	}
+	return shapes, nil
+}
+
+// bounds returns the size of the image which contains all the shapes with the margins.
+func bounds(shapes []*xlsx.DrawnShape) (width, height int) {
+	for _, s := range shapes {
+		width = maxInt(width, s.X1, s.X2)
+		height = maxInt(height, s.Y1, s.Y2)
+	}
+	return width + margin, height + margin
+}
+
+// textLines splits the text of the shape into the lines, which are wrapped in the shape if it wraps the text.
+func textLines(s *xlsx.DrawnShape) []string {
+	text := strings.Replace(s.Text, "\r\n", "\n", -1)
+	if s.Wrap {
+		text = seq2xls.WrapText(text, s.X2-s.X1-insetX*2, fontSize(s))
+	}
+	return strings.Split(text, "\n")
+}
+
+// fontSize returns the font size of the shape in points.
+func fontSize(s *xlsx.DrawnShape) int {
+	if s.FontSize <= 0 {
+		return defaultFontSize
+	}
+	return s.FontSize
+}
+
+// lineWidth returns the width of the line of the shape in pixels.
+func lineWidth(s *xlsx.DrawnShape) float64 {
+	if s.LineWidth <= 0 {
+		return 1
+	}
+	return s.LineWidth * 4 / 3
+}
+
+// textPlacement returns the position of the lines of the text in the shape,
+// which are the x where the lines are aligned at and the y of the top of the first line.
+func textPlacement(s *xlsx.DrawnShape, lineHeight, nLines int) (x, top int) {
+	switch s.HAlign {
+	case "ctr":
+		x = (s.X1 + s.X2) / 2
+	case "r":
+		x = s.X2 - insetX
+	default:
+		x = s.X1 + insetX
+	}
+
+	height := lineHeight * nLines
+	switch s.VAlign {
+	case "ctr":
+		top = (s.Y1+s.Y2)/2 - height/2
+	case "b":
+		top = s.Y2 - insetY - height
+	default:
+		top = s.Y1 + insetY
+	}
+	return x, top
+}
+
+func maxInt(v int, vs ...int) int {
+	for _, x := range vs {
+		if x > v {
+			v = x
+		}
+	}
+	return v
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+func newTestDiagram() *model.SequenceDiagram {
+	client := &model.Lifeline{Name: "client", Index: 0, ColorHex: "FFFFFF"}
+	server := &model.Lifeline{Name: "server", Index: 1, ColorHex: "FFFFFF"}
+	get := &model.Message{Index: 0, From: client, To: server, Type: model.Synchronous, Text: "GET <index>"}
+	ok := &model.Message{Index: 1, From: server, To: client, Type: model.Reply, Text: "200"}
+	return &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{client, server},
+		Messages:  []*model.Message{get, ok},
+		Notes:     []*model.Note{{Index: 0, Assoc: get, Text: "cached", ColorHex: "FBFB77"}},
+	}
+}
+
+func TestShapes(t *testing.T) {
+	shapes, err := Shapes(newTestDiagram(), seq2xls.DefaultLayout, nil)
+	if err != nil {
+		t.Fatalf("Shapes error %v", err)
+	}
+
+	// the dashed lines of the lifelines are painted first
+	if s := shapes[0]; !s.IsVertical() || s.DashType != "dash" || s.Descr != seq2xls.ShapeDescrPrefix+"lifeline-1" {
+		t.Errorf("Unexpected first shape %+v", s)
+	}
+
+	var reply bool
+	for _, s := range shapes {
+		if s.IsHorizontal() && s.Descr == seq2xls.ShapeDescrPrefix+"message-1" {
+			reply = true
+			if s.X1 <= s.X2 || s.TailType != "arrow" || s.DashType != "dash" {
+				t.Errorf("Unexpected reply line %+v", s)
+			}
+		}
+	}
+	if !reply {
+		t.Errorf("Reply line is not drawn")
+	}
+}
+
+func TestTextLines(t *testing.T) {
+	s := &xlsx.DrawnShape{X1: 0, X2: 92, Text: "GET /users HTTP/1.1"}
+	if lines := textLines(s); len(lines) != 1 {
+		t.Errorf("The text is wrapped without the wrap of the shape %q", lines)
+	}
+	s.Wrap = true
+	if lines := textLines(s); strings.Join(lines, "|") != "GET|/users|HTTP/1.1" {
+		t.Errorf("The text is not wrapped in the shape %q", lines)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := SVG(buf, newTestDiagram())
+	if err != nil {
+		t.Fatalf("SVG error %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"`,
+		`stroke-dasharray="4 3" marker-end="url(#arrow)"/>`,
+		`marker-end="url(#triangle)"/>`,
+		`fill="#FBFB77" stroke="#000000"`,
+		`text-anchor="middle"`,
+		`>GET &lt;index&gt;</tspan>`,
+		`>client</tspan>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("SVG does not contain %q:\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "</svg>\n") {
+		t.Errorf("SVG is not closed:\n%s", out)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	buf := new(bytes.Buffer)
+	err := PNG(buf, newTestDiagram())
+	if err != nil {
+		t.Fatalf("PNG error %v", err)
+	}
+
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatalf("Output is not a PNG image: %v", err)
+	}
+	shapes, _ := Shapes(newTestDiagram(), seq2xls.DefaultLayout, nil)
+	width, height := bounds(shapes)
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != height {
+		t.Errorf("Unexpected size %v, want %dx%d", b, width, height)
+	}
+
+	// the center of the lifeline on the border of its rectangle is black
+	ll := shapes[0]
+	if r, g, b, _ := img.At(ll.X1, ll.Y1).RGBA(); r != 0 || g != 0 || b != 0 {
+		t.Errorf("Border of the lifeline is not drawn at (%d, %d)", ll.X1, ll.Y1)
+	}
+}
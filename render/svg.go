@@ -0,0 +1,157 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// The markers of the ends of the lines by the type.
+const svgMarkers = `<defs>
+<marker id="triangle" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="10" markerHeight="10" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z" fill="#000000"/></marker>
+<marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="10" markerHeight="10" markerUnits="userSpaceOnUse" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10" fill="none" stroke="#000000"/></marker>
+</defs>
+`
+
+// svgAnchors is the values of 'text-anchor' by the horizontal alignment.
+var svgAnchors = map[string]string{
+	"ctr": "middle",
+	"r":   "end",
+}
+
+// SVG draws the diagram as an SVG image with the default layout.
+func SVG(w io.Writer, seq *model.SequenceDiagram) error {
+	return SVGWithTheme(w, seq, nil)
+}
+
+// SVGWithTheme draws the diagram as an SVG image with the default layout and the theme.
+// If the theme is nil, the one which the diagram selects is used.
+func SVGWithTheme(w io.Writer, seq *model.SequenceDiagram, theme *seq2xls.Theme) error {
+	shapes, err := Shapes(seq, seq2xls.DefaultLayout, theme)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	width, height := bounds(shapes)
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", width, height, width, height)
+	buf.WriteString(svgMarkers)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="#FFFFFF"/>`+"\n", width, height)
+	for _, s := range shapes {
+		if s.IsLine {
+			writeSVGLine(buf, s)
+		} else {
+			writeSVGRect(buf, s)
+		}
+	}
+	buf.WriteString("</svg>\n")
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeSVGLine(buf *bytes.Buffer, s *xlsx.DrawnShape) {
+	if !s.Lined {
+		return
+	}
+	fmt.Fprintf(buf, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s"`, s.X1, s.Y1, s.X2, s.Y2, svgColor(s.LineColor, "000000"))
+	writeSVGStrokeWidth(buf, s)
+	writeSVGDash(buf, s.DashType)
+	// the head is the start of the line and the tail is the end in DrawingML
+	if s.HeadType != "" {
+		fmt.Fprintf(buf, ` marker-start="url(#%s)"`, svgMarker(s.HeadType))
+	}
+	if s.TailType != "" {
+		fmt.Fprintf(buf, ` marker-end="url(#%s)"`, svgMarker(s.TailType))
+	}
+	buf.WriteString("/>\n")
+}
+
+func writeSVGRect(buf *bytes.Buffer, s *xlsx.DrawnShape) {
+	if s.Filled || s.Lined {
+		fill, stroke := "none", "none"
+		if s.Filled {
+			fill = svgColor(s.FillColor, "FFFFFF")
+		}
+		if s.Lined {
+			stroke = svgColor(s.LineColor, "000000")
+		}
+		fmt.Fprintf(buf, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="%s"`, s.X1, s.Y1, s.X2-s.X1, s.Y2-s.Y1, fill, stroke)
+		if s.Lined {
+			writeSVGStrokeWidth(buf, s)
+		}
+		writeSVGDash(buf, s.DashType)
+		buf.WriteString("/>\n")
+	}
+
+	if strings.TrimSpace(s.Text) == "" {
+		return
+	}
+	size := fontSize(s) * 4 / 3
+	lineHeight := size * 6 / 5
+	lines := textLines(s)
+	x, top := textPlacement(s, lineHeight, len(lines))
+	family := "sans-serif"
+	if s.EastAsianFontFamily != "" && s.EastAsianFontFamily != s.FontFamily {
+		family = s.EastAsianFontFamily + ", " + family
+	}
+	if s.FontFamily != "" {
+		family = s.FontFamily + ", " + family
+	}
+	fmt.Fprintf(buf, `<text x="%d" font-family="`, x)
+	xml.EscapeText(buf, []byte(family))
+	fmt.Fprintf(buf, `" font-size="%d" fill="%s"`, size, svgColor(s.TextColor, "000000"))
+	if anchor, ok := svgAnchors[s.HAlign]; ok {
+		fmt.Fprintf(buf, ` text-anchor="%s"`, anchor)
+	}
+	buf.WriteString(">")
+	for i, line := range lines {
+		// the baseline is placed around 80 percent of the height of the line
+		fmt.Fprintf(buf, `<tspan x="%d" y="%d" xml:space="preserve">`, x, top+lineHeight*i+size*4/5+(lineHeight-size)/2)
+		xml.EscapeText(buf, []byte(line))
+		buf.WriteString("</tspan>")
+	}
+	buf.WriteString("</text>\n")
+}
+
+// writeSVGStrokeWidth writes the width of the line unless it is the default of a pixel.
+func writeSVGStrokeWidth(buf *bytes.Buffer, s *xlsx.DrawnShape) {
+	if w := lineWidth(s); w != 1 {
+		fmt.Fprintf(buf, ` stroke-width="%s"`, strconv.FormatFloat(w, 'f', -1, 64))
+	}
+}
+
+func writeSVGDash(buf *bytes.Buffer, dashType string) {
+	pattern, ok := dashPatterns[dashType]
+	if !ok {
+		return
+	}
+	values := []string{}
+	for _, v := range pattern {
+		values = append(values, strconv.Itoa(v))
+	}
+	fmt.Fprintf(buf, ` stroke-dasharray="%s"`, strings.Join(values, " "))
+}
+
+// svgMarker returns the ID of the marker for the type of the end of the line.
+func svgMarker(endType string) string {
+	if endType == "triangle" {
+		return "triangle"
+	}
+	return "arrow"
+}
+
+// svgColor returns the color in the SVG notation, or the default if it is unset.
+func svgColor(hex, def string) string {
+	if hex == "" {
+		hex = def
+	}
+	return "#" + strings.ToUpper(hex)
+}
@@ -3,6 +3,7 @@ package ast
 import (
 	"strings"
 
+	"github.com/rsp9u/seq2xls/seqdiag/errors"
 	"github.com/rsp9u/seq2xls/seqdiag/token"
 )
 
@@ -16,17 +17,24 @@ type Stmt interface{}
 /****************
  * Diagram
  ****************/
+// Diagram is the root of the AST. Errors is the syntax errors which the parser has recovered from
+// by skipping the statements, in order of appearance.
 type Diagram struct {
-	ID    *ID
-	Stmts *DiagramInlineStmtList
+	ID             *ID
+	Lbrace, Rbrace token.Pos
+	Stmts          *DiagramInlineStmtList
+	Errors         []*errors.Error
 }
 
 type DiagramInlineStmtList struct {
 	Items []Stmt
 }
 
-func NewDiagram(id, stmts Attr) (*Diagram, error) {
-	return &Diagram{id.(*ID), stmts.(*DiagramInlineStmtList)}, nil
+func NewDiagram(id, lbrace, stmts, rbrace Attr) (*Diagram, error) {
+	d := &Diagram{ID: id.(*ID), Lbrace: TokenToPos(lbrace), Stmts: stmts.(*DiagramInlineStmtList)}
+	d.Stmts.Items, d.Rbrace = closeBlock(d.Stmts.Items, rbrace)
+	d.Stmts.Items, d.Errors = takeErrors(d.Stmts.Items, nil)
+	return d, nil
 }
 
 func NewDiagramInlineStmtList(acc, stmt Attr) (*DiagramInlineStmtList, error) {
@@ -40,21 +48,103 @@ func NewDiagramInlineStmtList(acc, stmt Attr) (*DiagramInlineStmtList, error) {
 	return acc.(*DiagramInlineStmtList), nil
 }
 
+/****************
+ * Error Statement
+ ****************/
+
+// ErrorStmt is a statement with a syntax error, which the parser skips up to the next ';'
+// or the end of the block. NewDiagram takes them out of the statements into the errors of the diagram.
+type ErrorStmt struct {
+	Err *errors.Error
+}
+
+func NewErrorStmt(err Attr) (*ErrorStmt, error) {
+	return &ErrorStmt{err.(*errors.Error)}, nil
+}
+
+// ErrorRbrace is the end of a block whose last statements are skipped for a syntax error without ';' after it.
+type ErrorRbrace struct {
+	Err    *errors.Error
+	Rbrace token.Pos
+}
+
+func NewErrorRbrace(err, rbrace Attr) (*ErrorRbrace, error) {
+	return &ErrorRbrace{err.(*errors.Error), TokenToPos(rbrace)}, nil
+}
+
+// closeBlock returns the position of the end of the block, appending the error statement to stmts
+// if the block ends with a syntax error.
+func closeBlock(stmts []Stmt, rbrace Attr) ([]Stmt, token.Pos) {
+	if v, ok := rbrace.(*ErrorRbrace); ok {
+		return append(stmts, &ErrorStmt{v.Err}), v.Rbrace
+	}
+	return stmts, TokenToPos(rbrace)
+}
+
+// takeErrors removes the error statements from the statements and the blocks in them,
+// and appends their errors to errs in order of appearance.
+func takeErrors(stmts []Stmt, errs []*errors.Error) ([]Stmt, []*errors.Error) {
+	rest := stmts[:0]
+	for _, stmt := range stmts {
+		switch v := stmt.(type) {
+		case *ErrorStmt:
+			errs = append(errs, v.Err)
+			continue
+		case *FragmentStmt:
+			v.Stmts.Items, errs = takeErrors(v.Stmts.Items, errs)
+		case *GroupStmt:
+			v.Stmts.Items, errs = takeErrors(v.Stmts.Items, errs)
+		case *RefStmt:
+			v.Stmts.Items, errs = takeErrors(v.Stmts.Items, errs)
+		case *EdgeStmt:
+			if v.EdgeBlock != nil {
+				v.EdgeBlock.Items, errs = takeErrors(v.EdgeBlock.Items, errs)
+			}
+		}
+		rest = append(rest, stmt)
+	}
+	return rest, errs
+}
+
+/****************
+ * Extension Statement
+ ****************/
+type ExtensionStmt struct {
+	Type    string
+	Pos     token.Pos
+	ID      *ID
+	Options *OptionList
+}
+
+func NewExtensionStmt(t, id, opt Attr) (*ExtensionStmt, error) {
+	return &ExtensionStmt{TokenToString(t), TokenToPos(t), id.(*ID), opt.(*OptionList)}, nil
+}
+
 /****************
  * Fragment Statement
  ****************/
 type FragmentStmt struct {
-	Type  string
-	ID    *ID
-	Stmts *FragmentInlineStmtList
+	Type           string
+	Pos            token.Pos
+	ID             *ID
+	Lbrace, Rbrace token.Pos
+	Stmts          *FragmentInlineStmtList
 }
 
 type FragmentInlineStmtList struct {
 	Items []Stmt
 }
 
-func NewFragmentStmt(t, id, stmts Attr) (*FragmentStmt, error) {
-	return &FragmentStmt{t.(string), id.(*ID), stmts.(*FragmentInlineStmtList)}, nil
+func NewFragmentStmt(t, id, lbrace, stmts, rbrace Attr) (*FragmentStmt, error) {
+	frag := &FragmentStmt{
+		Type:   TokenToString(t),
+		Pos:    TokenToPos(t),
+		ID:     id.(*ID),
+		Lbrace: TokenToPos(lbrace),
+		Stmts:  stmts.(*FragmentInlineStmtList),
+	}
+	frag.Stmts.Items, frag.Rbrace = closeBlock(frag.Stmts.Items, rbrace)
+	return frag, nil
 }
 
 func NewFragmentInlineStmtList(acc, stmt Attr) (*FragmentInlineStmtList, error) {
@@ -72,16 +162,20 @@ func NewFragmentInlineStmtList(acc, stmt Attr) (*FragmentInlineStmtList, error)
  * Group Statement
  ****************/
 type GroupStmt struct {
-	ID    *ID
-	Stmts *GroupInineStmtList
+	Pos            token.Pos
+	ID             *ID
+	Lbrace, Rbrace token.Pos
+	Stmts          *GroupInineStmtList
 }
 
 type GroupInineStmtList struct {
 	Items []Stmt
 }
 
-func NewGroupStmt(id, stmts Attr) (*GroupStmt, error) {
-	return &GroupStmt{id.(*ID), stmts.(*GroupInineStmtList)}, nil
+func NewGroupStmt(kw, id, lbrace, stmts, rbrace Attr) (*GroupStmt, error) {
+	grp := &GroupStmt{Pos: TokenToPos(kw), ID: id.(*ID), Lbrace: TokenToPos(lbrace), Stmts: stmts.(*GroupInineStmtList)}
+	grp.Stmts.Items, grp.Rbrace = closeBlock(grp.Stmts.Items, rbrace)
+	return grp, nil
 }
 
 func NewGroupInineStmtList(acc, stmt Attr) (*GroupInineStmtList, error) {
@@ -98,10 +192,12 @@ func NewGroupInineStmtList(acc, stmt Attr) (*GroupInineStmtList, error) {
 /****************
  * Edge Statement
  ****************/
+// EdgeStmt is a statement of the edges. Pos is the position of the first node.
 type EdgeStmt struct {
 	EdgeSegments *EdgeSegmentList
 	Options      *OptionList
 	EdgeBlock    *EdgeBlockInlineStmtList
+	Pos          token.Pos
 }
 
 type EdgeSegmentList struct {
@@ -109,21 +205,28 @@ type EdgeSegmentList struct {
 	LastNode *ID
 }
 
+// EdgeSegment is an edge between two nodes. Pos is the position of the edge.
 type EdgeSegment struct {
 	LeftNode  *ID
 	Edge      string
 	RightNode *ID
+	Pos       token.Pos
 }
 
+// EdgeBlockInlineStmtList is the statements in the block of the edge.
+// Lbrace and Rbrace are zero if the edge has no block.
 type EdgeBlockInlineStmtList struct {
-	Items []Stmt
+	Items          []Stmt
+	Lbrace, Rbrace token.Pos
 }
 
 func NewEdgeStmt(sgmts, opt, blk Attr) (*EdgeStmt, error) {
+	list := sgmts.(*EdgeSegmentList)
 	return &EdgeStmt{
-		sgmts.(*EdgeSegmentList),
+		list,
 		opt.(*OptionList),
 		blk.(*EdgeBlockInlineStmtList),
+		list.Items[0].LeftNode.Pos,
 	}, nil
 }
 
@@ -133,6 +236,7 @@ func NewEdgeSegmentList(l, e, r Attr) (*EdgeSegmentList, error) {
 		l.(*ID),
 		string(e.(*token.Token).Lit),
 		r.(*ID),
+		TokenToPos(e),
 	}
 	sgmts.Items = append(sgmts.Items, sgmt)
 	sgmts.LastNode = r.(*ID)
@@ -145,12 +249,23 @@ func AppendEdgeSegment(acc, e, r Attr) (*EdgeSegmentList, error) {
 		sgmts.LastNode,
 		string(e.(*token.Token).Lit),
 		r.(*ID),
+		TokenToPos(e),
 	}
 	sgmts.Items = append(sgmts.Items, sgmt)
 	sgmts.LastNode = r.(*ID)
 	return sgmts, nil
 }
 
+func NewEdgeBlock(lbrace, stmts, rbrace Attr) (*EdgeBlockInlineStmtList, error) {
+	blk := &EdgeBlockInlineStmtList{}
+	if stmts != nil {
+		blk = stmts.(*EdgeBlockInlineStmtList)
+	}
+	blk.Lbrace = TokenToPos(lbrace)
+	blk.Items, blk.Rbrace = closeBlock(blk.Items, rbrace)
+	return blk, nil
+}
+
 func NewEdgeBlockInlineStmtList(acc, stmt Attr) (*EdgeBlockInlineStmtList, error) {
 	if acc == nil {
 		acc = &EdgeBlockInlineStmtList{}
@@ -167,34 +282,54 @@ func NewEdgeBlockInlineStmtList(acc, stmt Attr) (*EdgeBlockInlineStmtList, error
  ****************/
 type SeparatorStmt struct {
 	Type, Value string
+	Pos         token.Pos
 }
 
 func NewSeparatorStmt(attr Attr) (*SeparatorStmt, error) {
 	s := TokenToString(attr)
-	return &SeparatorStmt{s[0:3], strings.TrimSpace(s[3 : len(s)-3])}, nil
+	return &SeparatorStmt{s[0:3], strings.TrimSpace(s[3 : len(s)-3]), TokenToPos(attr)}, nil
+}
+
+/****************
+ * Constraint Statement
+ ****************/
+// ConstraintStmt is a duration constraint between the messages which Begin and End name by their 'id' options.
+// Pos is the position of the keyword.
+type ConstraintStmt struct {
+	Begin, End *ID
+	Options    *OptionList
+	Pos        token.Pos
+}
+
+func NewConstraintStmt(kw, begin, end, opt Attr) (*ConstraintStmt, error) {
+	return &ConstraintStmt{begin.(*ID), end.(*ID), opt.(*OptionList), TokenToPos(kw)}, nil
 }
 
 /****************
  * Node Statement
  ****************/
+// NodeStmt is a declaration of the node. Pos is the position of the node.
 type NodeStmt struct {
 	ID     *ID
 	Option *OptionList
+	Pos    token.Pos
 }
 
 func NewNodeStmt(id, opt Attr) (*NodeStmt, error) {
-	return &NodeStmt{id.(*ID), opt.(*OptionList)}, nil
+	return &NodeStmt{id.(*ID), opt.(*OptionList), id.(*ID).Pos}, nil
 }
 
 /****************
  * Attribute Statement
  ****************/
+// AttributeStmt is an attribute of the diagram or the block. Pos is the position of the name.
 type AttributeStmt struct {
 	Type, Value *ID
+	Pos         token.Pos
 }
 
 func NewAttributeStmt(t, v Attr) (*AttributeStmt, error) {
-	return &AttributeStmt{t.(*ID), v.(*ID)}, nil
+	return &AttributeStmt{t.(*ID), v.(*ID), t.(*ID).Pos}, nil
 }
 
 /****************
@@ -204,8 +339,10 @@ type OptionList struct {
 	Items []*Option
 }
 
+// Option is an option of the node or the edge. Pos is the position of the name.
 type Option struct {
 	Type, Value *ID
+	Pos         token.Pos
 }
 
 func NewOptionList(acc, opt Attr) (*OptionList, error) {
@@ -220,22 +357,24 @@ func NewOptionList(acc, opt Attr) (*OptionList, error) {
 }
 
 func NewOption(t, v Attr) (*Option, error) {
-	return &Option{t.(*ID), v.(*ID)}, nil
+	return &Option{t.(*ID), v.(*ID), t.(*ID).Pos}, nil
 }
 
 /****************
  * ID
  ****************/
+// ID is an identifier or a literal. Pos is zero if it is omitted in the source text.
 type ID struct {
 	Value string
+	Pos   token.Pos
 }
 
 func NewID(id, t Attr) (*ID, error) {
 	switch t.(string) {
 	case "string":
-		return &ID{Unescape(TrimQuote(id))}, nil
+		return &ID{Unescape(TrimQuote(id)), TokenToPos(id)}, nil
 	default:
-		return &ID{TokenToString(id)}, nil
+		return &ID{TokenToString(id), TokenToPos(id)}, nil
 	}
 }
 
@@ -251,6 +390,10 @@ func TokenToString(attr Attr) string {
 	return string(attr.(*token.Token).Lit)
 }
 
+func TokenToPos(attr Attr) token.Pos {
+	return attr.(*token.Token).Pos
+}
+
 func TrimQuote(attr Attr) string {
 	s := string(attr.(*token.Token).Lit)
 	return s[1 : len(s)-1]
@@ -263,6 +406,43 @@ func Unescape(s string) string {
 	return s
 }
 
+/****************
+ * Comment
+ ****************/
+
+// Comment is a comment in the source text, which the parser skips.
+// Text includes the comment marks such as '#' and '/*'.
+type Comment struct {
+	Text string
+	Pos  token.Pos
+}
+
+/****************
+ * Ref Statement
+ ****************/
+
+// RefStmt is an interaction reference to the diagram which ID names.
+// The node statements in the block are the lifelines which it covers, all of them if there is none,
+// and the attribute statements are its attributes. Lbrace and Rbrace are zero without the block.
+type RefStmt struct {
+	Pos            token.Pos
+	ID             *ID
+	Lbrace, Rbrace token.Pos
+	Stmts          *GroupInineStmtList
+}
+
+func NewRefStmt(kw, id, lbrace, stmts, rbrace Attr) (*RefStmt, error) {
+	ref := &RefStmt{Pos: TokenToPos(kw), ID: id.(*ID), Stmts: &GroupInineStmtList{}}
+	if stmts != nil {
+		ref.Stmts = stmts.(*GroupInineStmtList)
+	}
+	if lbrace != nil {
+		ref.Lbrace = TokenToPos(lbrace)
+		ref.Stmts.Items, ref.Rbrace = closeBlock(ref.Stmts.Items, rbrace)
+	}
+	return ref, nil
+}
+
 /****************
  * Interfaces
  ****************/
@@ -277,3 +457,7 @@ func (s *FragmentStmt) GetItems() []Stmt {
 func (s *GroupStmt) GetItems() []Stmt {
 	return s.Stmts.Items
 }
+
+func (s *RefStmt) GetItems() []Stmt {
+	return s.Stmts.Items
+}
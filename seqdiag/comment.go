@@ -0,0 +1,70 @@
+package seqdiag
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+// ScanComments finds the comments in the given 'seqdiag' text, which the parser skips.
+//
+// The string literals and the separators are skipped in the same way as the lexer,
+// so that a comment mark inside them is not taken as a comment.
+func ScanComments(b []byte) []*ast.Comment {
+	comments := []*ast.Comment{}
+	pos := token.Pos{Offset: 0, Line: 1, Column: 1}
+
+	// advance moves the position to the given offset.
+	advance := func(offset int) {
+		for pos.Offset < offset && pos.Offset < len(b) {
+			r, size := utf8.DecodeRune(b[pos.Offset:])
+			pos.Offset += size
+			if r == '\n' {
+				pos.Line++
+				pos.Column = 1
+			} else {
+				pos.Column++
+			}
+		}
+	}
+	// skipTo returns the offset just after the closing mark, or the end of the text.
+	skipTo := func(from int, mark string) int {
+		if i := bytes.Index(b[from:], []byte(mark)); i >= 0 {
+			return from + i + len(mark)
+		}
+		return len(b)
+	}
+
+	for pos.Offset < len(b) {
+		rest := b[pos.Offset:]
+		switch {
+		case rest[0] == '"' || rest[0] == '\'':
+			advance(skipTo(pos.Offset+1, string(rest[0])))
+
+		case bytes.HasPrefix(rest, []byte("===")):
+			advance(skipTo(pos.Offset+3, "==="))
+
+		case bytes.HasPrefix(rest, []byte("...")):
+			advance(skipTo(pos.Offset+3, "..."))
+
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := skipTo(pos.Offset+2, "*/")
+			comments = append(comments, &ast.Comment{Text: string(b[pos.Offset:end]), Pos: pos})
+			advance(end)
+
+		case rest[0] == '#' || bytes.HasPrefix(rest, []byte("//")):
+			end := pos.Offset + len(rest)
+			if i := bytes.IndexAny(rest, "\r\n"); i >= 0 {
+				end = pos.Offset + i
+			}
+			comments = append(comments, &ast.Comment{Text: string(b[pos.Offset:end]), Pos: pos})
+			advance(end)
+
+		default:
+			advance(pos.Offset + 1)
+		}
+	}
+	return comments
+}
@@ -0,0 +1,39 @@
+package seqdiag
+
+import (
+	"testing"
+)
+
+func TestScanComments(t *testing.T) {
+	src := `# header
+seqdiag {
+  a -> b [label = "# not a comment"]; // trailing
+  === // not a comment ===
+  /* multi
+     line */ b;
+}`
+
+	expected := []struct {
+		text         string
+		line, column int
+	}{
+		{"# header", 1, 1},
+		{"// trailing", 3, 39},
+		{"/* multi\n     line */", 5, 3},
+	}
+
+	comments := ScanComments([]byte(src))
+	if len(comments) != len(expected) {
+		t.Fatalf("Mismatches the number of comments: expect %d, actual %d", len(expected), len(comments))
+	}
+	for i, e := range expected {
+		c := comments[i]
+		if c.Text != e.text || c.Pos.Line != e.line || c.Pos.Column != e.column {
+			t.Errorf("Mismatches comment[%d]: expect %q at %d:%d, actual %q at %d:%d",
+				i, e.text, e.line, e.column, c.Text, c.Pos.Line, c.Pos.Column)
+		}
+		if string([]byte(src)[c.Pos.Offset:c.Pos.Offset+len(c.Text)]) != c.Text {
+			t.Errorf("Mismatches offset of comment[%d]: %d", i, c.Pos.Offset)
+		}
+	}
+}
@@ -9,6 +9,21 @@ import (
 func AstToModel(d *ast.Diagram) (*model.SequenceDiagram, error) {
 	seq := &model.SequenceDiagram{}
 
+	for _, stmt := range d.Stmts.Items {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok {
+			continue
+		}
+		switch attr.Type.Value {
+		case "theme":
+			seq.Theme = attr.Value.Value
+		case "default_fontfamily":
+			seq.FontFamily = attr.Value.Value
+		case "default_lang":
+			seq.Lang = attr.Value.Value
+		}
+	}
+
 	lls, err := ExtractLifelines(d)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,27 @@
+package convertor
+
+import (
+	"fmt"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+// Error is an error of the conversion at the position in the source text.
+type Error struct {
+	Pos token.Pos
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+func errorf(pos token.Pos, format string, args ...interface{}) error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// modelPos converts the position of the token into the one of the model.
+func modelPos(pos token.Pos) model.Pos {
+	return model.Pos{Line: pos.Line, Column: pos.Column}
+}
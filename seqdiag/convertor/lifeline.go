@@ -35,14 +35,14 @@ func extractLifelinesFromStmts(stmts []ast.Stmt, lls []*model.Lifeline, index in
 		case *ast.EdgeStmt:
 			for _, sgmt := range v.EdgeSegments.Items {
 				if !containsLifeline(lls, sgmt.LeftNode.Value) {
-					ll := &model.Lifeline{Name: sgmt.LeftNode.Value, Index: index, ColorHex: "FFFFFF"}
+					ll := &model.Lifeline{Name: sgmt.LeftNode.Value, Index: index, ColorHex: "FFFFFF", Pos: modelPos(sgmt.LeftNode.Pos)}
 					lls = append(lls, ll)
 					index++
 					indexCnt++
 				}
 
 				if !containsLifeline(lls, sgmt.RightNode.Value) {
-					ll := &model.Lifeline{Name: sgmt.RightNode.Value, Index: index, ColorHex: "FFFFFF"}
+					ll := &model.Lifeline{Name: sgmt.RightNode.Value, Index: index, ColorHex: "FFFFFF", Pos: modelPos(sgmt.RightNode.Pos)}
 					lls = append(lls, ll)
 					index++
 					indexCnt++
@@ -60,7 +60,7 @@ func extractLifelinesFromStmts(stmts []ast.Stmt, lls []*model.Lifeline, index in
 
 		case *ast.NodeStmt:
 			if !containsLifeline(lls, v.ID.Value) {
-				ll := &model.Lifeline{Name: v.ID.Value, Index: index, ColorHex: "FFFFFF"}
+				ll := &model.Lifeline{Name: v.ID.Value, Index: index, ColorHex: "FFFFFF", Pos: modelPos(v.ID.Pos)}
 				lls = append(lls, ll)
 				index++
 				indexCnt++
@@ -1,7 +1,6 @@
 package convertor
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/golang-collections/collections/stack"
@@ -15,38 +14,64 @@ func ScanTimeline(d *ast.Diagram, seq *model.SequenceDiagram) error {
 	seq.Fragments = []*model.Fragment{}
 	seq.Notes = []*model.Note{}
 
-	err := scanTimelineInStmts(d.Stmts.Items, seq)
+	sc := &timelineScan{labelPos: model.LabelLeft, named: map[string]*model.Message{}}
+	for _, stmt := range d.Stmts.Items {
+		attr, ok := stmt.(*ast.AttributeStmt)
+		if !ok || attr.Type.Value != "default_label_position" {
+			continue
+		}
+		var err error
+		sc.labelPos, err = parseLabelPosition(attr.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := scanTimelineInStmts(d.Stmts.Items, seq, sc)
 	if err != nil {
 		return err
 	}
-	return nil
+	return resolveConstraints(sc, seq)
+}
+
+// timelineScan is the state shared while scanning the statements of the timeline.
+type timelineScan struct {
+	// labelPos is the position of the labels of the messages without the position.
+	labelPos model.LabelPosition
+	// named is the messages by the names given by their 'id' options.
+	named map[string]*model.Message
+	// constraints is the constraint statements, which are resolved after all messages are named.
+	constraints []*ast.ConstraintStmt
 }
 
-func scanTimelineInStmts(stmts []ast.Stmt, seq *model.SequenceDiagram) error {
+// scanTimelineInStmts puts the time series elements in the statements into the diagram model.
+func scanTimelineInStmts(stmts []ast.Stmt, seq *model.SequenceDiagram, sc *timelineScan) error {
 	for _, stmt := range stmts {
 		switch v := stmt.(type) {
 		case *ast.FragmentStmt:
 			frag := &model.Fragment{
 				Index: len(seq.Fragments),
 				Type:  getFragmentType(v),
+				Text:  v.ID.Value,
+				Pos:   modelPos(v.Pos),
 			}
 			seq.Fragments = append(seq.Fragments, frag)
 
 			beginIndex := len(seq.Messages)
-			err := scanTimelineInStmts(v.GetItems(), seq)
+			err := scanTimelineInStmts(v.GetItems(), seq, sc)
 			endIndex := len(seq.Messages) - 1
 			if err != nil {
 				return err
 			}
 			if endIndex < beginIndex {
-				return fmt.Errorf("empty fragment is not allowed")
+				return errorf(v.Pos, "empty fragment is not allowed")
 			}
 
 			frag.Begin = seq.Messages[beginIndex]
 			frag.End = seq.Messages[endIndex]
 
 		case *ast.GroupStmt:
-			err := scanTimelineInStmts(v.GetItems(), seq)
+			err := scanTimelineInStmts(v.GetItems(), seq, sc)
 			if err != nil {
 				return err
 			}
@@ -54,49 +79,69 @@ func scanTimelineInStmts(stmts []ast.Stmt, seq *model.SequenceDiagram) error {
 		case *ast.EdgeStmt:
 			tripReplySgmts := stack.New()
 			text := getMessageLabel(v)
+			msgLabelPos, err := getMessageLabelPosition(v, sc.labelPos)
+			if err != nil {
+				return err
+			}
+			name := getMessageName(v)
+			if name != nil {
+				if _, ok := sc.named[name.Value]; ok {
+					return errorf(name.Pos, "duplicate message id '%s'", name.Value)
+				}
+			}
 			lnote := getMessageLeftNote(v)
 			rnote := getMessageRightNote(v)
 
 			for _, sgmt := range v.EdgeSegments.Items {
 				edgeType := getMessageType(sgmt)
 				msg := &model.Message{
-					Index:    len(seq.Messages),
-					From:     getLifeline(seq.Lifelines, getFromNode(sgmt).Value),
-					To:       getLifeline(seq.Lifelines, getToNode(sgmt).Value),
-					Type:     edgeType,
-					ColorHex: "000000",
-					Text:     text,
+					Index:         len(seq.Messages),
+					From:          getLifeline(seq.Lifelines, getFromNode(sgmt).Value),
+					To:            getLifeline(seq.Lifelines, getToNode(sgmt).Value),
+					Type:          edgeType,
+					ColorHex:      "000000",
+					Text:          text,
+					LabelPosition: msgLabelPos,
+					Pos:           modelPos(sgmt.LeftNode.Pos),
 				}
 				seq.Messages = append(seq.Messages, msg)
+				if name != nil && sgmt == v.EdgeSegments.Items[0] {
+					sc.named[name.Value] = msg
+				}
 
 				if edgeType != model.SelfReference && isTripMessage(sgmt) {
 					tripReplySgmts.Push(&ast.EdgeSegment{
 						LeftNode:  sgmt.LeftNode,
 						RightNode: sgmt.RightNode,
 						Edge:      "<-",
+						Pos:       sgmt.Pos,
 					})
 				}
 
 				if lnote != nil {
 					seq.Notes = append(seq.Notes, &model.Note{
+						Index:    len(seq.Notes),
 						Assoc:    msg,
 						OnLeft:   lnote.OnLeft,
 						Text:     lnote.Text,
 						ColorHex: lnote.ColorHex,
+						Pos:      lnote.Pos,
 					})
 				}
 				if rnote != nil {
 					seq.Notes = append(seq.Notes, &model.Note{
+						Index:    len(seq.Notes),
 						Assoc:    msg,
 						OnLeft:   rnote.OnLeft,
 						Text:     rnote.Text,
 						ColorHex: rnote.ColorHex,
+						Pos:      rnote.Pos,
 					})
 				}
 			}
 
 			if v.EdgeBlock != nil {
-				err := scanTimelineInStmts(v.EdgeBlock.Items, seq)
+				err := scanTimelineInStmts(v.EdgeBlock.Items, seq, sc)
 				if err != nil {
 					return err
 				}
@@ -112,6 +157,7 @@ func scanTimelineInStmts(stmts []ast.Stmt, seq *model.SequenceDiagram) error {
 						To:       getLifeline(seq.Lifelines, getToNode(sgmt).Value),
 						Type:     getMessageType(sgmt),
 						ColorHex: "000000",
+						Pos:      modelPos(sgmt.LeftNode.Pos),
 					}
 					seq.Messages = append(seq.Messages, msg)
 				}
@@ -121,17 +167,102 @@ func scanTimelineInStmts(stmts []ast.Stmt, seq *model.SequenceDiagram) error {
 			if len(seq.Messages) > 0 {
 				beforeMsg = seq.Messages[len(seq.Messages)-1]
 			}
+			sepType := model.Divider
+			if v.Type == "..." {
+				sepType = model.Delay
+			}
 			sep := &model.Separator{
+				Index:  len(seq.Separators),
+				Type:   sepType,
 				Text:   v.Value,
 				Before: beforeMsg,
+				Pos:    modelPos(v.Pos),
 			}
 			seq.Separators = append(seq.Separators, sep)
+		case *ast.ConstraintStmt:
+			sc.constraints = append(sc.constraints, v)
+		case *ast.RefStmt:
+			var beforeMsg *model.Message
+			if len(seq.Messages) > 0 {
+				beforeMsg = seq.Messages[len(seq.Messages)-1]
+			}
+			seq.Fragments = append(seq.Fragments, &model.Fragment{
+				Index:     len(seq.Fragments),
+				Type:      model.Ref,
+				Text:      v.ID.Value,
+				Pos:       modelPos(v.Pos),
+				Lifelines: getRefLifelines(v, seq.Lifelines),
+				Before:    beforeMsg,
+				Link:      getRefLink(v),
+			})
 		}
 	}
 
 	return nil
 }
 
+// resolveConstraints puts the constraints between the named messages into the diagram model
+// in order of appearance. The messages of each constraint are ordered from top to bottom.
+func resolveConstraints(sc *timelineScan, seq *model.SequenceDiagram) error {
+	for _, stmt := range sc.constraints {
+		begin, ok := sc.named[stmt.Begin.Value]
+		if !ok {
+			return errorf(stmt.Begin.Pos, "unknown message id '%s'", stmt.Begin.Value)
+		}
+		end, ok := sc.named[stmt.End.Value]
+		if !ok {
+			return errorf(stmt.End.Pos, "unknown message id '%s'", stmt.End.Value)
+		}
+		if begin.Index > end.Index {
+			begin, end = end, begin
+		}
+
+		text := ""
+		for _, opt := range stmt.Options.Items {
+			if opt.Type.String() == "label" {
+				text = opt.Value.String()
+			}
+		}
+		seq.Constraints = append(seq.Constraints, &model.Constraint{
+			Index: len(seq.Constraints),
+			Begin: begin,
+			End:   end,
+			Text:  text,
+			Pos:   modelPos(stmt.Pos),
+		})
+	}
+	return nil
+}
+
+// getRefLifelines returns the lifelines which the node statements in the block of the reference name,
+// or empty if there is none.
+func getRefLifelines(stmt *ast.RefStmt, lls []*model.Lifeline) []*model.Lifeline {
+	ret := []*model.Lifeline{}
+	seen := map[*model.Lifeline]bool{}
+	for _, item := range stmt.GetItems() {
+		node, ok := item.(*ast.NodeStmt)
+		if !ok {
+			continue
+		}
+		ll := getLifeline(lls, node.ID.Value)
+		if ll != nil && !seen[ll] {
+			ret = append(ret, ll)
+			seen[ll] = true
+		}
+	}
+	return ret
+}
+
+// getRefLink returns the 'link' attribute of the reference, or empty if there is none.
+func getRefLink(stmt *ast.RefStmt) string {
+	for _, item := range stmt.GetItems() {
+		if attr, ok := item.(*ast.AttributeStmt); ok && attr.Type.Value == "link" {
+			return attr.Value.Value
+		}
+	}
+	return ""
+}
+
 func getLifeline(lls []*model.Lifeline, name string) *model.Lifeline {
 	for _, ll := range lls {
 		if ll.Name == name {
@@ -150,6 +281,35 @@ func getMessageLabel(stmt *ast.EdgeStmt) string {
 	return ""
 }
 
+// getMessageName returns the name given by the 'id' option of the edge, or nil if there is none.
+// The name refers to the first message of the edge.
+func getMessageName(stmt *ast.EdgeStmt) *ast.ID {
+	for _, opt := range stmt.Options.Items {
+		if opt.Type.String() == "id" && opt.Value.Value != "" {
+			return opt.Value
+		}
+	}
+	return nil
+}
+
+// getMessageLabelPosition returns the label position given by the option of the edge, or def if there is none.
+func getMessageLabelPosition(stmt *ast.EdgeStmt, def model.LabelPosition) (model.LabelPosition, error) {
+	for _, opt := range stmt.Options.Items {
+		if opt.Type.String() == "label_position" {
+			return parseLabelPosition(opt.Value)
+		}
+	}
+	return def, nil
+}
+
+func parseLabelPosition(id *ast.ID) (model.LabelPosition, error) {
+	p, ok := model.ParseLabelPosition(id.Value)
+	if !ok {
+		return p, errorf(id.Pos, "unknown label position '%s'", id.Value)
+	}
+	return p, nil
+}
+
 func getMessageLeftNote(stmt *ast.EdgeStmt) *model.Note {
 	for _, opt := range stmt.Options.Items {
 		if opt.Type.String() == "leftnote" {
@@ -158,6 +318,7 @@ func getMessageLeftNote(stmt *ast.EdgeStmt) *model.Note {
 				OnLeft:   true,
 				Text:     opt.Value.String(),
 				ColorHex: "ffb6c1",
+				Pos:      modelPos(opt.Pos),
 			}
 		}
 	}
@@ -172,6 +333,7 @@ func getMessageRightNote(stmt *ast.EdgeStmt) *model.Note {
 				OnLeft:   false,
 				Text:     opt.Value.String(),
 				ColorHex: "ffb6c1",
+				Pos:      modelPos(opt.Pos),
 			}
 		}
 	}
@@ -98,6 +98,40 @@ seqdiag {
 }
 `
 
+const testDataLabelPosition = `
+seqdiag {
+  default_label_position = center;
+  foo -> bar [label = "call"];
+  foo <-- bar [label = "reply", label_position = below];
+  loop {
+    foo -> bar [label = "poll"];
+  }
+}
+`
+
+const testDataConstraint = `
+seqdiag {
+  browser => web [label = "GET /", id = request];
+  loop {
+    browser -> web [id = poll];
+    constraint poll poll [label = "{< 1s}"];
+  }
+  browser <-- web [label = "done", id = response];
+  constraint response request [label = "{< 200ms}"];
+}
+`
+
+const testDataRef = `
+seqdiag {
+  ref "Login sequence" {
+    browser; web;
+    link = "login.xlsx";
+  }
+  browser -> web;
+  ref logout;
+}
+`
+
 func parseDiagram(t *testing.T, testData string) *model.SequenceDiagram {
 	d := seqdiag.ParseSeqdiag([]byte(testData))
 	lls, err := ExtractLifelines(d)
@@ -147,6 +181,23 @@ func TestExtractMessages(t *testing.T) {
 	checkMessage(t, seq.Messages[8], 8, "foo", "foo", model.SelfReference)
 }
 
+func TestExtractPositions(t *testing.T) {
+	seq := parseDiagram(t, testDataMessage)
+
+	if p := seq.Lifelines[0].Pos; p != (model.Pos{Line: 3, Column: 3}) {
+		t.Errorf("Invalid position of the lifeline %v", p)
+	}
+	if p := seq.Messages[0].Pos; p != (model.Pos{Line: 3, Column: 3}) {
+		t.Errorf("Invalid position of the message %v", p)
+	}
+	if p := seq.Messages[3].Pos; p != (model.Pos{Line: 5, Column: 11}) {
+		t.Errorf("Invalid position of the chained message %v", p)
+	}
+	if p := seq.Fragments[0].Pos; p != (model.Pos{Line: 6, Column: 3}) {
+		t.Errorf("Invalid position of the fragment %v", p)
+	}
+}
+
 func TestExtractMessagesTrip(t *testing.T) {
 	seq := parseDiagram(t, testDataMessageTrip)
 
@@ -189,6 +240,23 @@ func TestExtractMessagesLabel(t *testing.T) {
 	checkMessageLabel(t, seq.Messages[7], "INSERT INTO objects")
 }
 
+func TestExtractMessagesLabelPosition(t *testing.T) {
+	seq := parseDiagram(t, testDataLabelPosition)
+
+	expected := []model.LabelPosition{model.LabelCenter, model.LabelBelow, model.LabelCenter}
+	for i, msg := range seq.Messages {
+		if msg.LabelPosition != expected[i] {
+			t.Errorf("Mismatches label position of message %d [expect: %v, actual: %v]", i, expected[i], msg.LabelPosition)
+		}
+	}
+
+	d := seqdiag.ParseSeqdiag([]byte("seqdiag {\n  foo -> bar [label_position = top];\n}"))
+	err := ScanTimeline(d, &model.SequenceDiagram{})
+	if err == nil || err.Error() != "2:32: unknown label position 'top'" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
 func checkNote(t *testing.T, note *model.Note, idx int, onLeft bool, text string) {
 	if note.Assoc.Index != idx {
 		t.Fatalf("Mismatches index of message associated note [expect: %d, actual: %d]", idx, note.Assoc.Index)
@@ -247,6 +315,9 @@ func TestExtractFragmentsEmpty(t *testing.T) {
 	if err == nil {
 		t.Fatalf("Expected error does not occure")
 	}
+	if err.Error() != "4:3: empty fragment is not allowed" {
+		t.Fatalf("Error has no position: %v", err)
+	}
 }
 
 func checkSeparator(t *testing.T, sep *model.Separator, text string, beforeFrom string) {
@@ -269,3 +340,53 @@ func TestExtractSeparator(t *testing.T) {
 	checkSeparator(t, seq.Separators[5], "Sep6", "foo4")
 	checkSeparator(t, seq.Separators[6], "Sep7", "foo5")
 }
+
+func TestExtractConstraints(t *testing.T) {
+	seq := parseDiagram(t, testDataConstraint)
+
+	if len(seq.Constraints) != 2 {
+		t.Fatalf("Mismatches the number of constraints: %d", len(seq.Constraints))
+	}
+	c := seq.Constraints[0]
+	if c.Begin != seq.Messages[2] || c.End != seq.Messages[2] || c.Text != "{< 1s}" {
+		t.Errorf("Mismatches constraint in the fragment %+v", c)
+	}
+	c = seq.Constraints[1]
+	if c.Begin != seq.Messages[0] || c.End != seq.Messages[3] || c.Text != "{< 200ms}" || c.Pos.Line != 9 {
+		t.Errorf("Mismatches constraint between the messages in reverse order %+v", c)
+	}
+
+	tests := []struct {
+		src, err string
+	}{
+		{"seqdiag {\n  foo -> bar [id = a];\n  constraint a b;\n}", "3:16: unknown message id 'b'"},
+		{"seqdiag {\n  foo -> bar [id = a];\n  foo <-- bar [id = a];\n}", "3:21: duplicate message id 'a'"},
+	}
+	for _, tt := range tests {
+		d := seqdiag.ParseSeqdiag([]byte(tt.src))
+		lls, _ := ExtractLifelines(d)
+		err := ScanTimeline(d, &model.SequenceDiagram{Lifelines: lls})
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Unexpected error of %q: %v", tt.src, err)
+		}
+	}
+}
+
+func TestExtractRefs(t *testing.T) {
+	seq := parseDiagram(t, testDataRef)
+
+	if len(seq.Fragments) != 2 {
+		t.Fatalf("Mismatches the number of fragments: %d", len(seq.Fragments))
+	}
+	ref := seq.Fragments[0]
+	if ref.Type != model.Ref || ref.Text != "Login sequence" || ref.Before != nil || ref.Link != "login.xlsx" || ref.Pos.Line != 3 {
+		t.Errorf("Mismatches reference at the top %+v", ref)
+	}
+	if len(ref.Lifelines) != 2 || ref.Lifelines[0] != seq.Lifelines[0] || ref.Lifelines[1] != seq.Lifelines[1] {
+		t.Errorf("Mismatches lifelines of the reference %v", ref.Lifelines)
+	}
+	ref = seq.Fragments[1]
+	if ref.Type != model.Ref || ref.Text != "logout" || ref.Before != seq.Messages[0] || len(ref.Lifelines) != 0 || ref.Link != "" {
+		t.Errorf("Mismatches reference after the message %+v", ref)
+	}
+}
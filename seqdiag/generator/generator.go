@@ -0,0 +1,211 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const indentUnit = "  "
+
+var (
+	namePattern   = regexp.MustCompile(`^[A-Za-z0-9_\x{0080}-\x{ffff}][A-Za-z0-9_\-.\x{0080}-\x{ffff}]*$`)
+	numberPattern = regexp.MustCompile(`^-?[0-9.]+$`)
+	keywords      = map[string]bool{
+		"diagram":    true,
+		"seqdiag":    true,
+		"class":      true,
+		"plugin":     true,
+		"alt":        true,
+		"loop":       true,
+		"group":      true,
+		"constraint": true,
+		"ref":        true,
+	}
+)
+
+// Generate writes the given diagram model out as a 'seqdiag' text.
+//
+// Each message is written as a single edge statement, so nested edge blocks and
+// round-trip edges ("=>") of the original text are flattened into plain messages.
+// Fragments which 'seqdiag' cannot express are flattened too, except for the references. The messages which the constraints
+// refer to are named by their identifiers, and the constraints are written at the end.
+func Generate(w io.Writer, seq *model.SequenceDiagram) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString("seqdiag {\n")
+	if seq.Theme != "" {
+		fmt.Fprintf(buf, "%stheme = %s;\n", indentUnit, QuoteID(seq.Theme))
+	}
+	if seq.FontFamily != "" {
+		fmt.Fprintf(buf, "%sdefault_fontfamily = %s;\n", indentUnit, QuoteID(seq.FontFamily))
+	}
+	if seq.Lang != "" {
+		fmt.Fprintf(buf, "%sdefault_lang = %s;\n", indentUnit, QuoteID(seq.Lang))
+	}
+
+	for _, ll := range seq.Lifelines {
+		fmt.Fprintf(buf, "%s%s;\n", indentUnit, QuoteID(ll.Name))
+	}
+	if len(seq.Lifelines) > 0 && len(seq.Messages) > 0 {
+		buf.WriteString("\n")
+	}
+
+	for _, sep := range seq.Separators {
+		if sep.Before == nil {
+			writeSeparator(buf, sep, 1)
+		}
+	}
+	writeRefs(buf, seq.Fragments, nil, 1)
+
+	named := map[*model.Message]bool{}
+	for _, c := range seq.Constraints {
+		named[c.Begin] = true
+		named[c.End] = true
+	}
+
+	depth := 1
+	for _, msg := range seq.Messages {
+		for _, frag := range seq.Fragments {
+			if frag.Begin == msg && isSupportedFragment(frag.Type) {
+				fmt.Fprintf(buf, "%s%s {\n", indent(depth), frag.Type.String())
+				depth++
+			}
+		}
+
+		writeMessage(buf, msg, seq.Notes, named[msg], depth)
+
+		for i := len(seq.Fragments) - 1; i >= 0; i-- {
+			frag := seq.Fragments[i]
+			if frag.End == msg && isSupportedFragment(frag.Type) {
+				depth--
+				fmt.Fprintf(buf, "%s}\n", indent(depth))
+			}
+		}
+
+		for _, sep := range seq.Separators {
+			if sep.Before == msg {
+				writeSeparator(buf, sep, depth)
+			}
+		}
+		writeRefs(buf, seq.Fragments, msg, depth)
+	}
+
+	for _, c := range seq.Constraints {
+		stmt := fmt.Sprintf("constraint %s %s", c.Begin.ID(), c.End.ID())
+		if c.Text != "" {
+			stmt += " [label = " + QuoteString(c.Text) + "]"
+		}
+		fmt.Fprintf(buf, "%s%s;\n", indentUnit, stmt)
+	}
+
+	buf.WriteString("}\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMessage(buf *bytes.Buffer, msg *model.Message, notes []*model.Note, named bool, depth int) {
+	var stmt string
+	switch msg.Type {
+	case model.Asynchronous:
+		stmt = fmt.Sprintf("%s --> %s", QuoteID(msg.From.Name), QuoteID(msg.To.Name))
+	case model.Reply:
+		stmt = fmt.Sprintf("%s <-- %s", QuoteID(msg.To.Name), QuoteID(msg.From.Name))
+	default:
+		stmt = fmt.Sprintf("%s -> %s", QuoteID(msg.From.Name), QuoteID(msg.To.Name))
+	}
+
+	opts := []string{}
+	if msg.Text != "" {
+		opts = append(opts, "label = "+QuoteString(msg.Text))
+	}
+	if msg.LabelPosition != model.LabelLeft {
+		opts = append(opts, "label_position = "+msg.LabelPosition.String())
+	}
+	if named {
+		opts = append(opts, "id = "+msg.ID())
+	}
+	for _, note := range notes {
+		if note.Assoc != msg {
+			continue
+		}
+		if note.OnLeft {
+			opts = append(opts, "leftnote = "+QuoteString(note.Text))
+		} else {
+			opts = append(opts, "note = "+QuoteString(note.Text))
+		}
+	}
+	if len(opts) > 0 {
+		stmt += " [" + strings.Join(opts, ", ") + "]"
+	}
+
+	fmt.Fprintf(buf, "%s%s;\n", indent(depth), stmt)
+}
+
+func writeSeparator(buf *bytes.Buffer, sep *model.Separator, depth int) {
+	text := strings.Replace(sep.Text, "\n", " ", -1)
+	mark := "==="
+	if sep.Type == model.Delay {
+		mark = "..."
+	}
+	text = strings.Replace(text, mark[:1], "-", -1)
+	fmt.Fprintf(buf, "%s%s %s %s\n", indent(depth), mark, text, mark)
+}
+
+// writeRefs writes the references which are put after the message, or at the top if it is nil.
+func writeRefs(buf *bytes.Buffer, frags []*model.Fragment, before *model.Message, depth int) {
+	for _, frag := range frags {
+		if frag.Type != model.Ref || frag.Before != before {
+			continue
+		}
+		if len(frag.Lifelines) == 0 && frag.Link == "" {
+			fmt.Fprintf(buf, "%sref %s;\n", indent(depth), QuoteID(frag.Text))
+			continue
+		}
+		fmt.Fprintf(buf, "%sref %s {\n", indent(depth), QuoteID(frag.Text))
+		for _, ll := range frag.Lifelines {
+			fmt.Fprintf(buf, "%s%s;\n", indent(depth+1), QuoteID(ll.Name))
+		}
+		if frag.Link != "" {
+			fmt.Fprintf(buf, "%slink = %s;\n", indent(depth+1), QuoteID(frag.Link))
+		}
+		fmt.Fprintf(buf, "%s}\n", indent(depth))
+	}
+}
+
+func isSupportedFragment(t model.FragmentType) bool {
+	return t == model.Alt || t == model.Loop
+}
+
+func indent(depth int) string {
+	return strings.Repeat(indentUnit, depth)
+}
+
+// QuoteID returns the given identifier as is if it is a valid bare name or number, otherwise quotes it.
+func QuoteID(s string) string {
+	if (namePattern.MatchString(s) || numberPattern.MatchString(s)) && !keywords[s] {
+		return s
+	}
+	return QuoteString(s)
+}
+
+// QuoteString quotes the given text as a 'seqdiag' string literal.
+//
+// The literal has no escape sequence for quotation marks, so the text is
+// quoted with single quotes if it contains double quotes.
+func QuoteString(s string) string {
+	s = strings.Replace(s, "\n", `\n`, -1)
+	s = strings.Replace(s, "\r", `\r`, -1)
+	s = strings.Replace(s, "\t", `\t`, -1)
+	if !strings.Contains(s, `"`) {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, `'`) {
+		return `'` + s + `'`
+	}
+	return `"` + strings.Replace(s, `"`, `'`, -1) + `"`
+}
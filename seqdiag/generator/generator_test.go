@@ -0,0 +1,72 @@
+package generator
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+)
+
+const expectedSeqdiag = `seqdiag {
+  browser;
+  "web server";
+  "loop";
+
+  === begin ===
+  ref "Login sequence" {
+    browser;
+    "web server";
+    link = login.xlsx;
+  }
+  browser -> "web server" [label = "GET /index.html", id = message-0, leftnote = "left"];
+  loop {
+    "web server" --> "loop" [label = 'say "hello"'];
+    alt {
+      "web server" <-- "loop" [id = message-2, note = "multi\nline"];
+    }
+  }
+  "web server" -> "web server";
+  ... 5 min- later ...
+  ref "ref";
+  constraint message-0 message-2 [label = "{< 200ms}"];
+}
+`
+
+func TestGenerate(t *testing.T) {
+	browser := &model.Lifeline{Name: "browser", Index: 0}
+	web := &model.Lifeline{Name: "web server", Index: 1}
+	loop := &model.Lifeline{Name: "loop", Index: 2}
+	msgs := []*model.Message{
+		{Index: 0, From: browser, To: web, Type: model.Synchronous, Text: "GET /index.html"},
+		{Index: 1, From: web, To: loop, Type: model.Asynchronous, Text: `say "hello"`},
+		{Index: 2, From: loop, To: web, Type: model.Reply},
+		{Index: 3, From: web, To: web, Type: model.SelfReference},
+	}
+	seq := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{browser, web, loop},
+		Messages:  msgs,
+		Fragments: []*model.Fragment{
+			{Index: 0, Begin: msgs[1], End: msgs[2], Type: model.Loop},
+			{Index: 1, Begin: msgs[2], End: msgs[2], Type: model.Alt},
+			{Index: 2, Type: model.Ref, Text: "Login sequence", Lifelines: []*model.Lifeline{browser, web}, Link: "login.xlsx"},
+			{Index: 3, Type: model.Ref, Text: "ref", Before: msgs[3]},
+		},
+		Notes: []*model.Note{
+			{Assoc: msgs[0], OnLeft: true, Text: "left"},
+			{Assoc: msgs[2], OnLeft: false, Text: "multi\nline"},
+		},
+		Separators: []*model.Separator{
+			{Text: "begin", Before: nil},
+			{Type: model.Delay, Text: "5 min. later", Before: msgs[3]},
+		},
+		Constraints: []*model.Constraint{{Begin: msgs[0], End: msgs[2], Text: "{< 200ms}"}},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Generate(buf, seq); err != nil {
+		t.Fatalf("Generate error %v", err)
+	}
+	if buf.String() != expectedSeqdiag {
+		t.Fatalf("Mismatches generated text\n[expect]\n%s\n[actual]\n%s", expectedSeqdiag, buf.String())
+	}
+}
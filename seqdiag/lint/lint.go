@@ -0,0 +1,171 @@
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/seqdiag"
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	"github.com/rsp9u/seq2xls/seqdiag/convertor"
+	"github.com/rsp9u/seq2xls/seqdiag/errors"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+// Diagnostic is a problem found in the diagram.
+type Diagnostic struct {
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// String returns the diagnostic in the form of 'file:line:column: message (rule)'.
+func (d *Diagnostic) String() string {
+	s := fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+	if d.File != "" {
+		s = d.File + ":" + s
+	}
+	return s
+}
+
+// Rule is a check of the diagram.
+type Rule struct {
+	// Name is the identifier of the rule in the diagnostics.
+	Name string
+	// Check reports the problems of the diagram to the context.
+	Check func(c *Context)
+}
+
+// Context is the diagram being checked, which the rules report the problems to.
+type Context struct {
+	Diagram *ast.Diagram
+	// Model is the diagram converted from the AST, or nil if the conversion fails.
+	Model *model.SequenceDiagram
+
+	rule  *Rule
+	diags []*Diagnostic
+}
+
+// Report reports a problem at the position.
+func (c *Context) Report(pos token.Pos, format string, args ...interface{}) {
+	c.diags = append(c.diags, &Diagnostic{
+		Line:    pos.Line,
+		Column:  pos.Column,
+		Rule:    c.rule.Name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// report reports a problem at the position of the model element.
+func (c *Context) report(pos model.Pos, format string, args ...interface{}) {
+	c.Report(token.Pos{Line: pos.Line, Column: pos.Column}, format, args...)
+}
+
+// Lint checks the diagram with the rules and returns the diagnostics in order of the positions.
+//
+// The rules which need the model are skipped if the diagram cannot be converted to it,
+// and the error of the conversion is reported instead.
+func Lint(d *ast.Diagram, rules []*Rule) []*Diagnostic {
+	c := &Context{Diagram: d}
+	seq, err := convertor.AstToModel(d)
+	if err != nil {
+		c.rule = &Rule{Name: "convert"}
+		if cerr, ok := err.(*convertor.Error); ok {
+			c.Report(cerr.Pos, "%s", cerr.Msg)
+		} else {
+			c.Report(d.Lbrace, "%v", err)
+		}
+	} else {
+		c.Model = seq
+	}
+
+	for _, rule := range rules {
+		c.rule = rule
+		rule.Check(c)
+	}
+
+	sortDiagnostics(c.diags)
+	return c.diags
+}
+
+// LintSource parses the 'seqdiag' text and checks it with DefaultRules.
+// The syntax errors are returned as diagnostics. If the parser has recovered from them,
+// the rest of the diagram is checked as well. The file name is put into the diagnostics.
+func LintSource(file string, src []byte) []*Diagnostic {
+	d, err := seqdiag.Parse(src)
+	if err == nil {
+		return withFile(file, Lint(d, DefaultRules))
+	}
+
+	errs, ok := err.(seqdiag.ErrorList)
+	if !ok {
+		return withFile(file, []*Diagnostic{syntaxDiagnostic(err)})
+	}
+	diags := []*Diagnostic{}
+	for _, perr := range errs {
+		diags = append(diags, syntaxDiagnostic(perr))
+	}
+	diags = append(diags, Lint(d, DefaultRules)...)
+	sortDiagnostics(diags)
+	return withFile(file, diags)
+}
+
+// syntaxDiagnostic returns the diagnostic of the syntax error, which is at the error token if it is known.
+// The message does not repeat the position, unlike the error of gocc.
+func syntaxDiagnostic(err error) *Diagnostic {
+	diag := &Diagnostic{Rule: "syntax", Message: err.Error()}
+	perr, ok := err.(*errors.Error)
+	if !ok || perr.ErrorToken == nil {
+		return diag
+	}
+	diag.Line = perr.ErrorToken.Pos.Line
+	diag.Column = perr.ErrorToken.Pos.Column
+
+	found := fmt.Sprintf("'%s'", perr.ErrorToken.Lit)
+	if perr.ErrorToken.Type == token.EOF {
+		found = "end of file"
+	}
+	switch {
+	case perr.Err != nil:
+		diag.Message = perr.Err.Error()
+	case len(perr.ExpectedTokens) > 0:
+		diag.Message = fmt.Sprintf("unexpected %s, expected one of %s", found, strings.Join(perr.ExpectedTokens, ", "))
+	default:
+		diag.Message = "unexpected " + found
+	}
+	return diag
+}
+
+func withFile(file string, diags []*Diagnostic) []*Diagnostic {
+	for _, d := range diags {
+		d.File = file
+	}
+	return diags
+}
+
+func sortDiagnostics(diags []*Diagnostic) {
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].Line != diags[j].Line {
+			return diags[i].Line < diags[j].Line
+		}
+		return diags[i].Column < diags[j].Column
+	})
+}
+
+// walk calls fn for each statement in the statements and the blocks in them in order of appearance.
+func walk(stmts []ast.Stmt, fn func(stmt ast.Stmt)) {
+	for _, stmt := range stmts {
+		fn(stmt)
+		switch v := stmt.(type) {
+		case ast.ContainerStmt:
+			walk(v.GetItems(), fn)
+		case *ast.EdgeStmt:
+			if v.EdgeBlock != nil {
+				walk(v.EdgeBlock.Items, fn)
+			}
+		}
+	}
+}
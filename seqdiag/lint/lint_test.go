@@ -0,0 +1,200 @@
+package lint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	"github.com/rsp9u/seq2xls/seqdiag/errors"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+// pos returns a position at the line. The offsets only have to be in order.
+func pos(line, column int) token.Pos {
+	return token.Pos{Offset: line*1000 + column, Line: line, Column: column}
+}
+
+func id(value string, line, column int) *ast.ID {
+	return &ast.ID{Value: value, Pos: pos(line, column)}
+}
+
+func edge(left, e, right string, line int, opts ...*ast.Option) *ast.EdgeStmt {
+	return &ast.EdgeStmt{
+		EdgeSegments: &ast.EdgeSegmentList{Items: []*ast.EdgeSegment{
+			{LeftNode: id(left, line, 3), Edge: e, RightNode: id(right, line, 10)},
+		}},
+		Options:   &ast.OptionList{Items: opts},
+		EdgeBlock: &ast.EdgeBlockInlineStmtList{},
+	}
+}
+
+func node(name string, line, column int) *ast.NodeStmt {
+	return &ast.NodeStmt{ID: id(name, line, column), Option: &ast.OptionList{}}
+}
+
+// newTestDiagram returns the AST of the following text.
+//
+//	seqdiag {
+//	  edge_lenght = 300;
+//	  a; b; c; a;
+//	  a -> b [lable = "x", foo = 1];
+//	  a <-- b;
+//	  b <-- a;
+//	  par {
+//	    a => b;
+//	  }
+//	  group {
+//	    colour = red;
+//	  }
+//	  ref login {
+//	    a; c;
+//	    lnk = login.xlsx;
+//	  }
+//	}
+func newTestDiagram() *ast.Diagram {
+	return &ast.Diagram{
+		ID:     &ast.ID{},
+		Lbrace: pos(1, 9),
+		Rbrace: pos(17, 1),
+		Stmts: &ast.DiagramInlineStmtList{Items: []ast.Stmt{
+			&ast.AttributeStmt{Type: id("edge_lenght", 2, 3), Value: id("300", 2, 17)},
+			node("a", 3, 3),
+			node("b", 3, 6),
+			node("c", 3, 9),
+			node("a", 3, 12),
+			edge("a", "->", "b", 4,
+				&ast.Option{Type: id("lable", 4, 11), Value: id("x", 4, 19)},
+				&ast.Option{Type: id("foo", 4, 24), Value: id("1", 4, 30)},
+			),
+			edge("a", "<--", "b", 5),
+			edge("b", "<--", "a", 6),
+			&ast.FragmentStmt{
+				Type:   "par",
+				Pos:    pos(7, 3),
+				ID:     &ast.ID{},
+				Lbrace: pos(7, 7),
+				Rbrace: pos(9, 3),
+				Stmts:  &ast.FragmentInlineStmtList{Items: []ast.Stmt{edge("a", "=>", "b", 8)}},
+			},
+			&ast.GroupStmt{
+				Pos:    pos(10, 3),
+				ID:     &ast.ID{},
+				Lbrace: pos(10, 9),
+				Rbrace: pos(12, 3),
+				Stmts: &ast.GroupInineStmtList{Items: []ast.Stmt{
+					&ast.AttributeStmt{Type: id("colour", 11, 5), Value: id("red", 11, 14)},
+				}},
+			},
+			&ast.RefStmt{
+				Pos:    pos(13, 3),
+				ID:     id("login", 13, 7),
+				Lbrace: pos(13, 13),
+				Rbrace: pos(16, 3),
+				Stmts: &ast.GroupInineStmtList{Items: []ast.Stmt{
+					node("a", 14, 5),
+					node("c", 14, 8),
+					&ast.AttributeStmt{Type: id("lnk", 15, 5), Value: id("login.xlsx", 15, 11)},
+				}},
+			},
+		}},
+	}
+}
+
+func TestLint(t *testing.T) {
+	diags := Lint(newTestDiagram(), DefaultRules)
+
+	expected := []string{
+		"2:3: unknown diagram attribute 'edge_lenght', did you mean 'edge_length'? (attribute-typo)",
+		"3:12: node 'a' is already declared at line 3 (duplicate-node)",
+		"4:11: unknown edge option 'lable', did you mean 'label'? (attribute-typo)",
+		"4:24: unknown edge option 'foo' is ignored (unknown-option)",
+		"6:3: reply from 'a' to 'b' has no matching call (reply-without-call)",
+		"7:3: fragment type 'par' is not supported (unsupported-fragment)",
+		"11:5: unknown group attribute 'colour', did you mean 'color'? (attribute-typo)",
+		"15:5: unknown ref attribute 'lnk', did you mean 'link'? (attribute-typo)",
+	}
+	if len(diags) != len(expected) {
+		for _, d := range diags {
+			t.Log(d)
+		}
+		t.Fatalf("Unexpected number of diagnostics %d", len(diags))
+	}
+	for i, d := range diags {
+		if d.String() != expected[i] {
+			t.Errorf("Unexpected diagnostic [expect: %s, actual: %s]", expected[i], d)
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	diags := Lint(newTestDiagram(), []*Rule{DuplicateNode})
+	if len(diags) != 1 || diags[0].Rule != "duplicate-node" {
+		t.Errorf("Unexpected diagnostics %v", diags)
+	}
+
+	d := &Diagnostic{File: "a.diag", Line: 1, Column: 2, Rule: "r", Message: "m"}
+	if d.String() != "a.diag:1:2: m (r)" {
+		t.Errorf("Unexpected format %s", d)
+	}
+}
+
+func TestLintConvertError(t *testing.T) {
+	d := &ast.Diagram{
+		ID:     &ast.ID{},
+		Lbrace: pos(1, 9),
+		Rbrace: pos(4, 1),
+		Stmts: &ast.DiagramInlineStmtList{Items: []ast.Stmt{
+			&ast.FragmentStmt{
+				Type:   "loop",
+				Pos:    pos(2, 3),
+				ID:     &ast.ID{},
+				Lbrace: pos(2, 8),
+				Rbrace: pos(3, 3),
+				Stmts:  &ast.FragmentInlineStmtList{},
+			},
+		}},
+	}
+	diags := Lint(d, nil)
+	if len(diags) != 1 || diags[0].String() != "2:3: empty fragment is not allowed (convert)" {
+		t.Errorf("Unexpected diagnostics %v", diags)
+	}
+}
+
+func TestSyntaxDiagnostic(t *testing.T) {
+	err := &errors.Error{
+		ErrorToken:     &token.Token{Lit: []byte("}"), Pos: pos(11, 3)},
+		ExpectedTokens: []string{"id", ";"},
+	}
+	diag := syntaxDiagnostic(err)
+	diag.File = "l.diag"
+	if s := diag.String(); s != "l.diag:11:3: unexpected '}', expected one of id, ; (syntax)" {
+		t.Errorf("Unexpected diagnostic %s", s)
+	}
+	b, _ := json.Marshal(diag)
+	if s := string(b); s != `{"file":"l.diag","line":11,"column":3,"rule":"syntax","message":"unexpected '}', expected one of id, ;"}` {
+		t.Errorf("Unexpected JSON %s", s)
+	}
+
+	err = &errors.Error{ErrorToken: &token.Token{Type: token.EOF, Pos: pos(12, 1)}, ExpectedTokens: []string{"}"}}
+	if s := syntaxDiagnostic(err).String(); s != "12:1: unexpected end of file, expected one of } (syntax)" {
+		t.Errorf("Unexpected diagnostic at the end %s", s)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		d    int
+	}{
+		{"label", "label", 0},
+		{"lable", "label", 2},
+		{"colour", "color", 1},
+		{"", "note", 4},
+		{"ノート", "ノード", 1},
+	}
+	for _, tt := range tests {
+		if d := distance(tt.a, tt.b); d != tt.d {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, d, tt.d)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+)
+
+// DefaultRules is the rules applied by LintSource.
+var DefaultRules = []*Rule{
+	ReplyWithoutCall,
+	UnusedLifeline,
+	UnknownOption,
+	AttributeTypo,
+	DuplicateNode,
+	UnsupportedFragment,
+}
+
+var (
+	// edgeOptions is the option names of the edges.
+	edgeOptions = names("label", "return", "note", "leftnote", "rightnote", "color", "textcolor",
+		"fontsize", "style", "diagonal", "failed", "activate", "noactivate", "label_position", "id")
+	// constraintOptions is the option names of the constraints.
+	constraintOptions = names("label")
+	// nodeOptions is the option names of the nodes.
+	nodeOptions = names("label", "color", "textcolor", "linecolor", "fontsize", "fontfamily", "style",
+		"shape", "width", "height", "background", "icon", "numbered", "stacked", "description", "activated")
+	// diagramAttributes is the attribute names of the diagram.
+	diagramAttributes = names("activation", "autonumber", "edge_length", "span_height", "node_width",
+		"node_height", "fontsize", "default_fontsize", "default_fontfamily", "default_shape",
+		"default_linecolor", "default_textcolor", "default_node_color", "default_note_color",
+		"default_group_color", "default_lang", "default_label_position", "theme")
+	// groupAttributes is the attribute names of the groups.
+	groupAttributes = names("label", "color", "textcolor", "fontsize", "shape", "orientation")
+	// refAttributes is the attribute names of the references.
+	refAttributes = names("link")
+)
+
+// ReplyWithoutCall reports the replies which have no preceding call in the opposite direction.
+var ReplyWithoutCall = &Rule{Name: "reply-without-call", Check: checkReplies}
+
+// UnusedLifeline reports the lifelines which are declared but have no message.
+var UnusedLifeline = &Rule{Name: "unused-lifeline", Check: checkUnusedLifelines}
+
+// UnknownOption reports the options and the attributes whose names are unknown and ignored.
+// The names close to the known ones are reported by AttributeTypo instead.
+var UnknownOption = &Rule{Name: "unknown-option", Check: func(c *Context) { checkNames(c, false) }}
+
+// AttributeTypo reports the options and the attributes whose names look like misspellings of the known ones.
+var AttributeTypo = &Rule{Name: "attribute-typo", Check: func(c *Context) { checkNames(c, true) }}
+
+// DuplicateNode reports the nodes declared more than once.
+var DuplicateNode = &Rule{Name: "duplicate-node", Check: checkDuplicateNodes}
+
+// UnsupportedFragment reports the fragments which are converted into the unknown type.
+var UnsupportedFragment = &Rule{Name: "unsupported-fragment", Check: checkFragments}
+
+func checkReplies(c *Context) {
+	type call struct{ from, to string }
+	calls := []call{}
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		edge, ok := stmt.(*ast.EdgeStmt)
+		if !ok {
+			return
+		}
+		for _, sgmt := range edge.EdgeSegments.Items {
+			left, right := sgmt.LeftNode.Value, sgmt.RightNode.Value
+			switch {
+			case left == right || sgmt.Edge == "=>":
+				// self references and round trips need no reply
+			case strings.HasSuffix(sgmt.Edge, ">"):
+				calls = append(calls, call{left, right})
+			default:
+				// the reply goes from the right to the left
+				found := false
+				for i := len(calls) - 1; i >= 0; i-- {
+					if calls[i].from == left && calls[i].to == right {
+						calls = append(calls[:i], calls[i+1:]...)
+						found = true
+						break
+					}
+				}
+				if !found {
+					c.Report(sgmt.LeftNode.Pos, "reply from '%s' to '%s' has no matching call", right, left)
+				}
+			}
+		}
+	})
+}
+
+func checkUnusedLifelines(c *Context) {
+	if c.Model == nil {
+		return
+	}
+	used := map[*model.Lifeline]bool{}
+	for _, msg := range c.Model.Messages {
+		used[msg.From] = true
+		used[msg.To] = true
+	}
+	for _, note := range c.Model.Notes {
+		for _, ll := range note.Lifelines {
+			used[ll] = true
+		}
+	}
+	for _, frag := range c.Model.Fragments {
+		for _, ll := range frag.Lifelines {
+			used[ll] = true
+		}
+	}
+
+	reported := map[string]bool{}
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		node, ok := stmt.(*ast.NodeStmt)
+		if !ok || reported[node.ID.Value] {
+			return
+		}
+		for _, ll := range c.Model.Lifelines {
+			if ll.Name == node.ID.Value && !used[ll] {
+				c.Report(node.ID.Pos, "lifeline '%s' is declared but never used", ll.Name)
+				reported[ll.Name] = true
+			}
+		}
+	})
+}
+
+func checkNames(c *Context, typo bool) {
+	checkAttributes(c, typo, c.Diagram.Stmts.Items, "diagram attribute", diagramAttributes)
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		switch v := stmt.(type) {
+		case *ast.EdgeStmt:
+			for _, opt := range v.Options.Items {
+				checkName(c, typo, "edge option", opt.Type, edgeOptions)
+			}
+		case *ast.NodeStmt:
+			for _, opt := range v.Option.Items {
+				checkName(c, typo, "node option", opt.Type, nodeOptions)
+			}
+		case *ast.ConstraintStmt:
+			for _, opt := range v.Options.Items {
+				checkName(c, typo, "constraint option", opt.Type, constraintOptions)
+			}
+		case *ast.GroupStmt:
+			checkAttributes(c, typo, v.GetItems(), "group attribute", groupAttributes)
+		case *ast.RefStmt:
+			checkAttributes(c, typo, v.GetItems(), "ref attribute", refAttributes)
+		}
+	})
+}
+
+func checkAttributes(c *Context, typo bool, stmts []ast.Stmt, kind string, known map[string]bool) {
+	for _, stmt := range stmts {
+		if attr, ok := stmt.(*ast.AttributeStmt); ok {
+			checkName(c, typo, kind, attr.Type, known)
+		}
+	}
+}
+
+// checkName reports the unknown name. If typo is true, only the names close to the known ones
+// are reported with the suggestion, and otherwise only the others are reported.
+func checkName(c *Context, typo bool, kind string, id *ast.ID, known map[string]bool) {
+	if known[id.Value] {
+		return
+	}
+	suggestion := closest(id.Value, known)
+	switch {
+	case typo && suggestion != "":
+		c.Report(id.Pos, "unknown %s '%s', did you mean '%s'?", kind, id.Value, suggestion)
+	case !typo && suggestion == "":
+		c.Report(id.Pos, "unknown %s '%s' is ignored", kind, id.Value)
+	}
+}
+
+func checkDuplicateNodes(c *Context) {
+	// the nodes in the references name the lifelines which they cover rather than declare them
+	covered := map[*ast.NodeStmt]bool{}
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		if ref, ok := stmt.(*ast.RefStmt); ok {
+			for _, item := range ref.GetItems() {
+				if node, ok := item.(*ast.NodeStmt); ok {
+					covered[node] = true
+				}
+			}
+		}
+	})
+
+	declared := map[string]*ast.ID{}
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		node, ok := stmt.(*ast.NodeStmt)
+		if !ok || covered[node] {
+			return
+		}
+		if first, ok := declared[node.ID.Value]; ok {
+			c.Report(node.ID.Pos, "node '%s' is already declared at line %d", node.ID.Value, first.Pos.Line)
+			return
+		}
+		declared[node.ID.Value] = node.ID
+	})
+}
+
+func checkFragments(c *Context) {
+	if c.Model == nil {
+		return
+	}
+	types := map[model.Pos]string{}
+	walk(c.Diagram.Stmts.Items, func(stmt ast.Stmt) {
+		if frag, ok := stmt.(*ast.FragmentStmt); ok {
+			types[model.Pos{Line: frag.Pos.Line, Column: frag.Pos.Column}] = frag.Type
+		}
+	})
+	for _, frag := range c.Model.Fragments {
+		if frag.Type == model.UnknownFragment {
+			c.report(frag.Pos, "fragment type '%s' is not supported", types[frag.Pos])
+		}
+	}
+}
+
+// closest returns the known name within a small edit distance of the name, or empty if there is none.
+func closest(name string, known map[string]bool) string {
+	limit := 2
+	if len(name) <= 4 {
+		limit = 1
+	}
+
+	candidates := []string{}
+	for k := range known {
+		candidates = append(candidates, k)
+	}
+	sort.Strings(candidates)
+
+	best, bestDist := "", limit+1
+	for _, k := range candidates {
+		if d := distance(name, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between the strings.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func names(ns ...string) map[string]bool {
+	m := map[string]bool{}
+	for _, n := range ns {
+		m[n] = true
+	}
+	return m
+}
+
+func minInt(v int, vs ...int) int {
+	for _, x := range vs {
+		if x < v {
+			v = x
+		}
+	}
+	return v
+}
@@ -1,23 +1,75 @@
 package seqdiag
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	gocc "github.com/rsp9u/seq2xls/seqdiag/errors"
 	"github.com/rsp9u/seq2xls/seqdiag/lexer"
 	"github.com/rsp9u/seq2xls/seqdiag/parser"
 )
 
+// ErrorList is the syntax errors in the 'seqdiag' text in order of appearance.
+type ErrorList []*gocc.Error
+
+func (l ErrorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 // ParseSeqdiag parses the given 'seqdiag' text and converts into Go structures.
+// It panics if the text is not a valid 'seqdiag'.
+//
+// Deprecated: Use Parse, which returns all the syntax errors instead of panicking on them.
 func ParseSeqdiag(b []byte) *ast.Diagram {
+	d, err := Parse(b)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// Parse parses the given 'seqdiag' text and converts into Go structures.
+//
+// The parser skips the statements with syntax errors up to the next ';' or the end of the block and goes on.
+// If there are such statements, Parse returns the diagram without them and the ErrorList of all the errors.
+// Otherwise a syntax error is returned as *errors.Error of gocc without the diagram.
+func Parse(b []byte) (*ast.Diagram, error) {
 	lex := lexer.NewLexer(b)
 	p := parser.NewParser()
 	st, err := p.Parse(lex)
 	if err != nil {
-		panic(err)
+		if perr, ok := err.(*gocc.Error); ok {
+			removeErrorToken(perr)
+		}
+		return nil, err
 	}
 
 	d, ok := st.(*ast.Diagram)
 	if !ok {
-		panic("This is not a seqdiag")
+		return nil, errors.New("this is not a seqdiag")
 	}
-	return d
+	if len(d.Errors) > 0 {
+		for _, perr := range d.Errors {
+			removeErrorToken(perr)
+		}
+		return d, ErrorList(d.Errors)
+	}
+	return d, nil
+}
+
+// removeErrorToken removes the 'error' pseudo token, with which the grammar recovers from the errors,
+// from the expected tokens of the error, since it cannot be written in the text.
+func removeErrorToken(err *gocc.Error) {
+	expected := []string{}
+	for _, tok := range err.ExpectedTokens {
+		if tok != "error" {
+			expected = append(expected, tok)
+		}
+	}
+	err.ExpectedTokens = expected
 }
@@ -0,0 +1,318 @@
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/seqdiag"
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	"github.com/rsp9u/seq2xls/seqdiag/generator"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+const indentUnit = "  "
+
+var (
+	// optionOrder is the canonical order of the options. The others follow them in order of appearance.
+	optionOrder = []string{
+		"label",
+		"id",
+		"return",
+		"leftnote",
+		"note",
+		"rightnote",
+		"color",
+		"textcolor",
+		"fontsize",
+		"style",
+		"diagonal",
+		"failed",
+	}
+	// textOptions is the options whose values are always quoted.
+	textOptions = map[string]bool{
+		"label":     true,
+		"return":    true,
+		"leftnote":  true,
+		"note":      true,
+		"rightnote": true,
+	}
+)
+
+type printer struct {
+	buf      bytes.Buffer
+	comments []*ast.Comment
+	depth    int
+	// lastLine is the last line in the source text of the element printed last.
+	lastLine int
+	// lineOpen is whether the current output line is not terminated yet.
+	lineOpen bool
+	// blockStart is whether nothing is printed yet in the current block.
+	blockStart bool
+}
+
+// Fprint writes the diagram out to w as a canonical 'seqdiag' text.
+//
+// The statements are indented by two spaces and terminated by ';', the identifiers are
+// quoted only if necessary and the options are sorted in the canonical order.
+// A comment is put on the line before the statement which follows it, or at the end of the
+// line if it follows a statement on the same line in the source text. Blank lines between
+// the statements are kept but not more than one.
+func Fprint(w io.Writer, d *ast.Diagram, comments []*ast.Comment) error {
+	p := &printer{comments: comments}
+	p.flushComments(d.Lbrace.Offset)
+
+	p.beginLine(d.Lbrace.Line, true)
+	p.buf.WriteString("seqdiag")
+	if d.ID != nil && d.ID.Value != "" {
+		p.buf.WriteString(" " + generator.QuoteID(d.ID.Value))
+	}
+	p.buf.WriteString(" {")
+	p.lastLine = d.Lbrace.Line
+	p.block(d.Stmts.Items, d.Rbrace)
+
+	p.flushComments(math.MaxInt32)
+	p.newline()
+
+	_, err := w.Write(p.buf.Bytes())
+	return err
+}
+
+// Format parses the 'seqdiag' text and returns it in the canonical form with its comments.
+func Format(src []byte) ([]byte, error) {
+	d, err := seqdiag.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	buf := new(bytes.Buffer)
+	err = Fprint(buf, d, seqdiag.ScanComments(src))
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// newline terminates the current output line.
+func (p *printer) newline() {
+	if p.lineOpen {
+		p.buf.WriteString("\n")
+		p.lineOpen = false
+	}
+}
+
+// beginLine starts a new indented line for the element at the given line in the source text.
+// If blank is true, a blank line is put when the element is apart from the previous one.
+func (p *printer) beginLine(line int, blank bool) {
+	p.newline()
+	if blank && !p.blockStart && p.lastLine != 0 && line > p.lastLine+1 {
+		p.buf.WriteString("\n")
+	}
+	p.buf.WriteString(strings.Repeat(indentUnit, p.depth))
+	p.lineOpen = true
+	p.blockStart = false
+}
+
+// flushComments prints the comments which precede the given offset in the source text.
+func (p *printer) flushComments(offset int) {
+	for len(p.comments) > 0 && p.comments[0].Pos.Offset < offset {
+		c := p.comments[0]
+		p.comments = p.comments[1:]
+
+		if p.lineOpen && c.Pos.Line == p.lastLine {
+			p.buf.WriteString(" " + c.Text)
+		} else {
+			p.beginLine(c.Pos.Line, true)
+			p.buf.WriteString(c.Text)
+		}
+		p.lastLine = c.Pos.Line + strings.Count(c.Text, "\n")
+		if !strings.HasPrefix(c.Text, "/*") {
+			// nothing can follow a single line comment
+			p.newline()
+		}
+	}
+}
+
+// block prints the statements in the block and the closing brace.
+func (p *printer) block(stmts []ast.Stmt, rbrace token.Pos) {
+	p.depth++
+	p.blockStart = true
+	for _, stmt := range stmts {
+		p.flushComments(stmtPos(stmt).Offset)
+		p.stmt(stmt)
+	}
+	p.flushComments(rbrace.Offset)
+	p.depth--
+
+	p.beginLine(rbrace.Line, false)
+	p.buf.WriteString("}")
+	p.lastLine = rbrace.Line
+}
+
+func (p *printer) stmt(stmt ast.Stmt) {
+	switch v := stmt.(type) {
+	case *ast.AttributeStmt:
+		p.beginLine(v.Type.Pos.Line, true)
+		fmt.Fprintf(&p.buf, "%s = %s;", generator.QuoteID(v.Type.Value), generator.QuoteID(v.Value.Value))
+		p.lastLine = lastLine(v.Type, v.Value)
+
+	case *ast.NodeStmt:
+		p.beginLine(v.ID.Pos.Line, true)
+		p.buf.WriteString(generator.QuoteID(v.ID.Value) + options(v.Option) + ";")
+		p.lastLine = lastLine(append([]*ast.ID{v.ID}, optionIDs(v.Option)...)...)
+
+	case *ast.ExtensionStmt:
+		p.beginLine(v.Pos.Line, true)
+		p.buf.WriteString(v.Type + " " + generator.QuoteID(v.ID.Value) + options(v.Options) + ";")
+		p.lastLine = lastLine(append([]*ast.ID{v.ID}, optionIDs(v.Options)...)...)
+
+	case *ast.EdgeStmt:
+		ids := []*ast.ID{}
+		first := v.EdgeSegments.Items[0].LeftNode
+		p.beginLine(first.Pos.Line, true)
+		p.buf.WriteString(generator.QuoteID(first.Value))
+		for _, sgmt := range v.EdgeSegments.Items {
+			p.buf.WriteString(" " + sgmt.Edge + " " + generator.QuoteID(sgmt.RightNode.Value))
+			ids = append(ids, sgmt.RightNode)
+		}
+		p.buf.WriteString(options(v.Options))
+		p.lastLine = lastLine(append(ids, optionIDs(v.Options)...)...)
+
+		if v.EdgeBlock != nil && v.EdgeBlock.Lbrace.Line != 0 {
+			p.buf.WriteString(" {")
+			p.lastLine = v.EdgeBlock.Lbrace.Line
+			p.block(v.EdgeBlock.Items, v.EdgeBlock.Rbrace)
+		} else {
+			p.buf.WriteString(";")
+		}
+
+	case *ast.SeparatorStmt:
+		p.beginLine(v.Pos.Line, true)
+		fmt.Fprintf(&p.buf, "%s %s %s", v.Type, v.Value, v.Type)
+		p.lastLine = v.Pos.Line
+
+	case *ast.ConstraintStmt:
+		p.beginLine(v.Pos.Line, true)
+		fmt.Fprintf(&p.buf, "constraint %s %s%s;", generator.QuoteID(v.Begin.Value), generator.QuoteID(v.End.Value), options(v.Options))
+		p.lastLine = lastLine(append([]*ast.ID{v.Begin, v.End}, optionIDs(v.Options)...)...)
+
+	case *ast.FragmentStmt:
+		p.beginLine(v.Pos.Line, true)
+		p.buf.WriteString(v.Type)
+		if v.ID.Value != "" {
+			p.buf.WriteString(" " + generator.QuoteString(v.ID.Value))
+		}
+		p.buf.WriteString(" {")
+		p.lastLine = v.Lbrace.Line
+		p.block(v.Stmts.Items, v.Rbrace)
+
+	case *ast.GroupStmt:
+		p.beginLine(v.Pos.Line, true)
+		p.buf.WriteString("group")
+		if v.ID.Value != "" {
+			p.buf.WriteString(" " + generator.QuoteID(v.ID.Value))
+		}
+		p.buf.WriteString(" {")
+		p.lastLine = v.Lbrace.Line
+		p.block(v.Stmts.Items, v.Rbrace)
+
+	case *ast.RefStmt:
+		p.beginLine(v.Pos.Line, true)
+		p.buf.WriteString("ref " + generator.QuoteID(v.ID.Value))
+		p.lastLine = v.ID.Pos.Line
+		if v.Lbrace.Line != 0 {
+			p.buf.WriteString(" {")
+			p.lastLine = v.Lbrace.Line
+			p.block(v.Stmts.Items, v.Rbrace)
+		} else {
+			p.buf.WriteString(";")
+		}
+	}
+}
+
+// options returns the option list in the canonical form, or empty if there is no option.
+func options(opts *ast.OptionList) string {
+	if opts == nil || len(opts.Items) == 0 {
+		return ""
+	}
+
+	items := append([]*ast.Option{}, opts.Items...)
+	sort.SliceStable(items, func(i, j int) bool {
+		return optionRank(items[i].Type.Value) < optionRank(items[j].Type.Value)
+	})
+
+	strs := []string{}
+	for _, opt := range items {
+		key := generator.QuoteID(opt.Type.Value)
+		switch {
+		case opt.Value == nil || opt.Value.Value == "":
+			strs = append(strs, key)
+		case textOptions[opt.Type.Value]:
+			strs = append(strs, key+" = "+generator.QuoteString(opt.Value.Value))
+		default:
+			strs = append(strs, key+" = "+generator.QuoteID(opt.Value.Value))
+		}
+	}
+	return " [" + strings.Join(strs, ", ") + "]"
+}
+
+func optionRank(key string) int {
+	for i, k := range optionOrder {
+		if k == key {
+			return i
+		}
+	}
+	return len(optionOrder)
+}
+
+func optionIDs(opts *ast.OptionList) []*ast.ID {
+	ids := []*ast.ID{}
+	if opts == nil {
+		return ids
+	}
+	for _, opt := range opts.Items {
+		ids = append(ids, opt.Type)
+		if opt.Value != nil {
+			ids = append(ids, opt.Value)
+		}
+	}
+	return ids
+}
+
+// lastLine returns the last line in the source text among the given identifiers.
+func lastLine(ids ...*ast.ID) int {
+	line := 0
+	for _, id := range ids {
+		if id.Pos.Line > line {
+			line = id.Pos.Line
+		}
+	}
+	return line
+}
+
+// stmtPos returns the position of the first token of the statement.
+func stmtPos(stmt ast.Stmt) token.Pos {
+	switch v := stmt.(type) {
+	case *ast.AttributeStmt:
+		return v.Type.Pos
+	case *ast.NodeStmt:
+		return v.ID.Pos
+	case *ast.ExtensionStmt:
+		return v.Pos
+	case *ast.EdgeStmt:
+		return v.EdgeSegments.Items[0].LeftNode.Pos
+	case *ast.SeparatorStmt:
+		return v.Pos
+	case *ast.ConstraintStmt:
+		return v.Pos
+	case *ast.FragmentStmt:
+		return v.Pos
+	case *ast.GroupStmt:
+		return v.Pos
+	case *ast.RefStmt:
+		return v.Pos
+	}
+	return token.Pos{}
+}
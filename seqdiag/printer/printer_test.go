@@ -0,0 +1,120 @@
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/seqdiag/ast"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+const expectedFormat = `# header
+seqdiag {
+  edge_length = 300;
+  a [label = "A", color = red]; # trailing
+
+  // before edge
+  a -> b [note = "n", diagonal] {
+    b --> "c d";
+  }
+  loop "retry" {
+    === sep ===
+  }
+  constraint a b [label = "< 1s"];
+  ref "Login sequence" {
+    a;
+    link = login.xlsx;
+  }
+  ref logout;
+  /* last */
+}
+`
+
+// pos returns a position at the line. The offsets only have to be in order.
+func pos(line, column int) token.Pos {
+	return token.Pos{Offset: line*1000 + column, Line: line, Column: column}
+}
+
+func id(value string, line, column int) *ast.ID {
+	return &ast.ID{Value: value, Pos: pos(line, column)}
+}
+
+func TestFprint(t *testing.T) {
+	d := &ast.Diagram{
+		ID:     &ast.ID{},
+		Lbrace: pos(2, 9),
+		Rbrace: pos(21, 1),
+		Stmts: &ast.DiagramInlineStmtList{Items: []ast.Stmt{
+			&ast.AttributeStmt{Type: id("edge_length", 3, 3), Value: id("300", 3, 15)},
+			&ast.NodeStmt{ID: id("a", 4, 3), Option: &ast.OptionList{Items: []*ast.Option{
+				{Type: id("color", 4, 6), Value: id("red", 4, 12)},
+				{Type: id("label", 4, 16), Value: id("A", 4, 22)},
+			}}},
+			&ast.EdgeStmt{
+				EdgeSegments: &ast.EdgeSegmentList{Items: []*ast.EdgeSegment{
+					{LeftNode: id("a", 8, 3), Edge: "->", RightNode: id("b", 8, 8)},
+				}},
+				Options: &ast.OptionList{Items: []*ast.Option{
+					{Type: id("note", 8, 11), Value: id("n", 8, 16)},
+					{Type: id("diagonal", 8, 21), Value: &ast.ID{}},
+				}},
+				EdgeBlock: &ast.EdgeBlockInlineStmtList{
+					Lbrace: pos(8, 31),
+					Rbrace: pos(10, 3),
+					Items: []ast.Stmt{
+						&ast.EdgeStmt{
+							EdgeSegments: &ast.EdgeSegmentList{Items: []*ast.EdgeSegment{
+								{LeftNode: id("b", 9, 5), Edge: "-->", RightNode: id("c d", 9, 11)},
+							}},
+							Options:   &ast.OptionList{},
+							EdgeBlock: &ast.EdgeBlockInlineStmtList{},
+						},
+					},
+				},
+			},
+			&ast.FragmentStmt{
+				Type:   "loop",
+				Pos:    pos(11, 3),
+				ID:     id("retry", 11, 8),
+				Lbrace: pos(11, 16),
+				Rbrace: pos(13, 3),
+				Stmts: &ast.FragmentInlineStmtList{Items: []ast.Stmt{
+					&ast.SeparatorStmt{Type: "===", Value: "sep", Pos: pos(12, 5)},
+				}},
+			},
+			&ast.ConstraintStmt{
+				Begin: id("a", 14, 14),
+				End:   id("b", 14, 16),
+				Options: &ast.OptionList{Items: []*ast.Option{
+					{Type: id("label", 14, 19), Value: id("< 1s", 14, 27)},
+				}},
+				Pos: pos(14, 3),
+			},
+			&ast.RefStmt{
+				Pos:    pos(15, 3),
+				ID:     id("Login sequence", 15, 7),
+				Lbrace: pos(15, 24),
+				Rbrace: pos(18, 3),
+				Stmts: &ast.GroupInineStmtList{Items: []ast.Stmt{
+					&ast.NodeStmt{ID: id("a", 16, 5), Option: &ast.OptionList{}},
+					&ast.AttributeStmt{Type: id("link", 17, 5), Value: id("login.xlsx", 17, 12)},
+				}},
+			},
+			&ast.RefStmt{Pos: pos(19, 3), ID: id("logout", 19, 7), Stmts: &ast.GroupInineStmtList{}},
+		}},
+	}
+	comments := []*ast.Comment{
+		{Text: "# header", Pos: pos(1, 1)},
+		{Text: "# trailing", Pos: pos(4, 27)},
+		{Text: "// before edge", Pos: pos(7, 3)},
+		{Text: "/* last */", Pos: pos(20, 1)},
+	}
+
+	buf := new(bytes.Buffer)
+	if err := Fprint(buf, d, comments); err != nil {
+		t.Fatalf("Fprint error %v", err)
+	}
+	if buf.String() != expectedFormat {
+		t.Fatalf("Mismatches printed text\n[expect]\n%s\n[actual]\n%s", expectedFormat, buf.String())
+	}
+}
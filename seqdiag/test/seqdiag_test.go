@@ -3,8 +3,10 @@ package test
 import (
 	"testing"
 
+	"github.com/rsp9u/seq2xls/seqdiag"
 	"github.com/rsp9u/seq2xls/seqdiag/ast"
 	"github.com/rsp9u/seq2xls/seqdiag/lexer"
+	"github.com/rsp9u/seq2xls/seqdiag/lint"
 	"github.com/rsp9u/seq2xls/seqdiag/parser"
 )
 
@@ -19,6 +21,49 @@ seqdiag {
 }
 `
 
+const testDataErrors = `
+seqdiag {
+  a -> b;
+  a -> -> b;
+  loop {
+    b -> [;
+    b -> c;
+  }
+  c -> a;
+}
+`
+
+const testDataErrorsWithoutSc = `
+seqdiag {
+  a -> b
+  loop {
+    b -> -> c
+    c -> d
+  }
+  a -> [label = x]
+  d -> a
+}
+`
+
+const testDataKeywordNames = `
+seqdiag {
+  A -> ref [label = constraint, id = ref];
+  ref -> constraint
+  constraint -> A
+  constraint ref ref;
+  ref "Login"
+}
+`
+
+const testDataUnsupportedFragment = `
+seqdiag {
+  a -> b;
+  par {
+    a -> b;
+  }
+}
+`
+
 func checkEqual(t *testing.T, act, exp, errfmt string) {
 	if act != exp {
 		t.Fatalf(errfmt, act)
@@ -80,3 +125,74 @@ func TestSimple(t *testing.T) {
 	checkEdgeSgmt(t, e.EdgeSegments.Items[0], "browser", "webserver", "<--")
 	checkEqualInt(t, len(e.Options.Items), 0, "Wrong option size %v")
 }
+
+func TestErrorRecovery(t *testing.T) {
+	d, err := seqdiag.Parse([]byte(testDataErrors))
+	errs, ok := err.(seqdiag.ErrorList)
+	if !ok {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	checkEqualInt(t, len(errs), 2, "Wrong number of errors %v")
+	checkEqualInt(t, errs[0].ErrorToken.Pos.Line, 4, "Wrong line of the error %v")
+	checkEqualInt(t, errs[0].ErrorToken.Pos.Column, 8, "Wrong column of the error %v")
+	checkEqualInt(t, errs[1].ErrorToken.Pos.Line, 6, "Wrong line of the error %v")
+	checkEqualInt(t, errs[1].ErrorToken.Pos.Column, 10, "Wrong column of the error %v")
+	for _, perr := range errs {
+		for _, tok := range perr.ExpectedTokens {
+			if tok == "error" {
+				t.Errorf("The pseudo token is expected %v", perr.ExpectedTokens)
+			}
+		}
+	}
+
+	checkEqualInt(t, len(d.Stmts.Items), 3, "Wrong number of statements %v")
+	checkEdgeSgmt(t, d.Stmts.Items[0].(*ast.EdgeStmt).EdgeSegments.Items[0], "a", "b", "->")
+	frag := d.Stmts.Items[1].(*ast.FragmentStmt)
+	checkEqualInt(t, len(frag.Stmts.Items), 1, "Wrong number of statements in the fragment %v")
+	checkEdgeSgmt(t, frag.Stmts.Items[0].(*ast.EdgeStmt).EdgeSegments.Items[0], "b", "c", "->")
+	checkEdgeSgmt(t, d.Stmts.Items[2].(*ast.EdgeStmt).EdgeSegments.Items[0], "c", "a", "->")
+}
+
+func TestErrorRecoveryWithoutSc(t *testing.T) {
+	d, err := seqdiag.Parse([]byte(testDataErrorsWithoutSc))
+	errs, ok := err.(seqdiag.ErrorList)
+	if !ok {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	checkEqualInt(t, len(errs), 2, "Wrong number of errors %v")
+	checkEqualInt(t, errs[0].ErrorToken.Pos.Line, 5, "Wrong line of the error %v")
+	checkEqualInt(t, errs[0].ErrorToken.Pos.Column, 10, "Wrong column of the error %v")
+	checkEqualInt(t, errs[1].ErrorToken.Pos.Line, 8, "Wrong line of the error %v")
+	checkEqualInt(t, errs[1].ErrorToken.Pos.Column, 8, "Wrong column of the error %v")
+
+	checkEqualInt(t, len(d.Stmts.Items), 2, "Wrong number of statements %v")
+	checkEdgeSgmt(t, d.Stmts.Items[0].(*ast.EdgeStmt).EdgeSegments.Items[0], "a", "b", "->")
+	frag := d.Stmts.Items[1].(*ast.FragmentStmt)
+	checkEqualInt(t, len(frag.Stmts.Items), 0, "Wrong number of statements in the fragment %v")
+	checkEqualInt(t, frag.Rbrace.Line, 7, "Wrong line of the end of the fragment %v")
+}
+
+func TestKeywordNames(t *testing.T) {
+	d, err := seqdiag.Parse([]byte(testDataKeywordNames))
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	checkEqualInt(t, len(d.Stmts.Items), 5, "Wrong number of statements %v")
+
+	e := d.Stmts.Items[0].(*ast.EdgeStmt)
+	checkEdgeSgmt(t, e.EdgeSegments.Items[0], "A", "ref", "->")
+	checkEqual(t, e.Options.Items[0].Value.Value, "constraint", "Wrong option value %v")
+	checkEqual(t, e.Options.Items[1].Value.Value, "ref", "Wrong option value %v")
+	checkEdgeSgmt(t, d.Stmts.Items[1].(*ast.EdgeStmt).EdgeSegments.Items[0], "ref", "constraint", "->")
+	checkEdgeSgmt(t, d.Stmts.Items[2].(*ast.EdgeStmt).EdgeSegments.Items[0], "constraint", "A", "->")
+
+	c := d.Stmts.Items[3].(*ast.ConstraintStmt)
+	checkEqual(t, c.Begin.Value, "ref", "Wrong beginning of the constraint %v")
+	checkEqual(t, d.Stmts.Items[4].(*ast.RefStmt).ID.Value, "Login", "Wrong name of the reference %v")
+}
+
+func TestLintUnsupportedFragment(t *testing.T) {
+	diags := lint.LintSource("test.diag", []byte(testDataUnsupportedFragment))
+	checkEqualInt(t, len(diags), 1, "Wrong number of diagnostics %v")
+	checkEqual(t, diags[0].String(), "test.diag:4:3: fragment type 'par' is not supported (unsupported-fragment)", "Wrong diagnostic %v")
+}
@@ -0,0 +1,86 @@
+package server
+
+// editorPage is the page to edit a diagram with the live preview in SVG.
+const editorPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>seq2xls</title>
+<style>
+body { margin: 0; display: flex; height: 100vh; font-family: sans-serif; }
+#editor { display: flex; flex-direction: column; width: 40%; border-right: 1px solid #ccc; }
+#source { flex: 1; padding: 8px; border: none; resize: none; font-family: monospace; font-size: 14px; }
+#toolbar { padding: 8px; border-top: 1px solid #ccc; }
+#error { margin: 0; padding: 8px; color: #c00; white-space: pre-wrap; }
+#view { flex: 1; overflow: auto; padding: 8px; }
+</style>
+</head>
+<body>
+<div id="editor">
+<textarea id="source" spellcheck="false">seqdiag {
+  browser -> webserver [label = "GET /index.html"];
+  browser <-- webserver;
+}
+</textarea>
+<div id="toolbar">
+<button data-format="xlsx">Download xlsx</button>
+<button data-format="png">Download png</button>
+<button data-format="svg">Download svg</button>
+</div>
+<pre id="error"></pre>
+</div>
+<div id="view"><img id="preview" alt=""></div>
+<script>
+const source = document.getElementById('source');
+const preview = document.getElementById('preview');
+const error = document.getElementById('error');
+
+async function render(format) {
+  const res = await fetch('render?format=' + format, {method: 'POST', body: source.value});
+  if (!res.ok) {
+    const e = await res.json();
+    const format = (e) => e.line ? 'line ' + e.line + ', column ' + e.column + ': ' + e.error : e.error;
+    throw new Error(e.errors ? e.errors.map(format).join('\n') : format(e));
+  }
+  return res.blob();
+}
+
+async function update() {
+  try {
+    const blob = await render('svg');
+    if (preview.src) {
+      URL.revokeObjectURL(preview.src);
+    }
+    preview.src = URL.createObjectURL(blob);
+    error.textContent = '';
+  } catch (e) {
+    error.textContent = e.message;
+  }
+}
+
+let timer;
+source.addEventListener('input', () => {
+  clearTimeout(timer);
+  timer = setTimeout(update, 500);
+});
+
+document.querySelectorAll('#toolbar button').forEach((button) => {
+  button.addEventListener('click', async () => {
+    try {
+      const blob = await render(button.dataset.format);
+      const a = document.createElement('a');
+      a.href = URL.createObjectURL(blob);
+      a.download = 'diagram.' + button.dataset.format;
+      a.click();
+      setTimeout(() => URL.revokeObjectURL(a.href), 0);
+    } catch (e) {
+      error.textContent = e.message;
+    }
+  });
+});
+
+update();
+</script>
+</body>
+</html>
+`
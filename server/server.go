@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/render"
+	"github.com/rsp9u/seq2xls/seqdiag"
+	"github.com/rsp9u/seq2xls/seqdiag/convertor"
+	gocc "github.com/rsp9u/seq2xls/seqdiag/errors"
+)
+
+// Limits is the limits on the rendering requests.
+type Limits struct {
+	// MaxBodySize is the maximum size of the diagram text in bytes.
+	MaxBodySize int64
+	// RenderTimeout is the maximum time to wait for a diagram to be parsed and rendered,
+	// including the wait for a slot of MaxRenders. The request is responded with an error when it expires,
+	// but the rendering already started is not stopped and keeps its slot until it finishes.
+	RenderTimeout time.Duration
+	// MaxRenders is the maximum number of the diagrams rendered at once.
+	MaxRenders int
+}
+
+// DefaultLimits is the limits used by the zero values in the given limits.
+var DefaultLimits = Limits{MaxBodySize: 1 << 20, RenderTimeout: 10 * time.Second, MaxRenders: runtime.NumCPU()}
+
+// renderer writes out the diagram text in an output format.
+type renderer struct {
+	contentType string
+	render      func(w io.Writer, src []byte) error
+}
+
+// renderers is the renderers by the output format.
+var renderers = map[string]renderer{
+	"xlsx": {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", renderXLSX},
+	"svg":  {"image/svg+xml", renderImage(render.SVG)},
+	"png":  {"image/png", renderImage(render.PNG)},
+}
+
+// Error is the body of the error responses.
+// Line and Column are the position of the syntax error in the diagram text if it is known.
+// If there are several syntax errors, they are listed in Errors and the first one is the position.
+type Error struct {
+	Message string   `json:"error"`
+	Line    int      `json:"line,omitempty"`
+	Column  int      `json:"column,omitempty"`
+	Errors  []*Error `json:"errors,omitempty"`
+}
+
+type handler struct {
+	limits Limits
+	// renders holds a value for each diagram being rendered, up to MaxRenders.
+	renders chan struct{}
+}
+
+// NewHandler returns the handler which serves the editor page at '/' and renders the diagrams at '/render'.
+//
+// 'POST /render?format=xlsx|svg|png' takes the diagram text as the body, whose input format is detected
+// from the text, and responds the rendered file. The format defaults to xlsx.
+// The failures are responded as Error in JSON.
+func NewHandler(limits Limits) http.Handler {
+	if limits.MaxBodySize <= 0 {
+		limits.MaxBodySize = DefaultLimits.MaxBodySize
+	}
+	if limits.RenderTimeout <= 0 {
+		limits.RenderTimeout = DefaultLimits.RenderTimeout
+	}
+	if limits.MaxRenders <= 0 {
+		limits.MaxRenders = DefaultLimits.MaxRenders
+	}
+
+	h := &handler{limits: limits, renders: make(chan struct{}, limits.MaxRenders)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", h.serveEditor)
+	mux.HandleFunc("/render", h.serveRender)
+	return mux
+}
+
+func (h *handler) serveEditor(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/" {
+		http.NotFound(w, req)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, editorPage)
+}
+
+func (h *handler) serveRender(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, &Error{Message: "only POST is allowed"})
+		return
+	}
+
+	format := req.URL.Query().Get("format")
+	if format == "" {
+		format = "xlsx"
+	}
+	r, ok := renderers[format]
+	if !ok {
+		writeError(w, http.StatusBadRequest, &Error{Message: fmt.Sprintf("unknown output format '%s'", format)})
+		return
+	}
+
+	src, err := ioutil.ReadAll(io.LimitReader(req.Body, h.limits.MaxBodySize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &Error{Message: err.Error()})
+		return
+	}
+	if int64(len(src)) > h.limits.MaxBodySize {
+		writeError(w, http.StatusRequestEntityTooLarge, &Error{Message: fmt.Sprintf("the diagram exceeds %d bytes", h.limits.MaxBodySize)})
+		return
+	}
+
+	timer := time.NewTimer(h.limits.RenderTimeout)
+	defer timer.Stop()
+	select {
+	case h.renders <- struct{}{}:
+	case <-timer.C:
+		writeError(w, http.StatusServiceUnavailable, &Error{Message: "too many diagrams are being rendered"})
+		return
+	case <-req.Context().Done():
+		return
+	}
+
+	type result struct {
+		out []byte
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		// the slot is released when the rendering finishes, even if the request has timed out
+		defer func() { <-h.renders }()
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{err: fmt.Errorf("%v", p)}
+			}
+		}()
+		buf := new(bytes.Buffer)
+		err := r.render(buf, src)
+		done <- result{buf.Bytes(), err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			writeError(w, http.StatusBadRequest, newError(res.err))
+			return
+		}
+		w.Header().Set("Content-Type", r.contentType)
+		w.Write(res.out)
+	case <-timer.C:
+		writeError(w, http.StatusServiceUnavailable, &Error{Message: "rendering timed out"})
+	case <-req.Context().Done():
+	}
+}
+
+func renderXLSX(w io.Writer, src []byte) error {
+	wb, err := seq2xls.Render(src)
+	if err != nil {
+		return err
+	}
+	_, err = wb.WriteTo(w)
+	return err
+}
+
+func renderImage(draw func(io.Writer, *model.SequenceDiagram) error) func(io.Writer, []byte) error {
+	return func(w io.Writer, src []byte) error {
+		seq, err := seq2xls.Parse(seq2xls.DetectFormat(src), src)
+		if err != nil {
+			return err
+		}
+		return draw(w, seq)
+	}
+}
+
+// newError creates the error response, which has the position of the syntax error of seqdiag.
+func newError(err error) *Error {
+	e := &Error{Message: err.Error()}
+	if errs, ok := err.(seqdiag.ErrorList); ok {
+		for _, perr := range errs {
+			e.Errors = append(e.Errors, newError(perr))
+		}
+		if len(e.Errors) > 0 {
+			e.Line, e.Column = e.Errors[0].Line, e.Errors[0].Column
+		}
+	}
+	if perr, ok := err.(*gocc.Error); ok && perr.ErrorToken != nil {
+		e.Line = perr.ErrorToken.Pos.Line
+		e.Column = perr.ErrorToken.Pos.Column
+	}
+	if cerr, ok := err.(*convertor.Error); ok {
+		e.Line = cerr.Pos.Line
+		e.Column = cerr.Pos.Column
+	}
+	return e
+}
+
+func writeError(w http.ResponseWriter, status int, e *Error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(e)
+}
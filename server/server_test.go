@@ -0,0 +1,161 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rsp9u/seq2xls/seqdiag"
+	gocc "github.com/rsp9u/seq2xls/seqdiag/errors"
+	"github.com/rsp9u/seq2xls/seqdiag/token"
+)
+
+const serverTestJSON = `{
+  "version": 1,
+  "lifelines": [{"id": "a", "name": "client"}, {"id": "b", "name": "server"}],
+  "messages": [{"id": "m", "from": "a", "to": "b", "text": "GET"}]
+}`
+
+func post(h http.Handler, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) *Error {
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Unexpected content type of the error %q", ct)
+	}
+	e := &Error{}
+	if err := json.NewDecoder(rec.Body).Decode(e); err != nil {
+		t.Fatalf("Error is not a JSON: %v", err)
+	}
+	return e
+}
+
+func TestRender(t *testing.T) {
+	h := NewHandler(DefaultLimits)
+	tests := []struct {
+		target, contentType, prefix string
+	}{
+		{"/render?format=svg", "image/svg+xml", "<svg"},
+		{"/render?format=png", "image/png", "\x89PNG"},
+		{"/render", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "PK"},
+	}
+	for _, tt := range tests {
+		rec := post(h, tt.target, serverTestJSON)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: unexpected status %d: %s", tt.target, rec.Code, rec.Body)
+			continue
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+			t.Errorf("%s: unexpected content type %q", tt.target, ct)
+		}
+		if !strings.HasPrefix(rec.Body.String(), tt.prefix) {
+			t.Errorf("%s: unexpected body %q", tt.target, rec.Body.String()[:8])
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	h := NewHandler(Limits{MaxBodySize: 256})
+
+	rec := post(h, "/render?format=svg", `{"version": 1, "messages": [{"id": "m", "from": "a", "to": "b"}]}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Invalid diagram: unexpected status %d", rec.Code)
+	}
+	if e := decodeError(t, rec); e.Message == "" {
+		t.Errorf("Invalid diagram: empty error message")
+	}
+
+	rec = post(h, "/render?format=pdf", serverTestJSON)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Unknown format: unexpected status %d", rec.Code)
+	}
+	if e := decodeError(t, rec); e.Message != "unknown output format 'pdf'" {
+		t.Errorf("Unknown format: unexpected error %q", e.Message)
+	}
+
+	rec = post(h, "/render?format=svg", strings.Repeat(" ", 257))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Large body: unexpected status %d", rec.Code)
+	}
+	decodeError(t, rec)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/render", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET: unexpected status %d", rec.Code)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	syntaxError := func(line, column int) *gocc.Error {
+		return &gocc.Error{ErrorToken: &token.Token{Pos: token.Pos{Line: line, Column: column}}}
+	}
+	e := newError(seqdiag.ErrorList{syntaxError(4, 8), syntaxError(6, 10)})
+	if e.Line != 4 || e.Column != 8 || len(e.Errors) != 2 {
+		t.Fatalf("Unexpected error %+v", e)
+	}
+	if e.Errors[1].Line != 6 || e.Errors[1].Column != 10 {
+		t.Errorf("Unexpected second error %+v", e.Errors[1])
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	renderers["slow"] = renderer{"text/plain", func(w io.Writer, src []byte) error {
+		<-release
+		return nil
+	}}
+	defer delete(renderers, "slow")
+
+	h := NewHandler(Limits{RenderTimeout: 10 * time.Millisecond})
+	rec := post(h, "/render?format=slow", serverTestJSON)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected status %d", rec.Code)
+	}
+	decodeError(t, rec)
+}
+
+func TestMaxRenders(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	renderers["slow"] = renderer{"text/plain", func(w io.Writer, src []byte) error {
+		<-release
+		return nil
+	}}
+	defer delete(renderers, "slow")
+
+	h := NewHandler(Limits{RenderTimeout: 10 * time.Millisecond, MaxRenders: 1})
+	rec := post(h, "/render?format=slow", serverTestJSON)
+	if e := decodeError(t, rec); rec.Code != http.StatusServiceUnavailable || e.Message != "rendering timed out" {
+		t.Fatalf("Unexpected response of the first request %d %+v", rec.Code, e)
+	}
+
+	// the first rendering still occupies the slot after its timeout
+	rec = post(h, "/render?format=svg", serverTestJSON)
+	if e := decodeError(t, rec); rec.Code != http.StatusServiceUnavailable || e.Message != "too many diagrams are being rendered" {
+		t.Errorf("Unexpected response of the second request %d %+v", rec.Code, e)
+	}
+}
+
+func TestEditor(t *testing.T) {
+	h := NewHandler(DefaultLimits)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "render?format=") {
+		t.Errorf("Unexpected editor page %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Unexpected status %d for the missing page", rec.Code)
+	}
+}
@@ -0,0 +1,149 @@
+package seq2xls
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// charWidth and lineHeight are the estimated width of a half-width character and the height of a line
+	// of the text in the default font size in pixels.
+	charWidth       = 8
+	lineHeight      = 15
+	defaultFontSize = 11
+	// labelPaddingX and labelPaddingY are the insets of the text boxes of the labels.
+	labelPaddingX = 10
+	labelPaddingY = 5
+)
+
+// textSize returns the estimated width of the widest line and the height of the text in pixels
+// in the font size in points, or the default size if it is not positive.
+func textSize(text string, fontSize int) (w, h int) {
+	if fontSize <= 0 {
+		fontSize = defaultFontSize
+	}
+	lines := strings.Split(text, "\n")
+	cells := 0
+	for _, line := range lines {
+		if n := stringWidth(line); n > cells {
+			cells = n
+		}
+	}
+	return cells * charWidth * fontSize / defaultFontSize, len(lines) * lineHeight * fontSize / defaultFontSize
+}
+
+// WrapText breaks the lines of the text so that each line fits in the width in pixels in the font size in points,
+// estimating the widths of the characters in the same way as the layout of the diagrams.
+//
+// The lines are broken at the spaces and between the wide characters, and the words longer than the width
+// are broken anywhere. Each line keeps at least one character.
+func WrapText(text string, width, fontSize int) string {
+	if fontSize <= 0 {
+		fontSize = defaultFontSize
+	}
+	limit := width * defaultFontSize / (charWidth * fontSize)
+	if limit < 1 {
+		limit = 1
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		lines = append(lines, wrapLine(line, limit)...)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine breaks the line into the lines within limit cells.
+func wrapLine(s string, limit int) []string {
+	lines := []string{}
+	line, width, space := "", 0, ""
+	for _, word := range splitWords(s) {
+		w := stringWidth(word)
+		if strings.TrimSpace(word) == "" {
+			if line != "" {
+				space = word
+			}
+			continue
+		}
+
+		if line != "" && width+stringWidth(space)+w > limit {
+			lines = append(lines, line)
+			line, width = "", 0
+		}
+		if line != "" {
+			line += space
+			width += stringWidth(space)
+		}
+		space = ""
+
+		for line == "" && w > limit {
+			var head string
+			head, word = cutWidth(word, limit)
+			lines = append(lines, head)
+			w = stringWidth(word)
+		}
+		line += word
+		width += w
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitWords splits the text into the words, the runs of the spaces and the wide characters.
+func splitWords(s string) []string {
+	words := []string{}
+	word := ""
+	prevSpace, prevWide := false, false
+	for _, r := range s {
+		space, wide := unicode.IsSpace(r), runeWidth(r) == 2
+		if word != "" && (wide || prevWide || space != prevSpace) {
+			words = append(words, word)
+			word = ""
+		}
+		word += string(r)
+		prevSpace, prevWide = space, wide
+	}
+	if word != "" {
+		words = append(words, word)
+	}
+	return words
+}
+
+// cutWidth returns the head of the text within limit cells, which has at least one character, and the rest.
+func cutWidth(s string, limit int) (head, tail string) {
+	width := 0
+	for i, r := range s {
+		width += runeWidth(r)
+		if width > limit && i > 0 {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// stringWidth returns the number of the cells which the text occupies.
+func stringWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth returns 2 for the wide characters of East Asian and 1 for the others.
+func runeWidth(r rune) int {
+	switch {
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0xA4CF, // from CJK Radicals to Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul Syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK Compatibility Ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK Compatibility Forms
+		r >= 0xFF00 && r <= 0xFF60, // Fullwidth Forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK Unified Ideographs Extensions
+		return 2
+	}
+	return 1
+}
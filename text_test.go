@@ -0,0 +1,31 @@
+package seq2xls
+
+import "testing"
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text    string
+		width   int
+		wrapped string
+	}{
+		{"GET /users", 200, "GET /users"},
+		{"GET /users HTTP/1.1", 72, "GET\n/users\nHTTP/1.1"},
+		{"one  two\nthree", 64, "one  two\nthree"},
+		{"authenticate", 40, "authe\nntica\nte"},
+		{"ユーザー認証", 40, "ユー\nザー\n認証"},
+		{"ログイン request", 80, "ログイン\nrequest"},
+		{"", 80, ""},
+	}
+	for _, tt := range tests {
+		if wrapped := WrapText(tt.text, tt.width, 0); wrapped != tt.wrapped {
+			t.Errorf("WrapText(%q, %d) = %q, want %q", tt.text, tt.width, wrapped, tt.wrapped)
+		}
+	}
+
+	if wrapped := WrapText("GET /users", 120, 22); wrapped != "GET\n/users" {
+		t.Errorf("The font size is not taken into account: %q", wrapped)
+	}
+	if w, h := textSize("GET\n認証", 22); w != 64 || h != 60 {
+		t.Errorf("Unexpected size of the text %d x %d", w, h)
+	}
+}
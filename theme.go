@@ -0,0 +1,295 @@
+package seq2xls
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/model"
+	"gopkg.in/yaml.v2"
+)
+
+// LineStyle is the style of the lines and the borders.
+type LineStyle struct {
+	// Color is the color by rgb hex values like "000000". If it is empty, the borders of the rectangles
+	// are not drawn and the lines are drawn in black.
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+	// Width is the width in points, or zero for the default of the spreadsheet applications.
+	Width float64 `json:"width,omitempty" yaml:"width,omitempty"`
+	// Dash is the preset dash type of DrawingML such as "dash", or empty for the solid line.
+	// It is ignored for the borders of the rectangles.
+	Dash string `json:"dash,omitempty" yaml:"dash,omitempty"`
+}
+
+// FontStyle is the style of the text.
+type FontStyle struct {
+	// Family is the typeface, or empty for the default family of the diagram.
+	Family string `json:"family,omitempty" yaml:"family,omitempty"`
+	// EastAsianFamily and ComplexScriptFamily are the typefaces of the East Asian characters and
+	// the complex scripts such as Arabic and Thai. If they are empty, Family is used, or a typeface
+	// for the language of the text if Family is empty too.
+	EastAsianFamily     string `json:"eastAsianFamily,omitempty" yaml:"eastAsianFamily,omitempty"`
+	ComplexScriptFamily string `json:"complexScriptFamily,omitempty" yaml:"complexScriptFamily,omitempty"`
+	// Size is the size in points, or zero for the default.
+	Size int `json:"size,omitempty" yaml:"size,omitempty"`
+	// Color is the color by rgb hex values, or empty for the default.
+	Color string `json:"color,omitempty" yaml:"color,omitempty"`
+}
+
+// BoxStyle is the style of the rectangles with the text.
+type BoxStyle struct {
+	// Fill is the fill color by rgb hex values. The rectangles are not filled if it is empty,
+	// except that the notes and the execution specifications are filled with their own colors.
+	Fill string    `json:"fill,omitempty" yaml:"fill,omitempty"`
+	Line LineStyle `json:"line,omitempty" yaml:"line,omitempty"`
+	Font FontStyle `json:"font,omitempty" yaml:"font,omitempty"`
+}
+
+// MessageStyles is the styles of the message lines by the message type.
+type MessageStyles struct {
+	Synchronous   LineStyle `json:"synchronous,omitempty" yaml:"synchronous,omitempty"`
+	Asynchronous  LineStyle `json:"asynchronous,omitempty" yaml:"asynchronous,omitempty"`
+	Reply         LineStyle `json:"reply,omitempty" yaml:"reply,omitempty"`
+	Found         LineStyle `json:"found,omitempty" yaml:"found,omitempty"`
+	Lost          LineStyle `json:"lost,omitempty" yaml:"lost,omitempty"`
+	SelfReference LineStyle `json:"self-reference,omitempty" yaml:"self-reference,omitempty"`
+}
+
+// Of returns the style of the message type.
+func (s *MessageStyles) Of(t model.MessageType) LineStyle {
+	switch t {
+	case model.Asynchronous:
+		return s.Asynchronous
+	case model.Reply:
+		return s.Reply
+	case model.Found:
+		return s.Found
+	case model.Lost:
+		return s.Lost
+	case model.SelfReference:
+		return s.SelfReference
+	}
+	return s.Synchronous
+}
+
+// Theme is the visual style of the diagrams.
+//
+// A theme file is a YAML or JSON document of Theme. The styles which it omits are taken from
+// the built-in theme named by Base, or from the default theme.
+type Theme struct {
+	Base string `json:"base,omitempty" yaml:"base,omitempty"`
+	// Lifeline is the style of the heads of the lifelines, and LifelineLine is the style of their lines.
+	Lifeline     BoxStyle      `json:"lifeline,omitempty" yaml:"lifeline,omitempty"`
+	LifelineLine LineStyle     `json:"lifelineLine,omitempty" yaml:"lifelineLine,omitempty"`
+	Messages     MessageStyles `json:"messages,omitempty" yaml:"messages,omitempty"`
+	MessageText  FontStyle     `json:"messageText,omitempty" yaml:"messageText,omitempty"`
+	Note         BoxStyle      `json:"note,omitempty" yaml:"note,omitempty"`
+	ExecSpec     BoxStyle      `json:"execSpec,omitempty" yaml:"execSpec,omitempty"`
+	// Fragment is the style of the frames of the fragments, whose font is used for the labels and the guards.
+	Fragment BoxStyle `json:"fragment,omitempty" yaml:"fragment,omitempty"`
+	// Separator is the style of the labels of the separators, whose line is used for the double lines.
+	Separator BoxStyle `json:"separator,omitempty" yaml:"separator,omitempty"`
+	// Constraint is the style of the brackets of the duration constraints, whose font is used for their text.
+	Constraint BoxStyle `json:"constraint,omitempty" yaml:"constraint,omitempty"`
+}
+
+// blackLine is the solid black line of the default width.
+var blackLine = LineStyle{Color: "000000"}
+
+// DefaultTheme is the theme used by the diagrams without a theme.
+var DefaultTheme = Theme{
+	Lifeline:     BoxStyle{Fill: "FFFFFF", Line: blackLine},
+	LifelineLine: LineStyle{Color: "000000", Dash: "dash"},
+	Messages: MessageStyles{
+		Synchronous:   blackLine,
+		Asynchronous:  blackLine,
+		Reply:         LineStyle{Color: "000000", Dash: "dash"},
+		Found:         blackLine,
+		Lost:          blackLine,
+		SelfReference: blackLine,
+	},
+	Note:       BoxStyle{Line: blackLine},
+	ExecSpec:   BoxStyle{Line: blackLine},
+	Fragment:   BoxStyle{Line: blackLine},
+	Separator:  BoxStyle{Fill: "FFFFFF", Line: blackLine},
+	Constraint: BoxStyle{Line: blackLine},
+}
+
+// BuiltinThemes is the built-in themes by the name, which the diagrams can select.
+var BuiltinThemes = map[string]Theme{
+	"default":       DefaultTheme,
+	"monochrome":    monochromeTheme(),
+	"high-contrast": highContrastTheme(),
+}
+
+// monochromeTheme returns the theme for the printing, which has no colors but black, white and gray.
+func monochromeTheme() Theme {
+	t := DefaultTheme
+	t.Lifeline.Font.Color = "000000"
+	t.MessageText.Color = "000000"
+	t.Note = BoxStyle{Fill: "F2F2F2", Line: blackLine, Font: FontStyle{Color: "000000"}}
+	t.ExecSpec = BoxStyle{Fill: "FFFFFF", Line: blackLine}
+	t.Fragment.Font.Color = "000000"
+	t.Separator.Font.Color = "000000"
+	t.Constraint.Font.Color = "000000"
+	return t
+}
+
+// highContrastTheme returns the theme with the thick lines and the large text in the strong colors.
+func highContrastTheme() Theme {
+	thick := LineStyle{Color: "000000", Width: 2}
+	font := FontStyle{Size: 12, Color: "000000"}
+	return Theme{
+		Lifeline:     BoxStyle{Fill: "000000", Line: thick, Font: FontStyle{Size: 12, Color: "FFFFFF"}},
+		LifelineLine: LineStyle{Color: "000000", Width: 1.5, Dash: "dash"},
+		Messages: MessageStyles{
+			Synchronous:   thick,
+			Asynchronous:  thick,
+			Reply:         LineStyle{Color: "000000", Width: 2, Dash: "dash"},
+			Found:         thick,
+			Lost:          thick,
+			SelfReference: thick,
+		},
+		MessageText: font,
+		Note:        BoxStyle{Fill: "FFFF00", Line: thick, Font: font},
+		ExecSpec:    BoxStyle{Fill: "FFFFFF", Line: thick},
+		Fragment:    BoxStyle{Line: thick, Font: font},
+		Separator:   BoxStyle{Fill: "FFFFFF", Line: thick, Font: font},
+		Constraint:  BoxStyle{Line: thick, Font: font},
+	}
+}
+
+// presetDashes is the preset dash types of DrawingML.
+var presetDashes = map[string]bool{
+	"solid": true, "dot": true, "dash": true, "lgDash": true, "dashDot": true, "lgDashDot": true, "lgDashDotDot": true,
+	"sysDash": true, "sysDot": true, "sysDashDot": true, "sysDashDotDot": true,
+}
+
+// ParseTheme parses a theme file in YAML or JSON.
+// The colors may begin with '#', and an invalid color or dash type is reported with the path of its field.
+func ParseTheme(b []byte) (*Theme, error) {
+	head := struct {
+		Base string `yaml:"base"`
+	}{}
+	if err := yaml.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+	base, ok := BuiltinThemes[head.Base]
+	if !ok && head.Base != "" {
+		return nil, fmt.Errorf("unknown base theme '%s'", head.Base)
+	}
+	if !ok {
+		base = DefaultTheme
+	}
+
+	t := base
+	if err := yaml.UnmarshalStrict(b, &t); err != nil {
+		return nil, err
+	}
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// validate checks the colors and the dash types of the styles, and removes '#' from the colors.
+func (t *Theme) validate() error {
+	boxes := []struct {
+		path  string
+		style *BoxStyle
+	}{
+		{"lifeline", &t.Lifeline},
+		{"note", &t.Note},
+		{"execSpec", &t.ExecSpec},
+		{"fragment", &t.Fragment},
+		{"separator", &t.Separator},
+		{"constraint", &t.Constraint},
+	}
+	for _, box := range boxes {
+		if err := validateBox(box.path, box.style); err != nil {
+			return err
+		}
+	}
+
+	lines := []struct {
+		path  string
+		style *LineStyle
+	}{
+		{"lifelineLine", &t.LifelineLine},
+		{"messages.synchronous", &t.Messages.Synchronous},
+		{"messages.asynchronous", &t.Messages.Asynchronous},
+		{"messages.reply", &t.Messages.Reply},
+		{"messages.found", &t.Messages.Found},
+		{"messages.lost", &t.Messages.Lost},
+		{"messages.self-reference", &t.Messages.SelfReference},
+	}
+	for _, line := range lines {
+		if err := validateLine(line.path, line.style); err != nil {
+			return err
+		}
+	}
+	return validateColor("messageText.color", &t.MessageText.Color)
+}
+
+func validateBox(path string, s *BoxStyle) error {
+	if err := validateColor(path+".fill", &s.Fill); err != nil {
+		return err
+	}
+	if err := validateLine(path+".line", &s.Line); err != nil {
+		return err
+	}
+	return validateColor(path+".font.color", &s.Font.Color)
+}
+
+func validateLine(path string, s *LineStyle) error {
+	if s.Dash != "" && !presetDashes[s.Dash] {
+		return fmt.Errorf("%s.dash: unknown dash type '%s'", path, s.Dash)
+	}
+	return validateColor(path+".color", &s.Color)
+}
+
+// validateColor removes '#' from the color, and returns an error if it is not 6 hex digits.
+func validateColor(path string, c *string) error {
+	if *c == "" {
+		return nil
+	}
+	hex := strings.TrimPrefix(*c, "#")
+	valid := len(hex) == 6
+	for _, r := range hex {
+		valid = valid && strings.ContainsRune("0123456789abcdefABCDEF", r)
+	}
+	if !valid {
+		return fmt.Errorf("%s: invalid color '%s', which must be 6 hex digits", path, *c)
+	}
+	*c = hex
+	return nil
+}
+
+// LoadTheme returns the built-in theme of the name, or reads the theme file at the path.
+func LoadTheme(nameOrPath string) (*Theme, error) {
+	if t, ok := BuiltinThemes[nameOrPath]; ok {
+		return &t, nil
+	}
+	b, err := ioutil.ReadFile(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseTheme(b)
+}
+
+// SelectTheme returns the theme to draw the diagram with. The given theme takes precedence
+// over the built-in theme which the diagram selects, and DefaultTheme is used if neither is.
+func SelectTheme(seq *model.SequenceDiagram, theme *Theme) (*Theme, error) {
+	if theme != nil {
+		return theme, nil
+	}
+	if seq.Theme == "" {
+		t := DefaultTheme
+		return &t, nil
+	}
+	t, ok := BuiltinThemes[seq.Theme]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme '%s'", seq.Theme)
+	}
+	return &t, nil
+}
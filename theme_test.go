@@ -0,0 +1,116 @@
+package seq2xls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+func TestParseTheme(t *testing.T) {
+	src := `
+base: monochrome
+messages:
+  reply:
+    color: "FF0000"
+note:
+  font:
+    family: Meiryo
+`
+	theme, err := ParseTheme([]byte(src))
+	if err != nil {
+		t.Fatalf("ParseTheme error %v", err)
+	}
+	if reply := theme.Messages.Of(model.Reply); reply.Color != "FF0000" || reply.Dash != "dash" {
+		t.Errorf("Unexpected style of the replies %+v", reply)
+	}
+	if theme.Note.Fill != "F2F2F2" || theme.Note.Font.Family != "Meiryo" {
+		t.Errorf("Unexpected style of the notes %+v", theme.Note)
+	}
+
+	theme, err = ParseTheme([]byte(`{"lifeline": {"fill": "ADD8E6"}}`))
+	if err != nil {
+		t.Fatalf("ParseTheme error %v", err)
+	}
+	if theme.Lifeline.Fill != "ADD8E6" || theme.Lifeline.Line.Color != "000000" {
+		t.Errorf("Unexpected style of the lifelines %+v", theme.Lifeline)
+	}
+
+	for _, src := range []string{"base: sepia", "lifeline: {fil: red}"} {
+		if _, err := ParseTheme([]byte(src)); err == nil {
+			t.Errorf("No error for %q", src)
+		}
+	}
+
+	theme, err = ParseTheme([]byte(`note: {fill: "#fff2cc"}`))
+	if err != nil {
+		t.Fatalf("ParseTheme error %v", err)
+	}
+	if theme.Note.Fill != "fff2cc" {
+		t.Errorf("Unexpected fill of the notes %s", theme.Note.Fill)
+	}
+
+	tests := []struct {
+		src, err string
+	}{
+		{`lifeline: {fill: red}`, "lifeline.fill: invalid color 'red', which must be 6 hex digits"},
+		{`note: {font: {color: "#12345"}}`, "note.font.color: invalid color '#12345', which must be 6 hex digits"},
+		{`messages: {self-reference: {dash: dotted}}`, "messages.self-reference.dash: unknown dash type 'dotted'"},
+		{`fragment: {line: {color: "00000G"}}`, "fragment.line.color: invalid color '00000G', which must be 6 hex digits"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTheme([]byte(tt.src))
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("Mismatches error of %s: expect %q, actual %v", tt.src, tt.err, err)
+		}
+	}
+}
+
+func TestSelectTheme(t *testing.T) {
+	seq := &model.SequenceDiagram{Theme: "high-contrast"}
+	theme, err := SelectTheme(seq, nil)
+	if err != nil || theme.Lifeline.Fill != "000000" {
+		t.Errorf("The theme of the diagram is not selected: %+v, %v", theme, err)
+	}
+
+	mono, _ := LoadTheme("monochrome")
+	if theme, _ := SelectTheme(seq, mono); theme != mono {
+		t.Errorf("The given theme does not take precedence")
+	}
+
+	seq.Theme = "sepia"
+	if _, err := SelectTheme(seq, nil); err == nil {
+		t.Errorf("No error for the unknown theme")
+	}
+}
+
+func TestRenderWithTheme(t *testing.T) {
+	src := strings.Replace(convertTestJSON, `"version": 1,`, `"version": 1, "theme": "high-contrast",`, 1)
+	anchorOf := func(src string, opts ...Option) string {
+		wb, err := Render([]byte(src), opts...)
+		if err != nil {
+			t.Fatalf("Render error %v", err)
+		}
+		for _, s := range wb.Shapes() {
+			anchor, _ := xlsx.MarshalAnchor(s)
+			if anchor.Descr == ShapeDescrPrefix+"message-0" {
+				return string(anchor.XML)
+			}
+		}
+		t.Fatalf("message-0 is not drawn")
+		return ""
+	}
+
+	if !strings.Contains(anchorOf(src), `<a:ln w="25400">`) {
+		t.Errorf("The theme of the diagram is not applied")
+	}
+	if anchorOf(src, WithTheme(&DefaultTheme)) != anchorOf(convertTestJSON) {
+		t.Errorf("The given theme does not take precedence")
+	}
+
+	src = strings.Replace(convertTestJSON, `"version": 1,`, `"version": 1, "theme": "sepia",`, 1)
+	if _, err := Render([]byte(src)); err == nil {
+		t.Errorf("No error for the unknown theme")
+	}
+}
@@ -0,0 +1,194 @@
+package seq2xls
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// shapeRecorder is a canvas which only records the added shapes, and gives the hyperlinks
+// of them the relationships of the drawing.
+type shapeRecorder struct {
+	shapes []shape.Shape
+	links  *xlsx.DrawingLinks
+}
+
+func (r *shapeRecorder) AddShape(s shape.Shape) {
+	r.links.AddLink(s)
+	r.shapes = append(r.shapes, s)
+}
+
+func (r *shapeRecorder) UnshiftShape(s shape.Shape) {
+	r.links.AddLink(s)
+	r.shapes = append([]shape.Shape{s}, r.shapes...)
+}
+
+// UpdateWorkbook regenerates the diagram into the existing workbook generated by seq2xls.
+//
+// The shapes of the changed elements are replaced in place, the shapes of the new
+// elements are added and the shapes of the deleted elements are removed.
+// The shapes of the unchanged elements and the shapes not drawn by seq2xls are
+// left as they are, so that the manual edits in the workbook are preserved.
+// An element is regarded as changed when it is not drawn identically to the last time.
+// The layout and the theme are taken from the options, and the others are ignored.
+//
+// The diagram is drawn in the first worksheet. The elements are identified by their kinds and
+// their indices, so inserting an element changes all the elements of the kind after it as well as
+// the ones moved by it, and the manual edits of their shapes are lost.
+func UpdateWorkbook(filename string, src []byte, seq *model.SequenceDiagram, opts ...Option) error {
+	o := newOptions(opts)
+	theme, err := SelectTheme(seq, o.theme)
+	if err != nil {
+		return err
+	}
+
+	arc, err := xlsx.OpenArchive(filename)
+	if err != nil {
+		return err
+	}
+
+	sheet, drawingPath, err := arc.SheetDrawing(0)
+	if err != nil {
+		return err
+	}
+	b, ok := arc.Part(drawingPath)
+	if !ok {
+		return fmt.Errorf("%s is not found in the workbook", drawingPath)
+	}
+	drawing, err := xlsx.ParseRawDrawing(b)
+	if err != nil {
+		return err
+	}
+
+	oldDigests := map[string]string{}
+	metaPath, meta := findMetadata(arc, sheet)
+	if meta != nil {
+		for _, elem := range meta.Elements {
+			oldDigests[elem.ID] = elem.Digest
+		}
+	}
+
+	links, err := arc.DrawingLinks(drawingPath)
+	if err != nil {
+		return err
+	}
+	rec := &shapeRecorder{links: links}
+	DrawSequenceDiagramWithTheme(rec, seq, o.layout, theme)
+	anchors, err := marshalAnchors(rec.shapes)
+	if err != nil {
+		return err
+	}
+	part, err := newMetadataPart(src, seq, anchors)
+	if err != nil {
+		return err
+	}
+	newDigests := map[string]string{}
+	for _, elem := range part.Elements {
+		newDigests[elem.ID] = elem.Digest
+	}
+
+	drawing.Anchors = mergeAnchors(drawing.Anchors, anchors, oldDigests, newDigests)
+	arc.SetPart(drawingPath, drawing.Bytes())
+	arc.SetDrawingLinks(links)
+
+	if metaPath != "" {
+		arc.SetPart(metaPath, []byte(part.Content()))
+	} else {
+		_, err = arc.AddCustomXML([]byte(part.Content()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return arc.Save(filename)
+}
+
+// findMetadata finds the custom xml part embedded by seq2xls for the worksheet.
+// The part without the name of the worksheet is taken for any worksheet.
+func findMetadata(arc *xlsx.Archive, sheet string) (string, *metadataPart) {
+	for _, name := range arc.List() {
+		if !strings.HasPrefix(name, "customXml/item") || !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		b, _ := arc.Part(name)
+		meta := &metadataPart{}
+		if err := xml.Unmarshal(b, meta); err != nil {
+			continue
+		}
+		if meta.XMLName.Space == metadataNamespace && (meta.Sheet == "" || meta.Sheet == sheet) {
+			return name, meta
+		}
+	}
+	return "", nil
+}
+
+// mergeAnchors merges the newly drawn anchors into the existing anchors.
+//
+// The anchors of a changed element are replaced one by one if the number of them
+// is not changed, otherwise all of them are put at the first one's place.
+// The anchors of a new element are put just after the anchors of the element
+// which precedes it in the new drawing.
+func mergeAnchors(olds, news []*xlsx.RawAnchor, oldDigests, newDigests map[string]string) []*xlsx.RawAnchor {
+	newsByID := map[string][]*xlsx.RawAnchor{}
+	for _, anchor := range news {
+		id := elementID(anchor)
+		newsByID[id] = append(newsByID[id], anchor)
+	}
+	oldCounts := map[string]int{}
+	for _, anchor := range olds {
+		oldCounts[elementID(anchor)]++
+	}
+
+	merged := []*xlsx.RawAnchor{}
+	placed := map[string]int{}
+	for _, anchor := range olds {
+		id := elementID(anchor)
+		replacements, ok := newsByID[id]
+		switch {
+		case id == "":
+			merged = append(merged, anchor)
+		case !ok:
+			// deleted element
+		case oldDigests[id] != "" && oldDigests[id] == newDigests[id]:
+			merged = append(merged, anchor)
+			placed[id] = len(replacements)
+		case oldCounts[id] == len(replacements):
+			merged = append(merged, replacements[placed[id]])
+			placed[id]++
+		case placed[id] == 0:
+			merged = append(merged, replacements...)
+			placed[id] = len(replacements)
+		}
+	}
+
+	for i, anchor := range news {
+		id := elementID(anchor)
+		if oldCounts[id] != 0 {
+			continue
+		}
+
+		pos := 0
+		for j := i - 1; j >= 0; j-- {
+			if k := lastIndexOfElement(merged, elementID(news[j])); k >= 0 {
+				pos = k + 1
+				break
+			}
+		}
+		merged = append(merged[:pos], append([]*xlsx.RawAnchor{anchor}, merged[pos:]...)...)
+	}
+
+	return merged
+}
+
+func lastIndexOfElement(anchors []*xlsx.RawAnchor, id string) int {
+	for i := len(anchors) - 1; i >= 0; i-- {
+		if elementID(anchors[i]) == id {
+			return i
+		}
+	}
+	return -1
+}
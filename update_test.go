@@ -0,0 +1,187 @@
+package seq2xls
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+const drawingPath = "xl/drawings/drawing1.xml"
+
+const foreignAnchor = `<xdr:twoCellAnchor><xdr:from><xdr:col>0</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>0</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:from><xdr:to><xdr:col>1</xdr:col><xdr:colOff>0</xdr:colOff><xdr:row>1</xdr:row><xdr:rowOff>0</xdr:rowOff></xdr:to><xdr:sp><xdr:nvSpPr><xdr:cNvPr id="100" name="Callout"></xdr:cNvPr><xdr:cNvSpPr></xdr:cNvSpPr></xdr:nvSpPr></xdr:sp><xdr:clientData></xdr:clientData></xdr:twoCellAnchor>`
+
+func newUpdateTestDiagram(labels ...string) *model.SequenceDiagram {
+	foo := &model.Lifeline{Name: "foo", Index: 0, ColorHex: "FFFFFF"}
+	bar := &model.Lifeline{Name: "bar", Index: 1, ColorHex: "FFFFFF"}
+	seq := &model.SequenceDiagram{Lifelines: []*model.Lifeline{foo, bar}}
+	for i, label := range labels {
+		seq.Messages = append(seq.Messages, &model.Message{Index: i, From: foo, To: bar, Text: label})
+	}
+	return seq
+}
+
+func readAnchors(t *testing.T, path string) []*xlsx.RawAnchor {
+	arc, err := xlsx.OpenArchive(path)
+	if err != nil {
+		t.Fatalf("Open error %v", err)
+	}
+	b, _ := arc.Part(drawingPath)
+	d, err := xlsx.ParseRawDrawing(b)
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	return d.Anchors
+}
+
+func anchorsOf(anchors []*xlsx.RawAnchor, id string) []*xlsx.RawAnchor {
+	ret := []*xlsx.RawAnchor{}
+	for _, anchor := range anchors {
+		if elementID(anchor) == id {
+			ret = append(ret, anchor)
+		}
+	}
+	return ret
+}
+
+func TestUpdateWorkbook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seq2xls")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.xlsx")
+
+	seq := newUpdateTestDiagram("one", "two", "three")
+	wb := xlsx.NewWorkbook()
+	DrawSequenceDiagram(wb, seq)
+	if err := EmbedMetadata(wb, []byte("src"), seq); err != nil {
+		t.Fatalf("Embed error %v", err)
+	}
+	if err := wb.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	// simulate manual edits: move the label of the first message and add a callout
+	arc, _ := xlsx.OpenArchive(path)
+	b, _ := arc.Part(drawingPath)
+	d, _ := xlsx.ParseRawDrawing(b)
+	moved := anchorsOf(d.Anchors, "message-0")[1]
+	moved.XML = bytes.Replace(moved.XML, []byte("<xdr:row>"), []byte("<xdr:row>1"), 1)
+	d.Anchors = append(d.Anchors, &xlsx.RawAnchor{XML: []byte(foreignAnchor)})
+	arc.SetPart(drawingPath, d.Bytes())
+	if err := arc.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	// change the second message, and keep the others
+	if err := UpdateWorkbook(path, []byte("src"), newUpdateTestDiagram("one", "TWO", "three")); err != nil {
+		t.Fatalf("Update error %v", err)
+	}
+	anchors := readAnchors(t, path)
+	if len(anchors) != len(d.Anchors) {
+		t.Fatalf("Too many or few anchors %d", len(anchors))
+	}
+	if !bytes.Equal(anchorsOf(anchors, "message-0")[1].XML, moved.XML) {
+		t.Fatalf("Manually moved shape is not preserved")
+	}
+	if !bytes.Contains(anchorsOf(anchors, "message-1")[1].XML, []byte("TWO")) {
+		t.Fatalf("Changed shape is not updated")
+	}
+	if !bytes.Equal(anchors[len(anchors)-1].XML, []byte(foreignAnchor)) {
+		t.Fatalf("Shape not drawn by seq2xls is not preserved")
+	}
+
+	// delete the last message
+	if err := UpdateWorkbook(path, []byte("src"), newUpdateTestDiagram("one", "TWO")); err != nil {
+		t.Fatalf("Update error %v", err)
+	}
+	anchors = readAnchors(t, path)
+	if len(anchorsOf(anchors, "message-2")) != 0 {
+		t.Fatalf("Deleted shape is not removed")
+	}
+	if !bytes.Equal(anchorsOf(anchors, "message-0")[1].XML, moved.XML) {
+		t.Fatalf("Manually moved shape is not preserved")
+	}
+
+	// add a new message
+	if err := UpdateWorkbook(path, []byte("src"), newUpdateTestDiagram("one", "TWO", "four")); err != nil {
+		t.Fatalf("Update error %v", err)
+	}
+	anchors = readAnchors(t, path)
+	added := anchorsOf(anchors, "message-2")
+	if len(added) != 2 || !bytes.Contains(added[1].XML, []byte("four")) {
+		t.Fatalf("New shape is not added")
+	}
+}
+
+func TestUpdateWorkbookRef(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seq2xls")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.xlsx")
+
+	withRef := func(link string) *model.SequenceDiagram {
+		seq := newUpdateTestDiagram("one")
+		seq.Fragments = []*model.Fragment{{Type: model.Ref, Text: "Login", Link: link}}
+		return seq
+	}
+	seq := withRef("login.xlsx")
+	wb := xlsx.NewWorkbook()
+	DrawSequenceDiagram(wb, seq)
+	if err := EmbedMetadata(wb, []byte("src"), seq); err != nil {
+		t.Fatalf("Embed error %v", err)
+	}
+	if err := wb.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	const relsPath = "xl/drawings/_rels/drawing1.xml.rels"
+	readRels := func() string {
+		arc, err := xlsx.OpenArchive(path)
+		if err != nil {
+			t.Fatalf("Open error %v", err)
+		}
+		b, _ := arc.Part(relsPath)
+		return string(b)
+	}
+
+	// simulate a picture inserted by hand
+	image := `<Relationship Id="rId9" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/image" Target="../media/image1.png"></Relationship>`
+	arc, _ := xlsx.OpenArchive(path)
+	arc.SetPart(relsPath, []byte(strings.Replace(readRels(), "</Relationships>", image+"</Relationships>", 1)))
+	if err := arc.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+	before := anchorsOf(readAnchors(t, path), "fragment-0")
+
+	// the unchanged reference keeps its shapes and link
+	if err := UpdateWorkbook(path, []byte("src"), withRef("login.xlsx")); err != nil {
+		t.Fatalf("Update error %v", err)
+	}
+	after := anchorsOf(readAnchors(t, path), "fragment-0")
+	if len(after) != len(before) || !bytes.Equal(after[0].XML, before[0].XML) {
+		t.Fatalf("Unchanged reference is redrawn")
+	}
+
+	// the changed reference links to the new target, and the old link is removed
+	if err := UpdateWorkbook(path, []byte("src"), withRef("signin.xlsx")); err != nil {
+		t.Fatalf("Update error %v", err)
+	}
+	rels := readRels()
+	if !strings.Contains(rels, `Target="signin.xlsx" TargetMode="External"`) || strings.Contains(rels, "login.xlsx") ||
+		!strings.Contains(rels, image) {
+		t.Fatalf("Wrong relationships of the links\n%s", rels)
+	}
+	frame := anchorsOf(readAnchors(t, path), "fragment-0")[0]
+	if !bytes.Contains(frame.XML, []byte("hlinkClick")) {
+		t.Fatalf("Reference has no link\n%s", frame.XML)
+	}
+}
@@ -0,0 +1,121 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Watcher polls the files and reports the changed ones.
+//
+// It depends on no OS-specific notification API, but compares the modification
+// times and the sizes of the files every interval.
+type Watcher struct {
+	// Interval is the interval of the polling.
+	Interval time.Duration
+	// Debounce is the time to wait for a changed file to stop changing, so that
+	// the file saved several times in a short time is reported once.
+	Debounce time.Duration
+
+	paths   []string
+	match   func(path string) bool
+	files   map[string]fileState
+	pending map[string]time.Time
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// New creates a watcher of the given files and directories.
+// The files in the directories are watched recursively if match returns true for them.
+func New(paths []string, match func(path string) bool) *Watcher {
+	return &Watcher{
+		Interval: 500 * time.Millisecond,
+		Debounce: 300 * time.Millisecond,
+		paths:    paths,
+		match:    match,
+		files:    map[string]fileState{},
+		pending:  map[string]time.Time{},
+	}
+}
+
+// Files returns the files being watched currently in the lexical order.
+func (w *Watcher) Files() []string {
+	files := []string{}
+	for path := range w.scan() {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Run polls the files until stop is closed, and calls fn with the files which are created or modified.
+// The files which exist at the beginning are not reported.
+func (w *Watcher) Run(stop <-chan struct{}, fn func(paths []string)) {
+	w.files = w.scan()
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if changed := w.poll(now); len(changed) > 0 {
+				fn(changed)
+			}
+		}
+	}
+}
+
+// poll compares the files with the previous ones, and returns the changed files
+// which have not changed for the debounce time.
+func (w *Watcher) poll(now time.Time) []string {
+	files := w.scan()
+	for path, st := range files {
+		if prev, ok := w.files[path]; !ok || !prev.modTime.Equal(st.modTime) || prev.size != st.size {
+			w.pending[path] = now
+		}
+	}
+	w.files = files
+
+	changed := []string{}
+	for path, t := range w.pending {
+		if _, ok := files[path]; !ok {
+			// removed while waiting
+			delete(w.pending, path)
+			continue
+		}
+		if now.Sub(t) >= w.Debounce {
+			changed = append(changed, path)
+			delete(w.pending, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// scan returns the states of the watched files.
+func (w *Watcher) scan() map[string]fileState {
+	files := map[string]fileState{}
+	for _, root := range w.paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			files[root] = fileState{info.ModTime(), info.Size()}
+			continue
+		}
+		filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || !w.match(path) {
+				return nil
+			}
+			files[path] = fileState{info.ModTime(), info.Size()}
+			return nil
+		})
+	}
+	return files
+}
@@ -0,0 +1,59 @@
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "watch")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	diag := filepath.Join(dir, "a.diag")
+	sub := filepath.Join(dir, "sub", "b.diag")
+	os.MkdirAll(filepath.Dir(sub), 0755)
+	ioutil.WriteFile(diag, []byte("seqdiag {}"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a.xlsx"), nil, 0644)
+
+	w := New([]string{dir}, func(path string) bool { return strings.HasSuffix(path, ".diag") })
+	if files := w.Files(); !reflect.DeepEqual(files, []string{diag}) {
+		t.Fatalf("Unexpected watched files %v", files)
+	}
+	w.files = w.scan()
+
+	now := time.Now()
+	if changed := w.poll(now); len(changed) != 0 {
+		t.Errorf("Unchanged files are reported %v", changed)
+	}
+
+	// saved twice within the debounce time
+	os.Chtimes(diag, now, now.Add(time.Second))
+	ioutil.WriteFile(sub, []byte("seqdiag {}"), 0644)
+	if changed := w.poll(now); len(changed) != 0 {
+		t.Errorf("Changed files are reported before the debounce time %v", changed)
+	}
+	os.Chtimes(diag, now, now.Add(2*time.Second))
+	if changed := w.poll(now.Add(w.Debounce / 2)); len(changed) != 0 {
+		t.Errorf("Changed files are reported before the debounce time %v", changed)
+	}
+
+	changed := w.poll(now.Add(w.Debounce))
+	if !reflect.DeepEqual(changed, []string{sub}) {
+		t.Errorf("Unexpected changed files %v", changed)
+	}
+	changed = w.poll(now.Add(w.Debounce * 3 / 2))
+	if !reflect.DeepEqual(changed, []string{diag}) {
+		t.Errorf("Unexpected changed files %v", changed)
+	}
+	if changed := w.poll(now.Add(w.Debounce * 2)); len(changed) != 0 {
+		t.Errorf("Files are reported twice %v", changed)
+	}
+}
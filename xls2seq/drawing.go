@@ -0,0 +1,51 @@
+package xls2seq
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// readDrawing reads the shapes of the drawing of the worksheet named sheet in the xlsx archive,
+// which is compared regardless of the case as the spreadsheet applications do.
+// If sheet is empty, the workbook has to have only one worksheet, whose drawing is read.
+func readDrawing(r io.ReaderAt, size int64, sheet string) ([]*xlsx.DrawnShape, error) {
+	arc, err := xlsx.ReadArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	names, err := arc.SheetNames()
+	if err != nil {
+		return nil, err
+	}
+
+	index := -1
+	switch {
+	case sheet != "":
+		for i, name := range names {
+			if strings.EqualFold(name, sheet) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("worksheet '%s' is not found in the workbook", sheet)
+		}
+	case len(names) > 1:
+		return nil, fmt.Errorf("the workbook has %d worksheets, so the one to read has to be named", len(names))
+	default:
+		index = 0
+	}
+
+	_, drawingPath, err := arc.SheetDrawing(index)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := arc.Part(drawingPath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not found in the workbook", drawingPath)
+	}
+	return xlsx.ParseDrawnShapes(b)
+}
@@ -0,0 +1,548 @@
+package xls2seq
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+// tolerance is the allowable error in pixels when comparing positions.
+const tolerance = 2
+
+// execSpecWidth is the width of the execution specifications, which are skipped as they cannot be restored.
+const execSpecWidth = 12
+
+type placedMessage struct {
+	body *model.Message
+	y    int
+	// left and right are the horizontal extent of the arrow, or of the loop of the self-reference.
+	left, right int
+}
+
+// ReadFile reads the xlsx file generated by seq2xls and reconstructs the diagram model.
+// The workbook has to have only one worksheet.
+func ReadFile(path string) (*model.SequenceDiagram, error) {
+	return ReadFileSheet(path, "")
+}
+
+// ReadFileSheet reads the worksheet named sheet in the xlsx file generated by seq2xls, such as
+// one of the diagrams of a Markdown document, and reconstructs the diagram model.
+// If sheet is empty, it is the same as ReadFile.
+func ReadFileSheet(path, sheet string) (*model.SequenceDiagram, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadSheet(f, st.Size(), sheet)
+}
+
+// Read reads the xlsx contents generated by seq2xls and reconstructs the diagram model.
+// The workbook has to have only one worksheet.
+//
+// The elements are recognized by the geometry of the shapes, so the shapes
+// which are not drawn by seq2xls are ignored as far as possible.
+func Read(r io.ReaderAt, size int64) (*model.SequenceDiagram, error) {
+	return ReadSheet(r, size, "")
+}
+
+// ReadSheet reads the worksheet named sheet in the xlsx contents generated by seq2xls
+// and reconstructs the diagram model. If sheet is empty, it is the same as Read.
+func ReadSheet(r io.ReaderAt, size int64, sheet string) (*model.SequenceDiagram, error) {
+	shapes, err := readDrawing(r, size, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return reconstruct(shapes)
+}
+
+func reconstruct(shapes []*xlsx.DrawnShape) (*model.SequenceDiagram, error) {
+	seq := &model.SequenceDiagram{
+		Messages:   []*model.Message{},
+		Fragments:  []*model.Fragment{},
+		Notes:      []*model.Note{},
+		Separators: []*model.Separator{},
+	}
+	used := map[*xlsx.DrawnShape]bool{}
+
+	lls, heads := findLifelines(shapes, used)
+	if len(lls) == 0 {
+		return nil, fmt.Errorf("no lifeline is found in the drawing")
+	}
+	seq.Lifelines = lls
+	centers := map[*model.Lifeline]int{}
+	for i, ll := range lls {
+		centers[ll] = (heads[i].X1 + heads[i].X2) / 2
+	}
+	left, right := heads[0].X1, heads[len(heads)-1].X2
+
+	msgs := findMessages(shapes, seq.Lifelines, centers, used)
+	for _, msg := range msgs {
+		seq.Messages = append(seq.Messages, msg.body)
+	}
+
+	seq.Separators = findSeparators(shapes, msgs, left, right, centers[lls[0]], used)
+	refs := findRefs(shapes, msgs, lls, centers, used)
+	seq.Constraints = findConstraints(shapes, msgs, right, used)
+	findLabels(shapes, msgs, used)
+	seq.Fragments = findFragments(shapes, msgs, used)
+	for _, ref := range refs {
+		ref.Index = len(seq.Fragments)
+		seq.Fragments = append(seq.Fragments, ref)
+	}
+	seq.Notes = findNotes(shapes, msgs, centers, used)
+
+	return seq, nil
+}
+
+// findLifelines finds the lifeline heads, which are the rectangles with a dashed line below their center.
+func findLifelines(shapes []*xlsx.DrawnShape, used map[*xlsx.DrawnShape]bool) ([]*model.Lifeline, []*xlsx.DrawnShape) {
+	heads := []*xlsx.DrawnShape{}
+	for _, rect := range shapes {
+		if rect.IsLine || !rect.Filled || !rect.Lined {
+			continue
+		}
+		c := (rect.X1 + rect.X2) / 2
+		for _, line := range shapes {
+			if line.IsVertical() && line.DashType != "" && near(line.X1, c) && near(line.Y1, rect.Y2) {
+				heads = append(heads, rect)
+				used[rect] = true
+				used[line] = true
+				break
+			}
+		}
+	}
+	sort.SliceStable(heads, func(i, j int) bool { return heads[i].X1 < heads[j].X1 })
+
+	lls := []*model.Lifeline{}
+	for i, head := range heads {
+		lls = append(lls, &model.Lifeline{Name: head.Text, Index: i, ColorHex: head.FillColor})
+	}
+	return lls, heads
+}
+
+// findMessages finds the arrows between the lifelines and orders them from top to bottom.
+//
+// A self-reference message is recognized by its returning arrow, whose start is
+// connected to the lifeline with a horizontal and a vertical line.
+func findMessages(shapes []*xlsx.DrawnShape, lls []*model.Lifeline, centers map[*model.Lifeline]int, used map[*xlsx.DrawnShape]bool) []*placedMessage {
+	msgs := []*placedMessage{}
+	for _, line := range shapes {
+		if !line.IsHorizontal() || line.TailType == "" || used[line] {
+			continue
+		}
+		to := lifelineAt(lls, centers, line.X2)
+		if to == nil {
+			continue
+		}
+
+		from := lifelineAt(lls, centers, line.X1)
+		if from != nil && from != to {
+			left, right := line.X1, line.X2
+			if left > right {
+				left, right = right, left
+			}
+			msgs = append(msgs, &placedMessage{
+				body:  &model.Message{From: from, To: to, Type: messageTypeOf(line), ColorHex: "000000"},
+				y:     line.Y1,
+				left:  left,
+				right: right,
+			})
+			used[line] = true
+			continue
+		}
+
+		for _, vline := range shapes {
+			if !vline.IsVertical() || !near(vline.X1, line.X1) || !near(vline.Y2, line.Y1) {
+				continue
+			}
+			for _, hline := range shapes {
+				if hline.IsHorizontal() && near(hline.X1, line.X2) && near(hline.X2, line.X1) && near(hline.Y1, vline.Y1) {
+					msgs = append(msgs, &placedMessage{
+						body:  &model.Message{From: to, To: to, Type: model.SelfReference, ColorHex: "000000"},
+						y:     hline.Y1,
+						left:  line.X2,
+						right: line.X1,
+					})
+					used[line] = true
+					used[vline] = true
+					used[hline] = true
+					break
+				}
+			}
+			break
+		}
+	}
+
+	sort.SliceStable(msgs, func(i, j int) bool { return msgs[i].y < msgs[j].y })
+	for i, msg := range msgs {
+		msg.body.Index = i
+	}
+	return msgs
+}
+
+func messageTypeOf(line *xlsx.DrawnShape) model.MessageType {
+	switch {
+	case line.TailType == "arrow" && line.DashType != "":
+		return model.Reply
+	case line.TailType == "arrow":
+		return model.Asynchronous
+	default:
+		return model.Synchronous
+	}
+}
+
+// findSeparators finds the pairs of the parallel lines across all lifelines and the text on them,
+// and the delays, which are the dotted parts of the lifelines and the text in them.
+//
+// The left and the right are the edges of the most left and the most right lifeline heads,
+// and x is the center of the most left one.
+func findSeparators(shapes []*xlsx.DrawnShape, msgs []*placedMessage, left, right, x int, used map[*xlsx.DrawnShape]bool) []*model.Separator {
+	type placedSeparator struct {
+		body *model.Separator
+		y    int
+	}
+	seps := []*placedSeparator{}
+	before := func(y int) *model.Message {
+		var ret *model.Message
+		for _, msg := range msgs {
+			if msg.y < y {
+				ret = msg.body
+			}
+		}
+		return ret
+	}
+
+	for _, line1 := range shapes {
+		if !isSeparatorLine(line1, left, right) || used[line1] {
+			continue
+		}
+		for _, line2 := range shapes {
+			if line2 == line1 || !isSeparatorLine(line2, left, right) || used[line2] {
+				continue
+			}
+			if line2.Y1 <= line1.Y1 || line2.Y1-line1.Y1 > xlsx.CellSize {
+				continue
+			}
+
+			sep := &model.Separator{}
+			for _, rect := range shapes {
+				c := (rect.Y1 + rect.Y2) / 2
+				if !rect.IsLine && rect.Filled && !used[rect] && line1.Y1 <= c && c <= line2.Y1 {
+					sep.Text = rect.Text
+					used[rect] = true
+					break
+				}
+			}
+			sep.Before = before(line1.Y1)
+			seps = append(seps, &placedSeparator{sep, line1.Y1})
+			used[line1] = true
+			used[line2] = true
+			break
+		}
+	}
+
+	for _, gap := range shapes {
+		if !gap.IsVertical() || gap.DashType != "dot" || !near(gap.X1, x) || used[gap] {
+			continue
+		}
+		sep := &model.Separator{Type: model.Delay}
+		for _, rect := range shapes {
+			c := (rect.Y1 + rect.Y2) / 2
+			if !rect.IsLine && !rect.Filled && !rect.Lined && !used[rect] && near(rect.X1, left) && gap.Y1 <= c && c <= gap.Y2 {
+				sep.Text = rect.Text
+				used[rect] = true
+				break
+			}
+		}
+		sep.Before = before(gap.Y1)
+		seps = append(seps, &placedSeparator{sep, gap.Y1})
+		used[gap] = true
+	}
+
+	sort.SliceStable(seps, func(i, j int) bool { return seps[i].y < seps[j].y })
+	ret := []*model.Separator{}
+	for i, sep := range seps {
+		sep.body.Index = i
+		ret = append(ret, sep.body)
+	}
+	return ret
+}
+
+func isSeparatorLine(line *xlsx.DrawnShape, left, right int) bool {
+	return line.IsHorizontal() && line.TailType == "" && line.HeadType == "" &&
+		line.X1 <= left+tolerance && line.X2 >= right-tolerance
+}
+
+// findConstraints finds the brackets of the duration constraints, which are the vertical lines with
+// arrowheads at both ends on the right of the lifelines, and the text beside them.
+//
+// The bracket begins at the line of a message, and ends at the last message above its bottom,
+// so that it can end at the returning arrow of a self-reference.
+func findConstraints(shapes []*xlsx.DrawnShape, msgs []*placedMessage, right int, used map[*xlsx.DrawnShape]bool) []*model.Constraint {
+	constraints := []*model.Constraint{}
+	for _, bracket := range shapes {
+		if !bracket.IsVertical() || bracket.HeadType == "" || bracket.TailType == "" || bracket.X1 <= right || used[bracket] {
+			continue
+		}
+
+		c := &model.Constraint{}
+		for _, msg := range msgs {
+			if c.Begin == nil && near(msg.y, bracket.Y1) {
+				c.Begin = msg.body
+			}
+			if c.Begin != nil && msg.y <= bracket.Y2+tolerance {
+				c.End = msg.body
+			}
+		}
+		if c.Begin == nil {
+			continue
+		}
+		used[bracket] = true
+
+		for _, s := range shapes {
+			switch {
+			case used[s]:
+			case s.IsHorizontal() && s.X1 <= bracket.X1 && bracket.X1 <= s.X2 && (near(s.Y1, bracket.Y1) || near(s.Y1, bracket.Y2)):
+				// the ticks at the ends
+				used[s] = true
+			case !s.IsLine && !s.Filled && !s.Lined && near(s.X1, bracket.X1) && bracket.Y1 <= (s.Y1+s.Y2)/2 && (s.Y1+s.Y2)/2 <= bracket.Y2:
+				if c.Text == "" {
+					c.Text = s.Text
+					used[s] = true
+				}
+			}
+		}
+
+		c.Index = len(constraints)
+		constraints = append(constraints, c)
+	}
+	return constraints
+}
+
+// findRefs finds the frames of the references, which are the framed rectangles labeled "ref",
+// and the names in them. The references cover the lifelines whose centers are in the frames.
+// The links of the references are not restored.
+func findRefs(shapes []*xlsx.DrawnShape, msgs []*placedMessage, lls []*model.Lifeline, centers map[*model.Lifeline]int, used map[*xlsx.DrawnShape]bool) []*model.Fragment {
+	frames := []*xlsx.DrawnShape{}
+	for _, rect := range shapes {
+		if !rect.IsLine && rect.Lined && rect.Text == model.Ref.String() && !used[rect] {
+			frames = append(frames, rect)
+		}
+	}
+	sort.SliceStable(frames, func(i, j int) bool { return frames[i].Y1 < frames[j].Y1 })
+
+	refs := []*model.Fragment{}
+	for _, frame := range frames {
+		ref := &model.Fragment{Type: model.Ref, Lifelines: []*model.Lifeline{}}
+		for _, ll := range lls {
+			if frame.X1 <= centers[ll] && centers[ll] <= frame.X2 {
+				ref.Lifelines = append(ref.Lifelines, ll)
+			}
+		}
+		if len(ref.Lifelines) == len(lls) {
+			ref.Lifelines = []*model.Lifeline{}
+		}
+		for _, msg := range msgs {
+			if msg.y < frame.Y1 {
+				ref.Before = msg.body
+			}
+		}
+		for _, rect := range shapes {
+			c := (rect.Y1 + rect.Y2) / 2
+			if !rect.IsLine && !rect.Filled && !rect.Lined && !used[rect] &&
+				frame.X1-tolerance <= rect.X1 && rect.X2 <= frame.X2+tolerance && frame.Y1 < c && c < frame.Y2 {
+				ref.Text = rect.Text
+				used[rect] = true
+				break
+			}
+		}
+		refs = append(refs, ref)
+		used[frame] = true
+	}
+	return refs
+}
+
+// findLabels finds the text boxes without fill and line, which are placed along the arrows as the labels.
+func findLabels(shapes []*xlsx.DrawnShape, msgs []*placedMessage, used map[*xlsx.DrawnShape]bool) {
+	for _, rect := range shapes {
+		if rect.IsLine || rect.Filled || rect.Lined || rect.Text == "" || used[rect] {
+			continue
+		}
+
+		var found *placedMessage
+		var foundPos model.LabelPosition
+		c := (rect.Y1 + rect.Y2) / 2
+		for _, msg := range msgs {
+			if msg.body.Text != "" {
+				continue
+			}
+			pos, ok := labelPosition(rect, msg)
+			if !ok {
+				continue
+			}
+			if found == nil || abs(msg.y-c) < abs(found.y-c) {
+				found, foundPos = msg, pos
+			}
+		}
+		if found != nil {
+			found.body.Text = rect.Text
+			found.body.LabelPosition = foundPos
+			used[rect] = true
+		}
+	}
+}
+
+// labelPosition returns the position of the label at which the text box is placed for the message,
+// or false if the text box is not along the message. The vertical center of the label is within
+// its half height from the arrow.
+func labelPosition(rect *xlsx.DrawnShape, msg *placedMessage) (model.LabelPosition, bool) {
+	c := (rect.Y1 + rect.Y2) / 2
+	if abs(msg.y-c) > rect.Height()/2 {
+		return model.LabelLeft, false
+	}
+	switch {
+	case msg.body.Type == model.SelfReference:
+		return model.LabelLeft, near(rect.X1, msg.right)
+	case near(rect.X1, msg.left) && c > msg.y:
+		return model.LabelBelow, true
+	case near(rect.X1, msg.left):
+		return model.LabelLeft, true
+	case near((rect.X1+rect.X2)/2, (msg.left+msg.right)/2):
+		return model.LabelCenter, true
+	case near(rect.X2, msg.right):
+		return model.LabelRight, true
+	}
+	return model.LabelLeft, false
+}
+
+// findFragments finds the framed rectangles without fill, which enclose the messages.
+func findFragments(shapes []*xlsx.DrawnShape, msgs []*placedMessage, used map[*xlsx.DrawnShape]bool) []*model.Fragment {
+	rects := []*xlsx.DrawnShape{}
+	for _, rect := range shapes {
+		if !rect.IsLine && !rect.Filled && rect.Lined && !used[rect] {
+			rects = append(rects, rect)
+		}
+	}
+	sort.SliceStable(rects, func(i, j int) bool {
+		if rects[i].Y1 != rects[j].Y1 {
+			return rects[i].Y1 < rects[j].Y1
+		}
+		return rects[i].X1 < rects[j].X1
+	})
+
+	frags := []*model.Fragment{}
+	for _, rect := range rects {
+		frag := &model.Fragment{Type: fragmentTypeOf(rect.Text)}
+		for _, msg := range msgs {
+			if msg.y <= rect.Y1 || msg.y >= rect.Y2 {
+				continue
+			}
+			if frag.Begin == nil {
+				frag.Begin = msg.body
+			}
+			frag.End = msg.body
+		}
+		if frag.Begin == nil {
+			continue
+		}
+		frag.Index = len(frags)
+		frags = append(frags, frag)
+		used[rect] = true
+	}
+	return frags
+}
+
+func fragmentTypeOf(text string) model.FragmentType {
+	for t := model.Ref; t < model.UnknownFragment; t++ {
+		if t.String() == text {
+			return t
+		}
+	}
+	return model.UnknownFragment
+}
+
+// findNotes finds the remaining rectangles with fill, which are placed at the side of the message.
+func findNotes(shapes []*xlsx.DrawnShape, msgs []*placedMessage, centers map[*model.Lifeline]int, used map[*xlsx.DrawnShape]bool) []*model.Note {
+	notes := []*model.Note{}
+	for _, rect := range shapes {
+		if rect.IsLine || !rect.Filled || !rect.Lined || used[rect] {
+			continue
+		}
+		if isExecSpec(rect, centers) {
+			continue
+		}
+
+		var assoc *model.Message
+		for _, msg := range msgs {
+			if msg.y > rect.Y1 {
+				assoc = msg.body
+				break
+			}
+		}
+		if assoc == nil {
+			continue
+		}
+
+		onLeft := abs(rect.X2-centers[assoc.From]) < abs(rect.X1-centers[assoc.To])
+		notes = append(notes, &model.Note{
+			Assoc:    assoc,
+			OnLeft:   onLeft,
+			Text:     rect.Text,
+			ColorHex: rect.FillColor,
+		})
+		used[rect] = true
+	}
+
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Assoc.Index != notes[j].Assoc.Index {
+			return notes[i].Assoc.Index < notes[j].Assoc.Index
+		}
+		return notes[i].OnLeft && !notes[j].OnLeft
+	})
+	for i, note := range notes {
+		note.Index = i
+	}
+	return notes
+}
+
+func isExecSpec(rect *xlsx.DrawnShape, centers map[*model.Lifeline]int) bool {
+	if !near(rect.X2-rect.X1, execSpecWidth) {
+		return false
+	}
+	for _, c := range centers {
+		if rect.X1-tolerance <= c && c <= rect.X2+tolerance {
+			return true
+		}
+	}
+	return false
+}
+
+func lifelineAt(lls []*model.Lifeline, centers map[*model.Lifeline]int, x int) *model.Lifeline {
+	for _, ll := range lls {
+		if near(centers[ll], x) {
+			return ll
+		}
+	}
+	return nil
+}
+
+func near(a, b int) bool {
+	return abs(a-b) <= tolerance
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
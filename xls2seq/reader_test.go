@@ -0,0 +1,257 @@
+package xls2seq
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rsp9u/seq2xls"
+	"github.com/rsp9u/seq2xls/model"
+	"github.com/rsp9u/seq2xls/xlsx"
+)
+
+func newTestDiagram() *model.SequenceDiagram {
+	browser := &model.Lifeline{Name: "browser", Index: 0, ColorHex: "FFFFFF"}
+	web := &model.Lifeline{Name: "web server", Index: 1, ColorHex: "FFFFFF"}
+	db := &model.Lifeline{Name: "db", Index: 2, ColorHex: "FFFFFF"}
+
+	msgs := []*model.Message{
+		{Index: 0, From: browser, To: web, Type: model.Synchronous, Text: "GET /index.html"},
+		{Index: 1, From: web, To: db, Type: model.Asynchronous, Text: "SELECT", LabelPosition: model.LabelCenter},
+		{Index: 2, From: db, To: web, Type: model.Reply},
+		{Index: 3, From: web, To: web, Type: model.SelfReference, Text: "render"},
+		{Index: 4, From: web, To: browser, Type: model.Reply, Text: "200 OK", LabelPosition: model.LabelBelow},
+	}
+
+	return &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{browser, web, db},
+		Messages:  msgs,
+		Fragments: []*model.Fragment{
+			{Index: 0, Begin: msgs[1], End: msgs[3], Type: model.Loop},
+			{Index: 1, Begin: msgs[2], End: msgs[2], Type: model.Alt},
+			{Index: 2, Type: model.Ref, Text: "Login sequence", Lifelines: []*model.Lifeline{browser, web}},
+			{Index: 3, Type: model.Ref, Text: "logout", Before: msgs[4], Link: "logout.xlsx"},
+		},
+		Notes: []*model.Note{
+			{Assoc: msgs[0], OnLeft: true, Text: "left", ColorHex: "ffb6c1"},
+			{Assoc: msgs[0], OnLeft: false, Text: "right", ColorHex: "ffb6c1"},
+			{Assoc: msgs[4], OnLeft: false, Text: "multi\nline", ColorHex: "ffb6c1"},
+		},
+		Separators: []*model.Separator{
+			{Text: "start", Before: nil},
+			{Type: model.Delay, Text: "later", Before: msgs[1]},
+			{Text: "finish", Before: msgs[4]},
+		},
+		Constraints: []*model.Constraint{
+			{Index: 0, Begin: msgs[0], End: msgs[4], Text: "{< 200ms}"},
+			{Index: 1, Begin: msgs[3], End: msgs[3], Text: "{< 1s}"},
+		},
+	}
+}
+
+func drawAndRead(t *testing.T, seq *model.SequenceDiagram) *model.SequenceDiagram {
+	dir, err := ioutil.TempDir("", "xls2seq")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	wb := xlsx.NewWorkbook()
+	seq2xls.DrawSequenceDiagram(wb, seq)
+	path := filepath.Join(dir, "test.xlsx")
+	if err := wb.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	act, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("Read error %v", err)
+	}
+	return act
+}
+
+func TestReadLifelines(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Lifelines) != len(exp.Lifelines) {
+		t.Fatalf("Too many or few lifelines %d", len(act.Lifelines))
+	}
+	for i, ll := range act.Lifelines {
+		if ll.Index != i || ll.Name != exp.Lifelines[i].Name {
+			t.Fatalf("Mismatches lifeline [expect: %s[%d], actual: %s[%d]]", exp.Lifelines[i].Name, i, ll.Name, ll.Index)
+		}
+	}
+}
+
+func TestReadMessages(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Messages) != len(exp.Messages) {
+		t.Fatalf("Too many or few messages %d", len(act.Messages))
+	}
+	for i, msg := range act.Messages {
+		e := exp.Messages[i]
+		if msg.Index != i {
+			t.Fatalf("Mismatches index of message [expect: %d, actual: %d]", i, msg.Index)
+		}
+		if msg.From.Name != e.From.Name || msg.To.Name != e.To.Name {
+			t.Fatalf("Mismatches lifelines of message %d [expect: %s->%s, actual: %s->%s]", i, e.From.Name, e.To.Name, msg.From.Name, msg.To.Name)
+		}
+		if msg.Type != e.Type {
+			t.Fatalf("Mismatches message type %d [expect: %v, actual: %v]", i, e.Type, msg.Type)
+		}
+		if msg.Text != e.Text {
+			t.Fatalf("Mismatches label of message %d [expect: %s, actual: %s]", i, e.Text, msg.Text)
+		}
+		if msg.LabelPosition != e.LabelPosition {
+			t.Fatalf("Mismatches label position of message %d [expect: %v, actual: %v]", i, e.LabelPosition, msg.LabelPosition)
+		}
+	}
+}
+
+func TestReadFragments(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Fragments) != len(exp.Fragments) {
+		t.Fatalf("Too many or few fragments %d", len(act.Fragments))
+	}
+	for i, frag := range act.Fragments {
+		e := exp.Fragments[i]
+		if frag.Type != e.Type {
+			t.Fatalf("Mismatches type of the fragment %d [expect: %v, actual: %v]", i, e.Type, frag.Type)
+		}
+		if frag.Type == model.Ref {
+			if frag.Text != e.Text || len(frag.Lifelines) != len(e.Lifelines) || (frag.Before == nil) != (e.Before == nil) ||
+				frag.Before != nil && frag.Before.Index != e.Before.Index {
+				t.Fatalf("Mismatches the reference %d %+v", i, frag)
+			}
+			continue
+		}
+		if frag.Begin.Index != e.Begin.Index || frag.End.Index != e.End.Index {
+			t.Fatalf("Mismatches range of the fragment %d [expect: %d-%d, actual: %d-%d]", i, e.Begin.Index, e.End.Index, frag.Begin.Index, frag.End.Index)
+		}
+	}
+}
+
+func TestReadNotes(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Notes) != len(exp.Notes) {
+		t.Fatalf("Too many or few notes %d", len(act.Notes))
+	}
+	for i, note := range act.Notes {
+		e := exp.Notes[i]
+		if note.Assoc.Index != e.Assoc.Index {
+			t.Fatalf("Mismatches index of message associated note [expect: %d, actual: %d]", e.Assoc.Index, note.Assoc.Index)
+		}
+		if note.OnLeft != e.OnLeft {
+			t.Fatalf("Invalid side [expect: onleft=%v, actual: onleft=%v]", e.OnLeft, note.OnLeft)
+		}
+		if note.Text != e.Text {
+			t.Fatalf("Mismatches note text [expect: %s, actual: %s]", e.Text, note.Text)
+		}
+	}
+}
+
+func TestReadSeparators(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Separators) != len(exp.Separators) {
+		t.Fatalf("Too many or few separators %d", len(act.Separators))
+	}
+	if act.Separators[0].Text != "start" || act.Separators[0].Before != nil {
+		t.Fatalf("Mismatches the first separator %v", act.Separators[0])
+	}
+	if act.Separators[1].Type != model.Delay || act.Separators[1].Text != "later" || act.Separators[1].Before.Index != 1 {
+		t.Fatalf("Mismatches the delay %v", act.Separators[1])
+	}
+	if act.Separators[2].Type != model.Divider || act.Separators[2].Text != "finish" || act.Separators[2].Before.Index != 4 {
+		t.Fatalf("Mismatches the last separator %v", act.Separators[2])
+	}
+}
+
+func TestReadConstraints(t *testing.T) {
+	exp := newTestDiagram()
+	act := drawAndRead(t, exp)
+
+	if len(act.Constraints) != len(exp.Constraints) {
+		t.Fatalf("Too many or few constraints %d", len(act.Constraints))
+	}
+	for i, c := range act.Constraints {
+		e := exp.Constraints[i]
+		if c.Begin.Index != e.Begin.Index || c.End.Index != e.End.Index || c.Text != e.Text {
+			t.Errorf("Mismatches constraint[%d] %+v", i, c)
+		}
+	}
+}
+
+func TestReadWrappedLabels(t *testing.T) {
+	a := &model.Lifeline{Name: "a", Index: 0, ColorHex: "FFFFFF"}
+	b := &model.Lifeline{Name: "b", Index: 1, ColorHex: "FFFFFF"}
+	msg := &model.Message{Index: 0, From: a, To: b, Type: model.Synchronous, Text: "POST\nmultiline label here that is long enough to wrap"}
+	exp := &model.SequenceDiagram{
+		Lifelines: []*model.Lifeline{a, b},
+		Messages:  []*model.Message{msg},
+		Fragments: []*model.Fragment{
+			{Index: 0, Type: model.Ref, Text: "a reference whose name is long enough to wrap in the frame", Before: msg},
+		},
+	}
+	act := drawAndRead(t, exp)
+
+	if len(act.Messages) != 1 || act.Messages[0].Text != msg.Text {
+		t.Fatalf("The wrapped label is not read as it is written %+v", act.Messages)
+	}
+	if len(act.Fragments) != 1 || act.Fragments[0].Text != exp.Fragments[0].Text {
+		t.Fatalf("The wrapped name of the reference is not read as it is written %+v", act.Fragments)
+	}
+}
+
+func TestReadSheet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xls2seq")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the drawings of the sheets after the ninth are named before the second one in the archive
+	wb := xlsx.NewWorkbook()
+	wb.Sheets()[0].SetName("overview")
+	for i := 2; i <= 10; i++ {
+		a := &model.Lifeline{Name: fmt.Sprintf("a%d", i), Index: 0, ColorHex: "FFFFFF"}
+		seq2xls.DrawSequenceDiagram(wb.AddSheet(fmt.Sprintf("sheet %d", i)), &model.SequenceDiagram{Lifelines: []*model.Lifeline{a}})
+	}
+	seq2xls.DrawSequenceDiagram(wb.Sheets()[0], newTestDiagram())
+	path := filepath.Join(dir, "test.xlsx")
+	if err := wb.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	seq, err := ReadFileSheet(path, "Overview")
+	if err != nil {
+		t.Fatalf("Read error %v", err)
+	}
+	if len(seq.Lifelines) != 3 || seq.Lifelines[0].Name != "browser" {
+		t.Errorf("Wrong worksheet is read %+v", seq.Lifelines)
+	}
+	seq, err = ReadFileSheet(path, "sheet 2")
+	if err != nil {
+		t.Fatalf("Read error %v", err)
+	}
+	if len(seq.Lifelines) != 1 || seq.Lifelines[0].Name != "a2" {
+		t.Errorf("Wrong worksheet is read %+v", seq.Lifelines)
+	}
+
+	if _, err := ReadFile(path); err == nil {
+		t.Errorf("No error for the workbook of several worksheets without the name")
+	}
+	if _, err := ReadFileSheet(path, "missing"); err == nil {
+		t.Errorf("No error for the missing worksheet")
+	}
+}
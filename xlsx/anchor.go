@@ -0,0 +1,122 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+// RawDrawing is a drawing part split into the anchors, which keeps their xml as is.
+type RawDrawing struct {
+	head, tail []byte
+	Anchors    []*RawAnchor
+}
+
+// RawAnchor is an anchored shape of the drawing in the form of xml.
+type RawAnchor struct {
+	XML   []byte
+	Descr string
+}
+
+// ParseRawDrawing splits the contents of the drawing part into the anchors.
+func ParseRawDrawing(b []byte) (*RawDrawing, error) {
+	d := &RawDrawing{}
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	depth := 0
+	var begin, end int64
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 1 {
+				begin = dec.InputOffset()
+				end = begin
+			}
+			if depth == 2 {
+				begin = offset
+			}
+		case xml.EndElement:
+			depth--
+			if depth == 1 {
+				anchor, err := NewRawAnchor(b[begin:dec.InputOffset()])
+				if err != nil {
+					return nil, err
+				}
+				d.Anchors = append(d.Anchors, anchor)
+				if d.head == nil {
+					d.head = b[:begin]
+				}
+				end = dec.InputOffset()
+			}
+		}
+	}
+
+	if d.head == nil {
+		d.head = b[:end]
+	}
+	d.tail = b[end:]
+	return d, nil
+}
+
+// NewRawAnchor creates an anchor from its xml.
+func NewRawAnchor(b []byte) (*RawAnchor, error) {
+	anchor := &RawAnchor{XML: b}
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if t, ok := tok.(xml.StartElement); ok && t.Name.Local == "cNvPr" {
+			for _, attr := range t.Attr {
+				if attr.Name.Local == "descr" {
+					anchor.Descr = attr.Value
+				}
+			}
+			break
+		}
+	}
+	return anchor, nil
+}
+
+// MarshalAnchor creates an anchor from the shape.
+func MarshalAnchor(s shape.Shape) (*RawAnchor, error) {
+	buf := new(bytes.Buffer)
+	e := xml.NewEncoder(buf)
+	if err := s.MarshalXML(e, xml.StartElement{}); err != nil {
+		return nil, err
+	}
+	if err := e.Flush(); err != nil {
+		return nil, err
+	}
+	return NewRawAnchor(buf.Bytes())
+}
+
+// Bytes returns the contents of the drawing part.
+func (d *RawDrawing) Bytes() []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(d.head)
+	for i, anchor := range d.Anchors {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		buf.Write(anchor.XML)
+	}
+	buf.Write(d.tail)
+	return buf.Bytes()
+}
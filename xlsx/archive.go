@@ -0,0 +1,302 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rsp9u/go-xlsshape/oxml"
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+// Archive is an existing xlsx file, whose parts can be replaced without touching the other parts.
+type Archive struct {
+	files []*archiveFile
+}
+
+type archiveFile struct {
+	name     string
+	modified time.Time
+	data     []byte
+}
+
+// OpenArchive reads all parts of the xlsx file.
+func OpenArchive(filename string) (*Archive, error) {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return readArchive(&zr.Reader)
+}
+
+// ReadArchive reads all parts of the xlsx contents of the size.
+func ReadArchive(r io.ReaderAt, size int64) (*Archive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return readArchive(zr)
+}
+
+func readArchive(zr *zip.Reader) (*Archive, error) {
+	a := &Archive{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		a.files = append(a.files, &archiveFile{f.Name, f.Modified, data})
+	}
+	return a, nil
+}
+
+// List lists the all paths in this archive.
+func (a *Archive) List() []string {
+	paths := []string{}
+	for _, f := range a.files {
+		paths = append(paths, f.name)
+	}
+	return paths
+}
+
+// Part returns the contents of the part.
+func (a *Archive) Part(name string) ([]byte, bool) {
+	for _, f := range a.files {
+		if f.name == name {
+			return f.data, true
+		}
+	}
+	return nil, false
+}
+
+// SetPart replaces the contents of the part, or adds the part if it does not exist.
+func (a *Archive) SetPart(name string, data []byte) {
+	for _, f := range a.files {
+		if f.name == name {
+			f.data = data
+			f.modified = time.Now()
+			return
+		}
+	}
+	a.files = append(a.files, &archiveFile{name, time.Now(), data})
+}
+
+// Save writes out the contents into the file.
+func (a *Archive) Save(filename string) error {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, f := range a.files {
+		fw, err := w.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: f.modified})
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// workbookPath is the path of the workbook part, which lists the worksheets.
+const workbookPath = "xl/workbook.xml"
+
+// archiveSheet is a worksheet in the workbook part.
+type archiveSheet struct {
+	Name string `xml:"name,attr"`
+	RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+// sheets returns the worksheets in the order of the workbook.
+func (a *Archive) sheets() ([]archiveSheet, error) {
+	b, ok := a.Part(workbookPath)
+	if !ok {
+		return nil, fmt.Errorf("%s is not found in the workbook", workbookPath)
+	}
+	wb := struct {
+		Sheets []archiveSheet `xml:"sheets>sheet"`
+	}{}
+	if err := xml.Unmarshal(b, &wb); err != nil {
+		return nil, err
+	}
+	return wb.Sheets, nil
+}
+
+// SheetNames returns the names of the worksheets in the order of the workbook.
+func (a *Archive) SheetNames() ([]string, error) {
+	sheets, err := a.sheets()
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, s := range sheets {
+		names = append(names, s.Name)
+	}
+	return names, nil
+}
+
+// SheetDrawing returns the name of the worksheet at the index and the path of its drawing part,
+// which are found through the relationships of the workbook and the worksheet.
+func (a *Archive) SheetDrawing(index int) (name, drawingPath string, err error) {
+	sheets, err := a.sheets()
+	if err != nil {
+		return "", "", err
+	}
+	if index < 0 || index >= len(sheets) {
+		return "", "", fmt.Errorf("the workbook has no worksheet %d", index+1)
+	}
+	name = sheets[index].Name
+	sheetPath, err := a.relationshipTarget(workbookPath, sheets[index].RID)
+	if err != nil {
+		return "", "", err
+	}
+
+	b, ok := a.Part(sheetPath)
+	if !ok {
+		return "", "", fmt.Errorf("%s is not found in the workbook", sheetPath)
+	}
+	ws := struct {
+		Drawing struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"drawing"`
+	}{}
+	if err := xml.Unmarshal(b, &ws); err != nil {
+		return "", "", err
+	}
+	if ws.Drawing.RID == "" {
+		return "", "", fmt.Errorf("worksheet '%s' has no drawing", name)
+	}
+	drawingPath, err = a.relationshipTarget(sheetPath, ws.Drawing.RID)
+	return name, drawingPath, err
+}
+
+// relationshipTarget returns the path of the part which the relationship of the source part targets.
+func (a *Archive) relationshipTarget(source, rid string) (string, error) {
+	relsPath := oxml.RelationshipPath(source)
+	b, ok := a.Part(relsPath)
+	if !ok {
+		return "", fmt.Errorf("%s is not found in the workbook", relsPath)
+	}
+	rels := relationships{}
+	if err := xml.Unmarshal(b, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != rid {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join(path.Dir(source), rel.Target), nil
+	}
+	return "", fmt.Errorf("relationship %s is not found in %s", rid, relsPath)
+}
+
+// AddCustomXML adds a custom xml part and its relationship from the workbook, and returns the path of the part.
+func (a *Archive) AddCustomXML(data []byte) (string, error) {
+	const relsPath = "xl/_rels/workbook.xml.rels"
+
+	b, ok := a.Part(relsPath)
+	if !ok {
+		return "", fmt.Errorf("%s is not found in the workbook", relsPath)
+	}
+	rels := relationships{}
+	if err := xml.Unmarshal(b, &rels); err != nil {
+		return "", err
+	}
+
+	name := ""
+	for i := 1; name == ""; i++ {
+		name = fmt.Sprintf("customXml/item%d.xml", i)
+		if _, exists := a.Part(name); exists {
+			name = ""
+		}
+	}
+	rid := ""
+	for i := len(rels.Items) + 1; rid == ""; i++ {
+		rid = "rId" + strconv.Itoa(i)
+		for _, rel := range rels.Items {
+			if rel.ID == rid {
+				rid = ""
+				break
+			}
+		}
+	}
+
+	end := bytes.LastIndex(b, []byte("</Relationships>"))
+	if end < 0 {
+		return "", fmt.Errorf("%s is malformed", relsPath)
+	}
+	rel := fmt.Sprintf(`<Relationship Id="%s" Type="%s" Target="../%s"/>`, rid, typeRelationshipsCustomXML, name)
+	updated := append([]byte{}, b[:end]...)
+	updated = append(updated, rel...)
+	updated = append(updated, b[end:]...)
+
+	a.SetPart(relsPath, updated)
+	a.SetPart(name, data)
+	return name, nil
+}
+
+// DrawingLinks is the relationships of an existing drawing part, which gives the links to the shapes drawn into it anew.
+type DrawingLinks struct {
+	drawingPath string
+	rels        *relationships
+}
+
+// DrawingLinks reads the relationships of the drawing part, which are empty if the part has none.
+func (a *Archive) DrawingLinks(drawingPath string) (*DrawingLinks, error) {
+	path := oxml.RelationshipPath(drawingPath)
+	rels := &relationships{path: path}
+	if b, ok := a.Part(path); ok {
+		if err := xml.Unmarshal(b, rels); err != nil {
+			return nil, err
+		}
+	}
+	// the namespace is put by the attribute, not by the name
+	rels.XMLName = xml.Name{}
+	rels.Namespace = xmlnsRelationshipsPackage
+	return &DrawingLinks{drawingPath, rels}, nil
+}
+
+// AddLink adds the relationship of the hyperlink of the decorated shape.
+func (l *DrawingLinks) AddLink(shp shape.Shape) {
+	ds, ok := shp.(*Shape)
+	if !ok || ds.hyperlink == "" {
+		return
+	}
+	ds.linkID = l.rels.addHyperlink(ds.hyperlink)
+}
+
+// SetDrawingLinks replaces the relationships of the drawing part, which is to be set beforehand.
+// The hyperlinks which the shapes in the drawing no longer refer to are removed.
+func (a *Archive) SetDrawingLinks(l *DrawingLinks) {
+	drawing, _ := a.Part(l.drawingPath)
+	items := []relationship{}
+	for _, rel := range l.rels.Items {
+		if rel.Type != typeRelationshipsHyperlink || bytes.Contains(drawing, []byte(`:id="`+rel.ID+`"`)) {
+			items = append(items, rel)
+		}
+	}
+	l.rels.Items = items
+
+	if _, ok := a.Part(l.rels.Path()); !ok && len(items) == 0 {
+		return
+	}
+	a.SetPart(l.rels.Path(), []byte(l.rels.Content()))
+}
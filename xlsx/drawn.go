@@ -0,0 +1,251 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// Anchors are written by 'go-xlsshape' with CellSize pixels per cell and cellScale units per pixel of offset.
+const (
+	CellSize  = 20
+	cellScale = 10000
+)
+
+type xmlDrawing struct {
+	Anchors []xmlAnchor `xml:"twoCellAnchor"`
+}
+
+type xmlAnchor struct {
+	From  xmlCellPos `xml:"from"`
+	To    xmlCellPos `xml:"to"`
+	Shape xmlShape   `xml:"sp"`
+}
+
+type xmlCellPos struct {
+	Col    int `xml:"col"`
+	ColOff int `xml:"colOff"`
+	Row    int `xml:"row"`
+	RowOff int `xml:"rowOff"`
+}
+
+type xmlShape struct {
+	NonVisual struct {
+		Properties struct {
+			Name  string `xml:"name,attr"`
+			Descr string `xml:"descr,attr"`
+		} `xml:"cNvPr"`
+	} `xml:"nvSpPr"`
+	Properties struct {
+		XForm *struct {
+			FlipH string `xml:"flipH,attr"`
+			FlipV string `xml:"flipV,attr"`
+		} `xml:"xfrm"`
+		Geom struct {
+			Preset string `xml:"prst,attr"`
+		} `xml:"prstGeom"`
+		SolidFill *xmlSolidFill `xml:"solidFill"`
+		NoFill    *struct{}     `xml:"noFill"`
+		Line      *struct {
+			Width     string        `xml:"w,attr"`
+			SolidFill *xmlSolidFill `xml:"solidFill"`
+			NoFill    *struct{}     `xml:"noFill"`
+			Dash      *struct {
+				Value string `xml:"val,attr"`
+			} `xml:"prstDash"`
+			Head *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"headEnd"`
+			Tail *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"tailEnd"`
+		} `xml:"ln"`
+	} `xml:"spPr"`
+	TextBody *struct {
+		Properties struct {
+			Anchor string `xml:"anchor,attr"`
+			Wrap   string `xml:"wrap,attr"`
+		} `xml:"bodyPr"`
+		Paragraphs []struct {
+			Properties struct {
+				Align string `xml:"algn,attr"`
+			} `xml:"pPr"`
+			Runs []struct {
+				Properties struct {
+					Size      string        `xml:"sz,attr"`
+					Lang      string        `xml:"lang,attr"`
+					SolidFill *xmlSolidFill `xml:"solidFill"`
+					Latin     *xmlTypeface  `xml:"latin"`
+					EastAsian *xmlTypeface  `xml:"ea"`
+				} `xml:"rPr"`
+				Text string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"p"`
+	} `xml:"txBody"`
+}
+
+type xmlTypeface struct {
+	Typeface string `xml:"typeface,attr"`
+}
+
+type xmlSolidFill struct {
+	Color *struct {
+		Value string `xml:"val,attr"`
+	} `xml:"srgbClr"`
+}
+
+// DrawnShape is a shape of the drawing with the positions in pixels.
+//
+// The start and the end of lines are restored from the flip flags, whereas
+// (X1, Y1) and (X2, Y2) of rectangles are the left-top and the right-bottom.
+type DrawnShape struct {
+	IsLine         bool
+	X1, Y1, X2, Y2 int
+	DashType       string
+	HeadType       string
+	TailType       string
+	Filled         bool
+	FillColor      string
+	Lined          bool
+	LineColor      string
+	// LineWidth is the width of the line in points, or zero for the default.
+	LineWidth float64
+	Text      string
+	// HAlign and VAlign are the alignments of the text such as "ctr", or empty for the default.
+	HAlign, VAlign string
+	// Wrap is whether the lines of the text are wrapped in the width of the shape.
+	Wrap bool
+	// FontSize is the size of the text in points, or zero for the default.
+	FontSize int
+	// TextColor and FontFamily are the color and the typeface of the text, or empty for the defaults.
+	TextColor, FontFamily string
+	// EastAsianFontFamily is the typeface of the East Asian characters, or empty for the default.
+	EastAsianFontFamily string
+	// Lang is the language tag of the text such as "ja-JP".
+	Lang        string
+	Name, Descr string
+}
+
+// Height returns the height of the shape.
+func (s *DrawnShape) Height() int {
+	return s.Y2 - s.Y1
+}
+
+// IsHorizontal returns whether the shape is a horizontal line.
+func (s *DrawnShape) IsHorizontal() bool {
+	return s.IsLine && s.Y1 == s.Y2 && s.X1 != s.X2
+}
+
+// IsVertical returns whether the shape is a vertical line.
+func (s *DrawnShape) IsVertical() bool {
+	return s.IsLine && s.X1 == s.X2 && s.Y1 != s.Y2
+}
+
+// ParseDrawnShapes parses the shapes in the drawing part.
+func ParseDrawnShapes(b []byte) ([]*DrawnShape, error) {
+	d := xmlDrawing{}
+	if err := xml.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+
+	shapes := []*DrawnShape{}
+	for _, a := range d.Anchors {
+		shapes = append(shapes, newDrawnShape(&a))
+	}
+	return shapes, nil
+}
+
+// ParseDrawnShape parses the shape in the anchor.
+func ParseDrawnShape(anchor *RawAnchor) (*DrawnShape, error) {
+	a := xmlAnchor{}
+	if err := xml.Unmarshal(anchor.XML, &a); err != nil {
+		return nil, err
+	}
+	return newDrawnShape(&a), nil
+}
+
+func newDrawnShape(a *xmlAnchor) *DrawnShape {
+	sp := &a.Shape
+	s := &DrawnShape{
+		Name:  sp.NonVisual.Properties.Name,
+		Descr: sp.NonVisual.Properties.Descr,
+	}
+	s.X1, s.Y1 = a.From.pixel()
+	s.X2, s.Y2 = a.To.pixel()
+
+	if sp.Properties.Geom.Preset == "straightConnector1" || sp.Properties.Geom.Preset == "line" {
+		s.IsLine = true
+		if xf := sp.Properties.XForm; xf != nil {
+			if xf.FlipH == "1" {
+				s.X1, s.X2 = s.X2, s.X1
+			}
+			if xf.FlipV == "1" {
+				s.Y1, s.Y2 = s.Y2, s.Y1
+			}
+		}
+	}
+
+	if f := sp.Properties.SolidFill; f != nil && sp.Properties.NoFill == nil {
+		s.Filled = true
+		if f.Color != nil {
+			s.FillColor = f.Color.Value
+		}
+	}
+
+	if ln := sp.Properties.Line; ln != nil {
+		s.Lined = ln.SolidFill != nil && ln.NoFill == nil
+		if s.Lined && ln.SolidFill.Color != nil {
+			s.LineColor = ln.SolidFill.Color.Value
+		}
+		if w, err := strconv.Atoi(ln.Width); err == nil {
+			s.LineWidth = float64(w) / emuPerPoint
+		}
+		if ln.Dash != nil {
+			s.DashType = ln.Dash.Value
+		}
+		if ln.Head != nil && ln.Head.Type != "none" {
+			s.HeadType = ln.Head.Type
+		}
+		if ln.Tail != nil && ln.Tail.Type != "none" {
+			s.TailType = ln.Tail.Type
+		}
+	}
+
+	if tb := sp.TextBody; tb != nil {
+		s.VAlign = tb.Properties.Anchor
+		s.Wrap = tb.Properties.Wrap == "square"
+		lines := []string{}
+		for _, p := range tb.Paragraphs {
+			if s.HAlign == "" {
+				s.HAlign = p.Properties.Align
+			}
+			line := ""
+			for _, r := range p.Runs {
+				line += r.Text
+				if size, err := strconv.Atoi(r.Properties.Size); err == nil && s.FontSize == 0 {
+					s.FontSize = size / 100
+				}
+				if f := r.Properties.SolidFill; f != nil && f.Color != nil && s.TextColor == "" {
+					s.TextColor = f.Color.Value
+				}
+				if l := r.Properties.Latin; l != nil && s.FontFamily == "" {
+					s.FontFamily = l.Typeface
+				}
+				if ea := r.Properties.EastAsian; ea != nil && s.EastAsianFontFamily == "" {
+					s.EastAsianFontFamily = ea.Typeface
+				}
+				if s.Lang == "" {
+					s.Lang = r.Properties.Lang
+				}
+			}
+			lines = append(lines, line)
+		}
+		s.Text = strings.Join(lines, "\n")
+	}
+
+	return s
+}
+
+func (p xmlCellPos) pixel() (int, int) {
+	return p.Col*CellSize + p.ColOff/cellScale, p.Row*CellSize + p.RowOff/cellScale
+}
@@ -0,0 +1,90 @@
+package xlsx
+
+import (
+	"encoding/xml"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rsp9u/go-xlsshape/oxml"
+)
+
+const (
+	xmlnsRelationshipsPackage        = "http://schemas.openxmlformats.org/package/2006/relationships"
+	xmlnsRelationshipsOfficeDocument = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	typeRelationshipsHyperlink       = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+)
+
+// relationships is a relationships part like oxml.Relationships, whose items can target
+// the resources outside of the package.
+type relationships struct {
+	XMLName   xml.Name       `xml:"Relationships"`
+	Namespace string         `xml:"xmlns,attr"`
+	Items     []relationship `xml:"Relationship"`
+	path      string
+}
+
+type relationship struct {
+	XMLName    xml.Name `xml:"Relationship"`
+	ID         string   `xml:"Id,attr"`
+	Type       string   `xml:"Type,attr"`
+	Target     string   `xml:"Target,attr"`
+	TargetMode string   `xml:"TargetMode,attr,omitempty"`
+}
+
+// newRelationships creates the relationships part of the given part.
+func newRelationships(source oxml.Part) *relationships {
+	return &relationships{Namespace: xmlnsRelationshipsPackage, path: oxml.RelationshipPath(source.Path())}
+}
+
+// Path returns the file path in the archive.
+func (r *relationships) Path() string {
+	return r.path
+}
+
+// Content returns an xml string generated from object contents.
+func (r *relationships) Content() string {
+	content, err := oxml.DefaultEncode(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return content
+}
+
+// addHyperlink adds the relationship of the link and returns its id, or returns the id of the existing
+// relationship of the same link. The targets which do not begin with '#' are outside of the package,
+// and the paths of them are percent-encoded.
+func (r *relationships) addHyperlink(target string) string {
+	mode := ""
+	if !strings.HasPrefix(target, "#") {
+		mode = "External"
+		target = encodeExternalTarget(target)
+	}
+	for _, rel := range r.Items {
+		if rel.Type == typeRelationshipsHyperlink && rel.Target == target && rel.TargetMode == mode {
+			return rel.ID
+		}
+	}
+
+	rid := ""
+	for i := len(r.Items) + 1; rid == ""; i++ {
+		rid = "rId" + strconv.Itoa(i)
+		for _, rel := range r.Items {
+			if rel.ID == rid {
+				rid = ""
+				break
+			}
+		}
+	}
+	r.Items = append(r.Items, relationship{ID: rid, Type: typeRelationshipsHyperlink, Target: target, TargetMode: mode})
+	return rid
+}
+
+// encodeExternalTarget percent-encodes the path of the target outside of the package, leaving the URLs as they are.
+func encodeExternalTarget(target string) string {
+	if u, err := url.Parse(target); err == nil && len(u.Scheme) > 1 {
+		return target
+	}
+	return (&url.URL{Path: target}).String()
+}
@@ -0,0 +1,216 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strconv"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+// Shape decorates a shape of go-xlsshape with the properties which go-xlsshape does not support.
+//
+// The decorated properties are applied by rewriting the xml elements which the original shape generates.
+type Shape struct {
+	inner       shape.Shape
+	name, descr string
+	lineWidth   float64
+	textColor   string
+	fonts       Fonts
+	// hyperlink is the target of the link, and linkID is the id of its relationship in the drawing.
+	hyperlink, linkID string
+}
+
+// Fonts is the typefaces of the text by the kind of the characters. Empty ones are left to the spreadsheet applications.
+type Fonts struct {
+	Latin         string
+	EastAsian     string
+	ComplexScript string
+}
+
+// emuPerPoint is the number of English Metric Units, in which DrawingML measures the widths, per point.
+const emuPerPoint = 12700
+
+// Decorate creates a decorator of the given shape.
+func Decorate(s shape.Shape) *Shape {
+	return &Shape{inner: s}
+}
+
+// SetName sets the name of this, which is shown in the selection pane of the spreadsheet applications.
+func (s *Shape) SetName(name string) {
+	s.name = name
+}
+
+// SetDescr sets the description (alternative text) of this.
+func (s *Shape) SetDescr(descr string) {
+	s.descr = descr
+}
+
+// SetLineWidth sets the width of the line, or the border of the rectangle, in points.
+// Zero leaves the default width of the spreadsheet applications.
+func (s *Shape) SetLineWidth(pt float64) {
+	s.lineWidth = pt
+}
+
+// SetTextColor sets the color of the text by rgb hex values like "FF0000".
+func (s *Shape) SetTextColor(c string) {
+	s.textColor = c
+}
+
+// SetFontFamily sets the typeface of the text, which is used for all the kinds of the characters.
+func (s *Shape) SetFontFamily(family string) {
+	s.fonts = Fonts{Latin: family, EastAsian: family, ComplexScript: family}
+}
+
+// SetFonts sets the typefaces of the text by the kind of the characters.
+func (s *Shape) SetFonts(fonts Fonts) {
+	s.fonts = fonts
+}
+
+// SetHyperlink sets the target which this links to: a location in the workbook beginning with '#'
+// such as "#'Sheet2'!A1", or the path or the URL of another file.
+// The link takes effect when this is added to a worksheet of Workbook, which has the relationships of the links.
+func (s *Shape) SetHyperlink(target string) {
+	s.hyperlink = target
+}
+
+// Hyperlink returns the target which this links to, or empty if it has no link.
+func (s *Shape) Hyperlink() string {
+	return s.hyperlink
+}
+
+// MarshalXML generates the xml element from the original shape and puts it to the encoder with the decorations.
+func (s *Shape) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	buf := new(bytes.Buffer)
+	inner := xml.NewEncoder(buf)
+	if err := s.inner.MarshalXML(inner, start); err != nil {
+		return err
+	}
+	if err := inner.Flush(); err != nil {
+		return err
+	}
+
+	d := xml.NewDecoder(buf)
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		children := []xml.Token{}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			local := t.Name.Local
+			t.Name = rawName(t.Name)
+			for i := range t.Attr {
+				t.Attr[i].Name = rawName(t.Attr[i].Name)
+			}
+			switch local {
+			case "cNvPr":
+				t.Attr = s.decorateNonVisualProperties(t.Attr)
+				children = s.hyperlinkClick()
+			case "ln":
+				if s.lineWidth > 0 {
+					t.Attr = setAttr(t.Attr, "w", strconv.Itoa(int(s.lineWidth*emuPerPoint)))
+				}
+			}
+			tok = t
+		case xml.EndElement:
+			if t.Name.Local == "rPr" {
+				// the children of the run properties go before its end
+				for _, child := range s.textRunProperties() {
+					if err := e.EncodeToken(child); err != nil {
+						return err
+					}
+				}
+			}
+			t.Name = rawName(t.Name)
+			tok = t
+		}
+
+		if err := e.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return err
+		}
+		for _, child := range children {
+			if err := e.EncodeToken(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Shape) decorateNonVisualProperties(attrs []xml.Attr) []xml.Attr {
+	if s.name != "" {
+		attrs = setAttr(attrs, "name", s.name)
+	}
+	if s.descr != "" {
+		attrs = setAttr(attrs, "descr", s.descr)
+	}
+	return attrs
+}
+
+// hyperlinkClick returns the element of the link in the non-visual properties,
+// or nothing if the relationship of the link is not registered.
+func (s *Shape) hyperlinkClick() []xml.Token {
+	if s.linkID == "" {
+		return nil
+	}
+	attrs := []xml.Attr{
+		{Name: xml.Name{Local: "xmlns:r"}, Value: xmlnsRelationshipsOfficeDocument},
+		{Name: xml.Name{Local: "r:id"}, Value: s.linkID},
+	}
+	return []xml.Token{
+		xml.StartElement{Name: xml.Name{Local: "a:hlinkClick"}, Attr: attrs},
+		xml.EndElement{Name: xml.Name{Local: "a:hlinkClick"}},
+	}
+}
+
+// textRunProperties returns the elements of the color and the typeface of the text in the order of the schema.
+func (s *Shape) textRunProperties() []xml.Token {
+	toks := []xml.Token{}
+	if s.textColor != "" {
+		toks = append(toks,
+			xml.StartElement{Name: xml.Name{Local: "a:solidFill"}},
+			xml.StartElement{Name: xml.Name{Local: "a:srgbClr"}, Attr: []xml.Attr{{Name: xml.Name{Local: "val"}, Value: s.textColor}}},
+			xml.EndElement{Name: xml.Name{Local: "a:srgbClr"}},
+			xml.EndElement{Name: xml.Name{Local: "a:solidFill"}},
+		)
+	}
+	typefaces := []struct{ name, typeface string }{
+		{"a:latin", s.fonts.Latin},
+		{"a:ea", s.fonts.EastAsian},
+		{"a:cs", s.fonts.ComplexScript},
+	}
+	for _, f := range typefaces {
+		if f.typeface != "" {
+			toks = append(toks,
+				xml.StartElement{Name: xml.Name{Local: f.name}, Attr: []xml.Attr{{Name: xml.Name{Local: "typeface"}, Value: f.typeface}}},
+				xml.EndElement{Name: xml.Name{Local: f.name}},
+			)
+		}
+	}
+	return toks
+}
+
+// rawName restores the prefixed name, because go-xlsshape writes the prefix as a part of the local name.
+func rawName(n xml.Name) xml.Name {
+	if n.Space == "" {
+		return n
+	}
+	return xml.Name{Local: n.Space + ":" + n.Local}
+}
+
+func setAttr(attrs []xml.Attr, name, value string) []xml.Attr {
+	for i := range attrs {
+		if attrs[i].Name.Local == name {
+			attrs[i].Value = value
+			return attrs
+		}
+	}
+	return append(attrs, xml.Attr{Name: xml.Name{Local: name}, Value: value})
+}
@@ -0,0 +1,86 @@
+package xlsx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+func marshalShape(t *testing.T, s shape.Shape) string {
+	buf := new(bytes.Buffer)
+	e := xml.NewEncoder(buf)
+	if err := s.MarshalXML(e, xml.StartElement{}); err != nil {
+		t.Fatalf("Marshal error %v", err)
+	}
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Flush error %v", err)
+	}
+	return buf.String()
+}
+
+func TestDecorateNameAndDescr(t *testing.T) {
+	rect := shape.NewRectangle()
+	rect.SetText("foo", "en-US")
+	orig := marshalShape(t, rect)
+
+	ds := Decorate(rect)
+	ds.SetName("lifeline-0")
+	ds.SetDescr("seq2xls:lifeline-0")
+	act := marshalShape(t, ds)
+
+	exp := strings.Replace(orig, `<xdr:cNvPr id="1" name="">`, `<xdr:cNvPr id="1" name="lifeline-0" descr="seq2xls:lifeline-0">`, 1)
+	if act != exp {
+		t.Fatalf("Mismatches decorated xml\n[expect]\n%s\n[actual]\n%s", exp, act)
+	}
+}
+
+func TestDecorateNothing(t *testing.T) {
+	line := shape.NewLine()
+	line.SetStartPos(100, 40)
+	line.SetEndPos(20, 40)
+	line.SetTailType("triangle")
+
+	exp := marshalShape(t, line)
+	act := marshalShape(t, Decorate(line))
+	if act != exp {
+		t.Fatalf("Mismatches decorated xml\n[expect]\n%s\n[actual]\n%s", exp, act)
+	}
+}
+
+func TestDecorateStyle(t *testing.T) {
+	rect := shape.NewRectangle()
+	rect.SetText("foo", "en-US")
+	orig := marshalShape(t, rect)
+
+	ds := Decorate(rect)
+	ds.SetLineWidth(1.5)
+	ds.SetTextColor("FF0000")
+	ds.SetFontFamily("Meiryo")
+	act := marshalShape(t, ds)
+
+	exp := strings.Replace(orig, `<a:ln>`, `<a:ln w="19050">`, 1)
+	exp = strings.Replace(exp, `</a:rPr>`, `<a:solidFill><a:srgbClr val="FF0000"></a:srgbClr></a:solidFill>`+
+		`<a:latin typeface="Meiryo"></a:latin><a:ea typeface="Meiryo"></a:ea><a:cs typeface="Meiryo"></a:cs></a:rPr>`, 1)
+	if act != exp {
+		t.Fatalf("Mismatches decorated xml\n[expect]\n%s\n[actual]\n%s", exp, act)
+	}
+
+	rect.SetText("ノート", "ja-JP")
+	ds = Decorate(rect)
+	ds.SetTextColor("FF0000")
+	ds.SetFonts(Fonts{EastAsian: "Yu Gothic"})
+	anchor, err := MarshalAnchor(ds)
+	if err != nil {
+		t.Fatalf("MarshalAnchor error %v", err)
+	}
+	s, err := ParseDrawnShape(anchor)
+	if err != nil {
+		t.Fatalf("ParseDrawnShape error %v", err)
+	}
+	if s.TextColor != "FF0000" || s.LineWidth != 0 || s.FontFamily != "" || s.EastAsianFontFamily != "Yu Gothic" || s.Lang != "ja-JP" {
+		t.Errorf("Unexpected style of the parsed shape %+v", s)
+	}
+}
@@ -0,0 +1,285 @@
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rsp9u/go-xlsshape/oxml"
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+const (
+	contentTypeWorksheet = "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"
+	contentTypeDrawing   = "application/vnd.openxmlformats-officedocument.drawing+xml"
+
+	// maxSheetName is the maximum length of the sheet name which the spreadsheet applications accept.
+	maxSheetName = 31
+)
+
+const (
+	typeRelationshipsDocument           = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	typeRelationshipsCoreProperties     = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	typeRelationshipsExtentedProperties = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	typeRelationshipsCustomXML          = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+)
+
+// Workbook is a spreadsheet like oxml.Spreadsheet, which can contain additional parts and worksheets.
+//
+// The shapes added to the workbook itself are drawn in the first worksheet.
+type Workbook struct {
+	pkg      *oxml.Package
+	ct       *oxml.ContentTypes
+	workbook *oxml.Workbook
+	sheets   []*Sheet
+}
+
+// Sheet is a worksheet of the workbook, which has its own drawing.
+type Sheet struct {
+	wb      *Workbook
+	index   int
+	drawing *oxml.Drawing
+	// links is the relationships of the hyperlinks of the shapes, or nil until a shape with a link is added.
+	links *relationships
+}
+
+// NewWorkbook creates a new workbook with a single worksheet.
+func NewWorkbook() *Workbook {
+	ct := oxml.NewContentTypes()
+	ct.AddDefault(oxml.DefaultType{Extension: "rels", ContentType: "application/vnd.openxmlformats-package.relationships+xml"})
+	ct.AddDefault(oxml.DefaultType{Extension: "xml", ContentType: "application/xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/xl/workbook.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/xl/worksheets/sheet1.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/xl/theme/theme1.xml", ContentType: "application/vnd.openxmlformats-officedocument.theme+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/xl/styles.xml", ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/xl/drawings/drawing1.xml", ContentType: "application/vnd.openxmlformats-officedocument.drawing+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/docProps/core.xml", ContentType: "application/vnd.openxmlformats-package.core-properties+xml"})
+	ct.AddOverride(oxml.OverrideType{PartName: "/docProps/app.xml", ContentType: "application/vnd.openxmlformats-officedocument.extended-properties+xml"})
+
+	coreProps := oxml.NewCoreProps()
+	appProps := oxml.NewAppProps()
+
+	wb := oxml.NewWorkbook("xl/workbook.xml")
+	ws := oxml.NewWorksheet("xl/worksheets/sheet1.xml")
+	wb.Add("Sheet1", "1", ws)
+	ws.SetDefaultCellSize("2.5", "15")
+
+	drawing := oxml.NewDrawing("xl/drawings/drawing1.xml")
+	ws.AddDrawing(drawing)
+
+	rel := oxml.NewRelationships("_rels/.rels")
+	rel.Add(oxml.Relationship{ID: "rId1", Type: typeRelationshipsDocument, Target: wb.Path()})
+	rel.Add(oxml.Relationship{ID: "rId2", Type: typeRelationshipsCoreProperties, Target: coreProps.Path()})
+	rel.Add(oxml.Relationship{ID: "rId3", Type: typeRelationshipsExtentedProperties, Target: appProps.Path()})
+
+	p := &oxml.Package{}
+	p.Add(ct)
+	p.Add(rel)
+	p.Add(coreProps)
+	p.Add(appProps)
+	p.Add(wb)
+	p.Add(wb.Relationships())
+	p.Add(drawing)
+	p.Add(ws)
+	p.Add(ws.Relationships())
+
+	w := &Workbook{pkg: p, ct: ct, workbook: wb}
+	w.sheets = []*Sheet{{wb: w, index: 0, drawing: drawing}}
+	return w
+}
+
+// AddShape adds a shape into the drawing of the first worksheet.
+func (wb *Workbook) AddShape(s shape.Shape) {
+	wb.sheets[0].AddShape(s)
+}
+
+// UnshiftShape adds a shape into the drawing of the first worksheet.
+// The given shape will be drawn under the existing shapes.
+func (wb *Workbook) UnshiftShape(s shape.Shape) {
+	wb.sheets[0].UnshiftShape(s)
+}
+
+// Shapes returns the shapes in the drawing of the first worksheet.
+func (wb *Workbook) Shapes() []shape.Shape {
+	return wb.sheets[0].Shapes()
+}
+
+// Sheets returns the worksheets of this workbook.
+func (wb *Workbook) Sheets() []*Sheet {
+	return wb.sheets
+}
+
+// AddSheet adds a new worksheet with a drawing into this workbook.
+// The name is adjusted by the same rules as SetName.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	n := len(wb.sheets) + 1
+	wsPath := fmt.Sprintf("xl/worksheets/sheet%d.xml", n)
+	drawingPath := fmt.Sprintf("xl/drawings/drawing%d.xml", n)
+	wb.ct.AddOverride(oxml.OverrideType{PartName: "/" + wsPath, ContentType: contentTypeWorksheet})
+	wb.ct.AddOverride(oxml.OverrideType{PartName: "/" + drawingPath, ContentType: contentTypeDrawing})
+
+	ws := oxml.NewWorksheet(wsPath)
+	ws.SetDefaultCellSize("2.5", "15")
+	drawing := oxml.NewDrawing(drawingPath)
+	ws.AddDrawing(drawing)
+	wb.workbook.Add(fmt.Sprintf("Sheet%d", n), strconv.Itoa(n), ws)
+
+	wb.pkg.Add(drawing)
+	wb.pkg.Add(ws)
+	wb.pkg.Add(ws.Relationships())
+
+	sheet := &Sheet{wb: wb, index: len(wb.sheets), drawing: drawing}
+	wb.sheets = append(wb.sheets, sheet)
+	sheet.SetName(name)
+	return sheet
+}
+
+// SheetByName returns the worksheet of the name, which is compared in the same way as
+// the spreadsheet applications after adjusted by the rules of SetName, or nil if there is none.
+func (wb *Workbook) SheetByName(name string) *Sheet {
+	name = sanitizeSheetName(name)
+	for _, sheet := range wb.sheets {
+		if strings.EqualFold(sheet.Name(), name) {
+			return sheet
+		}
+	}
+	return nil
+}
+
+// Workbook returns the workbook which this belongs to.
+func (s *Sheet) Workbook() *Workbook {
+	return s.wb
+}
+
+// Location returns the target of the links to the top left cell of this, such as "#'Sheet2'!A1".
+func (s *Sheet) Location() string {
+	return "#'" + strings.Replace(s.Name(), "'", "''", -1) + "'!A1"
+}
+
+// Index returns the position of this worksheet in the workbook, which starts from zero.
+func (s *Sheet) Index() int {
+	return s.index
+}
+
+// Name returns the name of this worksheet.
+func (s *Sheet) Name() string {
+	return s.wb.workbook.Sheets.Items[s.index].Name
+}
+
+// SetName sets the name of this worksheet.
+//
+// The characters which are not allowed in the sheet name are removed and the name
+// is truncated to 31 characters. A number is appended if the name is already used
+// by another worksheet.
+func (s *Sheet) SetName(name string) {
+	name = sanitizeSheetName(name)
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", s.index+1)
+	}
+
+	unique := name
+	for i := 2; s.wb.sheetNameUsed(unique, s.index); i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		unique = truncate(name, maxSheetName-len(suffix)) + suffix
+	}
+	s.wb.workbook.Sheets.Items[s.index].Name = unique
+}
+
+// AddShape adds a shape into the drawing of this worksheet.
+func (s *Sheet) AddShape(shp shape.Shape) {
+	s.addLink(shp)
+	s.drawing.AddShape(shp)
+}
+
+// UnshiftShape adds a shape into the drawing of this worksheet.
+// The given shape will be drawn under the existing shapes.
+func (s *Sheet) UnshiftShape(shp shape.Shape) {
+	s.addLink(shp)
+	// oxml.Drawing.UnshiftShape loses the shape when the slice is reallocated, so it is prepended here.
+	s.drawing.Shapes = append([]shape.Shape{shp}, s.drawing.Shapes...)
+}
+
+// Shapes returns the shapes in the drawing of this worksheet.
+func (s *Sheet) Shapes() []shape.Shape {
+	return s.drawing.Shapes
+}
+
+// addLink adds the relationship of the hyperlink of the decorated shape into the drawing.
+// The relationships part is added into the package with the first link.
+func (s *Sheet) addLink(shp shape.Shape) {
+	ds, ok := shp.(*Shape)
+	if !ok || ds.hyperlink == "" {
+		return
+	}
+	if s.links == nil {
+		s.links = newRelationships(s.drawing)
+		s.wb.pkg.Add(s.links)
+	}
+	ds.linkID = s.links.addHyperlink(ds.hyperlink)
+}
+
+func (wb *Workbook) sheetNameUsed(name string, except int) bool {
+	for i, item := range wb.workbook.Sheets.Items {
+		if i != except && strings.EqualFold(item.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) || r < ' ' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), "'")
+	return strings.TrimSpace(truncate(name, maxSheetName))
+}
+
+// truncate truncates the string to the given number of characters.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// AddCustomXML adds a custom xml part, which is kept by the spreadsheet applications, into this workbook.
+func (wb *Workbook) AddCustomXML(part oxml.Part) {
+	rels := wb.workbook.Relationships()
+	rid := "rId" + strconv.Itoa(len(rels.Items)+1)
+	rels.Add(oxml.Relationship{ID: rid, Type: typeRelationshipsCustomXML, Target: oxml.TargetPath(wb.workbook, part)})
+	wb.pkg.Add(part)
+}
+
+// Save writes out the contents into the file.
+func (wb *Workbook) Save(filename string) error {
+	buf, err := wb.pkg.Packaging()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// Bytes returns the contents of the workbook as an xlsx file.
+func (wb *Workbook) Bytes() ([]byte, error) {
+	buf, err := wb.pkg.Packaging()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes the workbook out to w as an xlsx file.
+func (wb *Workbook) WriteTo(w io.Writer) (int64, error) {
+	buf, err := wb.pkg.Packaging()
+	if err != nil {
+		return 0, err
+	}
+	return buf.WriteTo(w)
+}
@@ -0,0 +1,225 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rsp9u/go-xlsshape/oxml/shape"
+)
+
+func TestSheetNames(t *testing.T) {
+	wb := NewWorkbook()
+	wb.Sheets()[0].SetName("Login: [draft]")
+
+	tests := []struct {
+		name, exp string
+	}{
+		{"login draft", "login draft (2)"},
+		{"", "Sheet3"},
+		{"'quoted'", "quoted"},
+		{"A very long heading which exceeds the limit", "A very long heading which excee"},
+		{"A very long heading which exceeds the limit", "A very long heading which e (2)"},
+	}
+
+	if name := wb.Sheets()[0].Name(); name != "Login draft" {
+		t.Fatalf("Wrong sheet name %s", name)
+	}
+	for _, tt := range tests {
+		if name := wb.AddSheet(tt.name).Name(); name != tt.exp {
+			t.Fatalf("Wrong sheet name [expect: %s, actual: %s]", tt.exp, name)
+		}
+	}
+}
+
+func TestAddSheet(t *testing.T) {
+	wb := NewWorkbook()
+	wb.AddShape(shape.NewRectangle())
+	sheet := wb.AddSheet("Second")
+	sheet.AddShape(shape.NewRectangle())
+	sheet.AddShape(shape.NewRectangle())
+
+	if len(wb.Shapes()) != 1 || len(sheet.Shapes()) != 2 {
+		t.Fatalf("Shapes are added into the wrong sheet")
+	}
+
+	dir, err := ioutil.TempDir("", "xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "sheets.xlsx")
+	if err := wb.Save(filename); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+
+	for _, name := range []string{"xl/worksheets/sheet2.xml", "xl/drawings/drawing2.xml", "xl/worksheets/_rels/sheet2.xml.rels"} {
+		if _, ok := parts[name]; !ok {
+			t.Fatalf("%s is not found", name)
+		}
+	}
+	if !strings.Contains(parts["[Content_Types].xml"], "/xl/drawings/drawing2.xml") {
+		t.Fatalf("Content type of the second drawing is not found")
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Second"`) {
+		t.Fatalf("Second sheet is not found in the workbook")
+	}
+}
+
+func TestBytesAndWriteTo(t *testing.T) {
+	wb := NewWorkbook()
+	wb.AddSheet("second")
+
+	b, err := wb.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes error %v", err)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(b), int64(len(b))); err != nil {
+		t.Fatalf("Bytes is not a zip archive: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	n, err := wb.WriteTo(buf)
+	if err != nil {
+		t.Fatalf("WriteTo error %v", err)
+	}
+	if n != int64(len(b)) || buf.Len() != len(b) {
+		t.Errorf("Mismatches written size: Bytes %d, WriteTo %d", len(b), n)
+	}
+}
+
+func TestUnshiftShape(t *testing.T) {
+	wb := NewWorkbook()
+	shapes := []shape.Shape{}
+	for i := 0; i < 10; i++ {
+		s := shape.NewLine()
+		shapes = append([]shape.Shape{s}, shapes...)
+		if i%2 == 0 {
+			wb.UnshiftShape(s)
+		} else {
+			wb.Sheets()[0].UnshiftShape(s)
+		}
+	}
+
+	if len(wb.Shapes()) != len(shapes) {
+		t.Fatalf("Mismatches the number of shapes %d", len(wb.Shapes()))
+	}
+	for i, s := range wb.Shapes() {
+		if s != shapes[i] {
+			t.Errorf("Mismatches shape %d", i)
+		}
+	}
+}
+
+func TestHyperlink(t *testing.T) {
+	wb := NewWorkbook()
+	second := wb.AddSheet("Login sequence")
+	if sheet := wb.SheetByName("login SEQUENCE"); sheet != second {
+		t.Fatalf("SheetByName returns the wrong sheet %v", sheet)
+	}
+	if loc := second.Location(); loc != "#'Login sequence'!A1" {
+		t.Fatalf("Wrong location %s", loc)
+	}
+
+	internal := Decorate(shape.NewRectangle())
+	internal.SetHyperlink(second.Location())
+	wb.AddShape(internal)
+	external := Decorate(shape.NewRectangle())
+	external.SetHyperlink("login.xlsx")
+	wb.UnshiftShape(external)
+	spaced := Decorate(shape.NewRectangle())
+	spaced.SetHyperlink("docs/Login sequence.xlsx")
+	wb.AddShape(spaced)
+	url := Decorate(shape.NewRectangle())
+	url.SetHyperlink("https://example.com/login?id=1")
+	wb.AddShape(url)
+
+	b, err := wb.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes error %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(b)
+	}
+
+	rels, ok := parts["xl/drawings/_rels/drawing1.xml.rels"]
+	if !ok {
+		t.Fatalf("Relationships of the drawing are not found")
+	}
+	for _, exp := range []string{
+		`<Relationship Id="rId1" Type="` + typeRelationshipsHyperlink + `" Target="#&#39;Login sequence&#39;!A1"></Relationship>`,
+		`<Relationship Id="rId2" Type="` + typeRelationshipsHyperlink + `" Target="login.xlsx" TargetMode="External"></Relationship>`,
+		`<Relationship Id="rId3" Type="` + typeRelationshipsHyperlink + `" Target="docs/Login%20sequence.xlsx" TargetMode="External"></Relationship>`,
+		`<Relationship Id="rId4" Type="` + typeRelationshipsHyperlink + `" Target="https://example.com/login?id=1" TargetMode="External"></Relationship>`,
+	} {
+		if !strings.Contains(rels, exp) {
+			t.Errorf("%s is not found in the relationships\n%s", exp, rels)
+		}
+	}
+	if _, ok := parts["xl/drawings/_rels/drawing2.xml.rels"]; ok {
+		t.Errorf("Relationships of the drawing without links are added")
+	}
+	for _, rid := range []string{"rId1", "rId2"} {
+		if !strings.Contains(parts["xl/drawings/drawing1.xml"], `<a:hlinkClick xmlns:r="`+xmlnsRelationshipsOfficeDocument+`" r:id="`+rid+`">`) {
+			t.Errorf("Link %s is not found in the drawing", rid)
+		}
+	}
+}
+
+func TestArchiveSheetDrawing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "xlsx")
+	if err != nil {
+		t.Fatalf("TempDir error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.xlsx")
+
+	wb := NewWorkbook()
+	wb.AddSheet("second")
+	if err := wb.Save(path); err != nil {
+		t.Fatalf("Save error %v", err)
+	}
+	arc, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("Open error %v", err)
+	}
+
+	name, drawing, err := arc.SheetDrawing(1)
+	if err != nil {
+		t.Fatalf("SheetDrawing error %v", err)
+	}
+	if name != "second" || drawing != "xl/drawings/drawing2.xml" {
+		t.Errorf("Wrong worksheet %s or drawing %s", name, drawing)
+	}
+	if _, _, err := arc.SheetDrawing(2); err == nil {
+		t.Errorf("No error for the missing worksheet")
+	}
+	if names, err := arc.SheetNames(); err != nil || strings.Join(names, ",") != "Sheet1,second" {
+		t.Errorf("Unexpected worksheets %v %v", names, err)
+	}
+}